@@ -8,50 +8,103 @@ import (
 )
 
 type Grammar struct {
-	SymbolTable          *grammar.SymbolTable
-	Productions          grammar.Productions
-	AugmentedStartSymbol grammar.SymbolID
+	SymbolTable *grammar.SymbolTable
+	Productions grammar.Productions
+
+	// AugmentedStartSymbol is the first element of AugmentedStartSymbols.
+	AugmentedStartSymbol  grammar.SymbolID
+	AugmentedStartSymbols []grammar.SymbolID
+
+	// Sources holds the source location of each production. Augmented productions have no source.
+	Sources map[grammar.ProductionID]*Source
+
+	// Labels holds the labels of each production. Productions without labels have no labels.
+	Labels map[grammar.ProductionID]*Labels
+}
+
+// Labels holds the labels of an alternative. Alternative is written as #label, and Fields holds the label of each
+// RHS symbol written as label=symbol, where an empty string means no label.
+type Labels struct {
+	Alternative string
+	Fields      []string
 }
 
-func Convert(root *parser.AST) (*Grammar, error) {
+// Source is the location of an alternative a production was converted from.
+type Source struct {
+	File     string
+	Position parser.Position
+}
+
+// Convert converts an AST into a grammar.
+//
+// The start symbols are determined in the following order of priority.
+//
+// 1. startSymbols passed
+// 2. symbols declared by %start directives (%start file expr;)
+// 3. the LHS of the first production in the root file
+//
+// Each start symbol S is augmented with a production S' → S.
+func Convert(root *parser.AST, startSymbols ...string) (*Grammar, error) {
 	st := grammar.NewSymbolTable()
 	prods := grammar.NewProductions()
 	g := &Grammar{
-		SymbolTable: st,
-		Productions: prods,
+		SymbolTable:           st,
+		Productions:           prods,
+		AugmentedStartSymbols: []grammar.SymbolID{},
+		Sources:               map[grammar.ProductionID]*Source{},
+		Labels:                map[grammar.ProductionID]*Labels{},
 	}
 
-	isFirst := true
-	for _, prodAST := range root.Children {
-		if prodAST.State != parser.StateProduction {
+	for _, dirAST := range root.Children {
+		if dirAST.State != parser.StateDirective {
 			continue
 		}
+		if dirAST.Tokens[0].Text() == parser.DirectiveInclude {
+			return nil, fmt.Errorf("%%%s directives must be resolved by parser.ParseFile. file: %v, position: %v", parser.DirectiveInclude, dirAST.File, dirAST.Pos)
+		}
+	}
 
-		if isFirst {
-			isFirst = false
+	starts, err := findStartSymbols(root, startSymbols)
+	if err != nil {
+		return nil, err
+	}
 
-			lhsAST := prodAST.Children[0]
-			augmentedStartSymbol := fmt.Sprintf("%s'", lhsAST.Tokens[0].Text())
-			lhsID := st.Intern(augmentedStartSymbol, grammar.SymbolKindStart)
+	for _, prodAST := range root.Children {
+		if prodAST.State != parser.StateProduction {
+			continue
+		}
 
-			startSymbol := lhsAST.Tokens[0].Text()
-			startSymbolID := st.Intern(startSymbol, grammar.SymbolKindNonTerminal)
-			rhsIDs := []grammar.SymbolID{startSymbolID}
+		lhsAST := prodAST.Children[0]
+		if lhsAST.Tokens[0].Text() == grammar.ErrorSymbolName {
+			return nil, fmt.Errorf("%v is reserved as a terminal symbol and cannot have productions. file: %v, position: %v", grammar.ErrorSymbolName, prodAST.File, lhsAST.Tokens[0].Pos())
+		}
+		st.Intern(lhsAST.Tokens[0].Text(), grammar.SymbolKindNonTerminal)
+	}
 
-			prod, err := grammar.NewProduction(lhsID, rhsIDs)
-			if err != nil {
-				return nil, err
+	for _, start := range starts {
+		startID := st.LookupByString(start.name)
+		if startID.IsNil() || !startID.Kind().IsNonTerminalSymbol() {
+			if start.ast != nil {
+				return nil, fmt.Errorf("start symbol %v is not defined. file: %v, position: %v", start.name, start.ast.File, start.ast.Pos)
 			}
+			return nil, fmt.Errorf("start symbol %v is not defined", start.name)
+		}
 
-			prods.Append(prod)
-
-			g.AugmentedStartSymbol = lhsID
+		augmentedStartID := st.Intern(fmt.Sprintf("%s'", start.name), grammar.SymbolKindStart)
+		prod, err := grammar.NewProduction(augmentedStartID, []grammar.SymbolID{startID})
+		if err != nil {
+			return nil, err
 		}
+		prods.Append(prod)
 
-		lhsAST := prodAST.Children[0]
-		st.Intern(lhsAST.Tokens[0].Text(), grammar.SymbolKindNonTerminal)
+		g.AugmentedStartSymbols = append(g.AugmentedStartSymbols, augmentedStartID)
+	}
+	if len(g.AugmentedStartSymbols) > 0 {
+		g.AugmentedStartSymbol = g.AugmentedStartSymbols[0]
 	}
 
+	// altLabels holds the location of each label of alternatives.
+	altLabels := map[string]*Source{}
 	for _, prodAST := range root.Children {
 		if prodAST.State != parser.StateProduction {
 			continue
@@ -73,8 +126,121 @@ func Convert(root *parser.AST) (*Grammar, error) {
 			}
 
 			prods.Append(prod)
+
+			g.Sources[prod.ID()] = &Source{
+				File:     prodAST.File,
+				Position: altAST.Pos,
+			}
+
+			labels, err := convertLabels(altAST, prodAST.File, altLabels)
+			if err != nil {
+				return nil, err
+			}
+			if labels != nil {
+				g.Labels[prod.ID()] = labels
+			}
 		}
 	}
 
 	return g, nil
 }
+
+// convertLabels returns the labels of an alternative, or nil when it has no label. A label of an alternative must be
+// unique in the grammar, and a label of a symbol must be unique in the alternative.
+func convertLabels(altAST *parser.AST, file string, altLabels map[string]*Source) (*Labels, error) {
+	if altAST.Label == nil && altAST.FieldLabels == nil {
+		return nil, nil
+	}
+
+	labels := &Labels{
+		Fields: make([]string, len(altAST.Tokens)),
+	}
+	if altAST.Label != nil {
+		label := altAST.Label.Text()
+		if prev, ok := altLabels[label]; ok {
+			return nil, fmt.Errorf("label #%v is duplicated. file: %v, position: %v, previous file: %v, previous position: %v", label, file, altAST.Label.Pos(), prev.File, prev.Position)
+		}
+		altLabels[label] = &Source{
+			File:     file,
+			Position: altAST.Label.Pos(),
+		}
+		labels.Alternative = label
+	}
+	for i, tok := range altAST.FieldLabels {
+		if tok == nil {
+			continue
+		}
+		for _, label := range labels.Fields[:i] {
+			if label == tok.Text() {
+				return nil, fmt.Errorf("label %v is duplicated in an alternative. file: %v, position: %v", label, file, tok.Pos())
+			}
+		}
+		labels.Fields[i] = tok.Text()
+	}
+
+	return labels, nil
+}
+
+type startSymbol struct {
+	name string
+
+	// ast is the %start directive declaring the symbol. It is nil when the symbol isn't declared by a directive.
+	ast *parser.AST
+}
+
+func findStartSymbols(root *parser.AST, names []string) ([]*startSymbol, error) {
+	starts := []*startSymbol{}
+	declared := map[string]struct{}{}
+	add := func(name string, ast *parser.AST) {
+		if _, ok := declared[name]; ok {
+			return
+		}
+		declared[name] = struct{}{}
+		starts = append(starts, &startSymbol{
+			name: name,
+			ast:  ast,
+		})
+	}
+
+	if len(names) > 0 {
+		for _, name := range names {
+			add(name, nil)
+		}
+		return starts, nil
+	}
+
+	for _, dirAST := range root.Children {
+		if dirAST.State != parser.StateDirective || dirAST.Tokens[0].Text() != parser.DirectiveStart {
+			continue
+		}
+		if len(dirAST.Tokens) < 2 {
+			return nil, fmt.Errorf("%%%s requires one or more symbols. file: %v, position: %v", parser.DirectiveStart, dirAST.File, dirAST.Pos)
+		}
+		for _, tok := range dirAST.Tokens[1:] {
+			if tok.Type() != parser.TokenTypeID {
+				return nil, fmt.Errorf("%%%s takes only IDs. file: %v, position: %v", parser.DirectiveStart, dirAST.File, tok.Pos())
+			}
+			add(tok.Text(), dirAST)
+		}
+	}
+	if len(starts) > 0 {
+		return starts, nil
+	}
+
+	// The start symbol is the LHS of the first production in the root file rather than in included files.
+	var startAST *parser.AST
+	for _, prodAST := range root.Children {
+		if prodAST.State != parser.StateProduction {
+			continue
+		}
+		if startAST == nil || (startAST.File != root.File && prodAST.File == root.File) {
+			startAST = prodAST
+		}
+	}
+	if startAST == nil {
+		return nil, fmt.Errorf("the grammar contains no production")
+	}
+	add(startAST.Children[0].Tokens[0].Text(), nil)
+
+	return starts, nil
+}
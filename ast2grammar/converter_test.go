@@ -107,3 +107,328 @@ func TestConvert(t *testing.T) {
 		}
 	}
 }
+
+func TestConvert_StartSymbolOfRootFile(t *testing.T) {
+	parse := func(src string, file string) *parser.AST {
+		p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, child := range ast.Children {
+			child.File = file
+		}
+		ast.File = file
+		return ast
+	}
+
+	// The same as the AST that parser.ParseFile returns when main.sousa includes expr.sousa at its beginning.
+	root := parse(`stmt: expr;`, "main.sousa")
+	included := parse(`expr: id;`, "expr.sousa")
+	root.Children = append(included.Children, root.Children...)
+
+	g, err := Convert(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := g.Productions.Get(g.AugmentedStartSymbol)
+	if len(start) != 1 {
+		t.Fatalf("unexpected productions of the augmented start symbol\nwant: 1 production\ngot: %v productions", len(start))
+	}
+	expected, err := (&production{lhs: "stmt'", rhs: alternative{"stmt"}}).genProduction(g.SymbolTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start[0].Equal(expected) {
+		t.Fatalf("unexpected start production\nwant: %v\ngot: %v", expected, start[0])
+	}
+}
+
+func TestConvert_StartSymbols(t *testing.T) {
+	src := `%start stmt expr; expr: expr "+" id | id; stmt: expr ";";`
+
+	tests := map[string]struct {
+		src          string
+		startSymbols []string
+		expected     []string
+		err          bool
+	}{
+		"start symbols are declared by a directive": {
+			src:      src,
+			expected: []string{"stmt", "expr"},
+		},
+		"start symbols passed override the directive": {
+			src:          src,
+			startSymbols: []string{"expr"},
+			expected:     []string{"expr"},
+		},
+		"the first production decides the start symbol without any declaration": {
+			src:      `expr: expr "+" id | id; stmt: expr ";";`,
+			expected: []string{"expr"},
+		},
+		"an undefined symbol is declared": {
+			src: `%start foo; expr: id;`,
+			err: true,
+		},
+		"a terminal symbol is passed": {
+			src:          src,
+			startSymbols: []string{"id"},
+			err:          true,
+		},
+		"a string is declared": {
+			src: `%start "expr"; expr: id;`,
+			err: true,
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			p, err := parser.NewParser(parser.NewLexer(strings.NewReader(tt.src)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := p.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g, err := Convert(root, tt.startSymbols...)
+			if tt.err {
+				if err == nil {
+					t.Fatal("an error was not returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(g.AugmentedStartSymbols) != len(tt.expected) {
+				t.Fatalf("unexpected start symbols\nwant: %v symbols\ngot: %v symbols", len(tt.expected), len(g.AugmentedStartSymbols))
+			}
+			if g.AugmentedStartSymbol != g.AugmentedStartSymbols[0] {
+				t.Fatalf("AugmentedStartSymbol must be the first start symbol\nwant: %v\ngot: %v", g.AugmentedStartSymbols[0], g.AugmentedStartSymbol)
+			}
+			for i, start := range tt.expected {
+				expected, err := (&production{lhs: start + "'", rhs: alternative{start}}).genProduction(g.SymbolTable)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sym := g.AugmentedStartSymbols[i]
+				if !sym.Kind().IsStartSymbol() {
+					t.Fatalf("unexpected symbol kind\nwant: %v\ngot: %v", grammar.SymbolKindStart, sym.Kind())
+				}
+				prods := g.Productions.Get(sym)
+				if len(prods) != 1 || !prods[0].Equal(expected) {
+					t.Fatalf("unexpected start production\nwant: %v\ngot: %v", expected, prods)
+				}
+			}
+		})
+	}
+}
+
+func TestConvert_Sources(t *testing.T) {
+	src := `E: E "+" T
+  | T;
+T: id;`
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, child := range root.Children {
+		child.File = "test.sousa"
+	}
+	g, err := Convert(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prod   production
+		source *Source
+	}{
+		{
+			prod:   production{lhs: "E'", rhs: alternative{"E"}},
+			source: nil,
+		},
+		{
+			prod:   production{lhs: "E", rhs: alternative{"E", "+", "T"}},
+			source: &Source{File: "test.sousa", Position: parser.Position{Line: 1, Column: 4}},
+		},
+		{
+			prod:   production{lhs: "E", rhs: alternative{"T"}},
+			source: &Source{File: "test.sousa", Position: parser.Position{Line: 2, Column: 5}},
+		},
+		{
+			prod:   production{lhs: "T", rhs: alternative{"id"}},
+			source: &Source{File: "test.sousa", Position: parser.Position{Line: 3, Column: 4}},
+		},
+	}
+	for _, tt := range tests {
+		expected, err := tt.prod.genProduction(g.SymbolTable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var prod *grammar.Production
+		for _, p := range g.Productions.All()[g.SymbolTable.LookupByString(tt.prod.lhs)] {
+			if p.Equal(expected) {
+				prod = p
+			}
+		}
+		if prod == nil {
+			t.Fatalf("a production was not found: %v", expected)
+		}
+
+		source, ok := g.Sources[prod.ID()]
+		if tt.source == nil {
+			if ok {
+				t.Fatalf("an augmented production has a source: %v", source)
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("a source was not found: %v", prod)
+		}
+		if *source != *tt.source {
+			t.Fatalf("unexpected source\nwant: %+v\ngot: %+v", tt.source, source)
+		}
+	}
+}
+
+func TestConvert_ErrorSymbol(t *testing.T) {
+	tests := map[string]struct {
+		src string
+		err bool
+	}{
+		"error is used in an alternative": {
+			src: `stmts: stmts stmt | stmt; stmt: id ";" | error ";";`,
+		},
+		"error has productions": {
+			src: `stmt: id ";" | error ";"; error: id;`,
+			err: true,
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			p, err := parser.NewParser(parser.NewLexer(strings.NewReader(tt.src)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := p.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g, err := Convert(root)
+			if tt.err {
+				if err == nil {
+					t.Fatal("an error was not returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sym := g.SymbolTable.LookupByString(grammar.ErrorSymbolName)
+			if !sym.Kind().IsTerminalSymbol() {
+				t.Fatalf("unexpected symbol kind of error\nwant: %v\ngot: %v", grammar.SymbolKindTerminal, sym.Kind())
+			}
+		})
+	}
+}
+
+func TestConvert_Labels(t *testing.T) {
+	tests := map[string]struct {
+		src    string
+		labels map[string]*Labels
+		err    bool
+	}{
+		"labels of alternatives and symbols": {
+			src: `expr: left=expr "+" right=term #add | term; term: id #id;`,
+			labels: map[string]*Labels{
+				`expr → expr + term`: {Alternative: "add", Fields: []string{"left", "", "right"}},
+				`term → id`:          {Alternative: "id", Fields: []string{""}},
+			},
+		},
+		"labels of symbols only": {
+			src: `expr: x=id;`,
+			labels: map[string]*Labels{
+				`expr → id`: {Fields: []string{"x"}},
+			},
+		},
+		"a label of an alternative is duplicated": {
+			src: `expr: id #a; term: id #a;`,
+			err: true,
+		},
+		"a label of a symbol is duplicated in an alternative": {
+			src: `expr: x=id "+" x=id;`,
+			err: true,
+		},
+		"the same label of symbols in different alternatives": {
+			src: `expr: x=id | x=num;`,
+			labels: map[string]*Labels{
+				`expr → id`:  {Fields: []string{"x"}},
+				`expr → num`: {Fields: []string{"x"}},
+			},
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			p, err := parser.NewParser(parser.NewLexer(strings.NewReader(tt.src)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := p.Parse()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g, err := Convert(root)
+			if tt.err {
+				if err == nil {
+					t.Fatal("an error was not returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(g.Labels) != len(tt.labels) {
+				t.Fatalf("unexpected number of labeled productions\nwant: %v\ngot: %v", len(tt.labels), len(g.Labels))
+			}
+			for _, prods := range g.Productions.All() {
+				for _, prod := range prods {
+					lhs := prod.LHS()
+					lhsText, _ := g.SymbolTable.ToString(lhs)
+					rhs, _ := prod.RHS()
+					rule := lhsText + " →"
+					for _, sym := range rhs {
+						text, _ := g.SymbolTable.ToString(sym)
+						rule += " " + text
+					}
+
+					expected, ok := tt.labels[rule]
+					labels, labeled := g.Labels[prod.ID()]
+					if ok != labeled {
+						t.Fatalf("unexpected labels of %v\nwant: %+v\ngot: %+v", rule, expected, labels)
+					}
+					if !ok {
+						continue
+					}
+					if labels.Alternative != expected.Alternative || strings.Join(labels.Fields, ",") != strings.Join(expected.Fields, ",") {
+						t.Fatalf("unexpected labels of %v\nwant: %+v\ngot: %+v", rule, expected, labels)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type edit struct {
+	op   byte
+	text string
+}
+
+// writeUnifiedDiff writes the differences between a and b in the unified format.
+func writeUnifiedDiff(w io.Writer, name string, a, b []byte) error {
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	edits := diffLines(aLines, bLines)
+
+	// aLine[i] and bLine[i] are the 0-origin line numbers of a and b that edits[i] refers to.
+	aLine := make([]int, len(edits)+1)
+	bLine := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aLine[i+1] = aLine[i]
+		bLine[i+1] = bLine[i]
+		if e.op != '+' {
+			aLine[i+1]++
+		}
+		if e.op != '-' {
+			bLine[i+1]++
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "--- %s.orig\n+++ %s\n", name, name)
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		last := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				last = j
+			} else if j-last > 2*diffContextLines {
+				break
+			}
+		}
+		stop := last + diffContextLines + 1
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		aCount := aLine[stop] - aLine[start]
+		bCount := bLine[stop] - bLine[start]
+		fmt.Fprintf(buf, "@@ -%v +%v @@\n", hunkRange(aLine[start], aCount), hunkRange(bLine[start], bCount))
+		for _, e := range edits[start:stop] {
+			fmt.Fprintf(buf, "%c%s", e.op, e.text)
+			if !strings.HasSuffix(e.text, "\n") {
+				fmt.Fprint(buf, "\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = stop
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%v,0", start)
+	}
+	return fmt.Sprintf("%v,%v", start+1, count)
+}
+
+// splitLines splits src into lines keeping their line feeds so that a last line without a line feed differs from
+// the same line with one.
+func splitLines(src []byte) []string {
+	lines := strings.SplitAfter(string(src), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script transforming a into b using Myers' O(ND) algorithm.
+func diffLines(a, b []string) []edit {
+	n := len(a)
+	m := len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	trace := [][]int{}
+
+loop:
+	for d := 0; d <= n+m; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break loop
+			}
+		}
+	}
+
+	edits := []edit{}
+	x := n
+	y := m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, edit{op: ' ', text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{op: '+', text: b[y-1]})
+				y--
+			} else {
+				edits = append(edits, edit{op: '-', text: a[x-1]})
+				x--
+			}
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		caption  string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			caption: "a line is changed",
+			a:       "a\nb\nc\n",
+			b:       "a\nB\nc\n",
+			expected: `--- g.sousa.orig
++++ g.sousa
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			caption: "lines are inserted into an empty file",
+			a:       "",
+			b:       "a\nb\n",
+			expected: `--- g.sousa.orig
++++ g.sousa
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			caption: "distant changes make separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:       "x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ny\n",
+			expected: `--- g.sousa.orig
++++ g.sousa
+@@ -1,4 +1,4 @@
+-1
++x
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++y
+`,
+		},
+		{
+			caption: "the original lacks a final newline",
+			a:       "a\nb",
+			b:       "a\nb\n",
+			expected: `--- g.sousa.orig
++++ g.sousa
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+		},
+		{
+			caption: "the result lacks a final newline",
+			a:       "a\nb\n",
+			b:       "a\nb",
+			expected: `--- g.sousa.orig
++++ g.sousa
+@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`,
+		},
+		{
+			caption: "a context line lacks a final newline",
+			a:       "a\nb\nc",
+			b:       "A\nb\nc",
+			expected: `--- g.sousa.orig
++++ g.sousa
+@@ -1,3 +1,3 @@
+-a
++A
+ b
+ c
+\ No newline at end of file
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			var b bytes.Buffer
+			err := writeUnifiedDiff(&b, "g.sousa", []byte(tt.a), []byte(tt.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.expected {
+				t.Fatalf("unexpected diff\nwant:\n%v\ngot:\n%v", tt.expected, b.String())
+			}
+		})
+	}
+}
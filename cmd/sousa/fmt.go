@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/nihei9/sousa/parser"
+	"github.com/nihei9/sousa/printer"
+	"github.com/spf13/cobra"
+)
+
+var fmtFlags = struct {
+	write *bool
+	diff  *bool
+	list  *bool
+}{}
+
+func newFmtCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fmt",
+		Short: "Format grammar files",
+		Long: `Format grammar files in the canonical format.
+Without file paths, fmt formats the standard input.`,
+		Example: `  sousa fmt -w grammar.sousa`,
+		RunE:    runFmt,
+	}
+	fmtFlags.write = cmd.Flags().BoolP("write", "w", false, "write the result to the source file instead of the standard output")
+	fmtFlags.diff = cmd.Flags().BoolP("diff", "d", false, "display diffs instead of rewriting files")
+	fmtFlags.list = cmd.Flags().BoolP("list", "l", false, "list files whose formatting differs from the canonical format")
+
+	return cmd
+}
+
+func runFmt(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		if *fmtFlags.write {
+			return fmt.Errorf("cannot use -w with the standard input")
+		}
+
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return formatFile(cmd, stdinName, src, 0)
+	}
+
+	for _, path := range args {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = formatFile(cmd, path, src, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatFile(cmd *cobra.Command, path string, src []byte, perm os.FileMode) error {
+	p, err := parser.NewParser(parser.NewLexer(bytes.NewReader(src)))
+	if err != nil {
+		return err
+	}
+	p.SetSourceFilePath(path)
+	ast, err := p.Parse()
+	if err != nil {
+		return err
+	}
+
+	formatted := new(bytes.Buffer)
+	err = printer.Fprint(formatted, ast)
+	if err != nil {
+		return err
+	}
+
+	changed := !bytes.Equal(src, formatted.Bytes())
+	if *fmtFlags.write && changed {
+		err := ioutil.WriteFile(path, formatted.Bytes(), perm)
+		if err != nil {
+			return err
+		}
+	}
+	if *fmtFlags.list && changed {
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), path)
+		if err != nil {
+			return err
+		}
+	}
+	if *fmtFlags.diff && changed {
+		err := writeUnifiedDiff(cmd.OutOrStdout(), path, src, formatted.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	if !*fmtFlags.write && !*fmtFlags.diff && !*fmtFlags.list {
+		_, err := cmd.OutOrStdout().Write(formatted.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
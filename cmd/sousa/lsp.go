@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+
+	"github.com/nihei9/sousa/lsp"
+	"github.com/spf13/cobra"
+)
+
+func newLSPCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a language server for grammar files",
+		Long: `Run a language server speaking the Language Server Protocol over the standard input and output.
+It reports syntax errors, undefined start symbols, and conflicts of the parsing table, and supports
+go-to-definition, find-references, hover showing FIRST and FOLLOW sets, renaming, and formatting.`,
+		Args: cobra.NoArgs,
+		RunE: runLSP,
+	}
+
+	return cmd
+}
+
+func runLSP(cmd *cobra.Command, args []string) error {
+	return lsp.NewServer(os.Stdin, os.Stdout).Serve()
+}
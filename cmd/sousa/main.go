@@ -1,11 +1,20 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/nihei9/sousa/ast2grammar"
 	"github.com/nihei9/sousa/grammar"
 	"github.com/nihei9/sousa/parser"
+	"github.com/nihei9/sousa/table"
 	"github.com/nihei9/sousa/writer"
 	"github.com/spf13/cobra"
 )
@@ -27,83 +36,470 @@ func doMain() int {
 	return 0
 }
 
+// version is the version of sousa. Generated Go code records it in its stamp.
+const version = "0.1.0"
+
+var startSymbols *[]string
+
+var genFlags = struct {
+	format   *string
+	lang     *string
+	pkg      *string
+	compress *bool
+	ast      *bool
+	jobs     *int
+	watch    *bool
+	interval *time.Duration
+	outDir   *string
+	prefix   *string
+	force    *bool
+}{}
+
+const (
+	// stdinName is the file name of the grammar read from the standard input.
+	stdinName = "<standard input>"
+
+	// stdoutName is the --out-dir that means the standard output.
+	stdoutName = "-"
+)
+
 func newCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:           "sousa",
-		Short:         "Sousa is a parsing table generator",
-		Long:          `Sousa is a parsing table generator.`,
-		Args:          cobra.ExactArgs(1),
+		Use:   "sousa [grammar file]",
+		Short: "Sousa is a parsing table generator",
+		Long: `Sousa is a parsing table generator.
+Without a grammar file, sousa reads the grammar from the standard input. The files it includes are relative to
+the current directory.
+
+With -o -, sousa writes a single output to the standard output. The output format defaults to json then, and
+outputs consisting of multiple files, such as csv, cannot be written.
+
+Generated Go code records a stamp of the grammar files, the options, and the version of sousa. When the
+existing files have the same stamp, sousa leaves them as they are, so running it from go:generate is cheap.
+Existing files that don't look like the output of sousa are never overwritten without --force.
+
+Conflicts of the parsing table are reported on the standard error. As in yacc, a shift takes precedence over a
+reduction, and a reduction by an earlier production takes precedence over a later one.`,
+		Example: `  sousa -o gen --prefix expr_ expr.sousa
+  cat expr.sousa | sousa -o - --format binary > expr.tbl`,
+		Version:       version,
+		Args:          cobra.MaximumNArgs(1),
 		RunE:          run,
 		SilenceErrors: true,
 		SilenceUsage:  true,
 	}
+	startSymbols = cmd.PersistentFlags().StringSlice("start", nil, "start symbols overriding %start directives (e.g. --start file,expr)")
+	genFlags.format = cmd.Flags().String("format", "csv", "output format (csv|json|binary)")
+	genFlags.lang = cmd.Flags().String("lang", "", "generate source code in the language instead of table files (go)")
+	genFlags.pkg = cmd.Flags().String("package", "main", "package name of generated Go code")
+	genFlags.compress = cmd.Flags().Bool("compress", false, "compress the parsing table of binary and generated code")
+	genFlags.ast = cmd.Flags().Bool("ast", false, "also generate typed AST nodes, a visitor, and their constructors in sousa_ast.go (requires --lang go)")
+	genFlags.jobs = cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "number of workers generating the LR(0) automaton")
+	genFlags.watch = cmd.Flags().Bool("watch", false, "regenerate the output whenever the grammar file or a file it includes changes")
+	genFlags.interval = cmd.Flags().Duration("watch-interval", 500*time.Millisecond, "interval of polling files in watch mode")
+	genFlags.outDir = cmd.Flags().StringP("out-dir", "o", ".", "directory to write output files to, or - to write the output to the standard output")
+	genFlags.prefix = cmd.Flags().String("prefix", "", "prefix of output file names")
+	genFlags.force = cmd.Flags().BoolP("force", "f", false, "overwrite existing output files")
+	cmd.AddCommand(newTransformCmd())
+	cmd.AddCommand(newFmtCmd())
+	cmd.AddCommand(newParseCmd())
+	cmd.AddCommand(newLSPCmd())
+	cmd.AddCommand(newVerifyCmd(cmd))
 
 	return cmd
 }
 
 func run(cmd *cobra.Command, args []string) error {
-	filepath := args[0]
-	file, err := os.Open(filepath)
+	err := checkGenFlags(cmd)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if *genFlags.watch {
+		if len(args) == 0 {
+			return fmt.Errorf("--watch needs a grammar file")
+		}
+		if *genFlags.outDir == stdoutName {
+			return fmt.Errorf("cannot use --watch with -o %v", stdoutName)
+		}
+		return watch(cmd, args[0], *genFlags.interval)
+	}
 
-	lexer := parser.NewLexer(file)
-	parser, err := parser.NewParser(lexer)
-	if err != nil {
-		return err
+	var g *ast2grammar.Grammar
+	stamp := ""
+	if len(args) == 0 {
+		g, err = readGrammarFromStdin()
+		if err != nil {
+			return err
+		}
+	} else {
+		var files []string
+		g, files, err = readGrammarWithIncludes(args[0])
+		if err != nil {
+			return err
+		}
+		stamp, err = newStamp(files)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = generate(cmd, g, stamp, *genFlags.force)
+	return err
+}
+
+func checkGenFlags(cmd *cobra.Command) error {
+	if *genFlags.ast && *genFlags.lang != "go" {
+		return fmt.Errorf("--ast requires --lang go")
+	}
+	if *genFlags.outDir == stdoutName && !cmd.Flags().Changed("format") {
+		*genFlags.format = "json"
 	}
-	parser.SetSourceFilePath(filepath)
-	ast, err := parser.Parse()
+	return nil
+}
+
+// generate generates the parsing table of a grammar and writes the output files. Generated Go code embeds the stamp,
+// and generate does nothing when the existing files have the same stamp. Unless force is true, it refuses to overwrite
+// existing files that don't look like the output of sousa. It returns a nil table when it does nothing.
+func generate(cmd *cobra.Command, g *ast2grammar.Grammar, stamp string, force bool) (*grammar.ParsingTable, error) {
+	outs, err := outputs(g, stamp)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if *genFlags.outDir == stdoutName {
+		if len(outs) != 1 {
+			names := []string{}
+			for _, out := range outs {
+				names = append(names, out.name)
+			}
+			return nil, fmt.Errorf("cannot write multiple output files to the standard output: %v", strings.Join(names, ", "))
+		}
+		parsingTable, err := generateParsingTable(g, *genFlags.jobs)
+		if err != nil {
+			return nil, err
+		}
+		err = reportConflicts(os.Stderr, g, parsingTable)
+		if err != nil {
+			return nil, err
+		}
+		if *genFlags.compress {
+			// The report must not be mixed with the output.
+			err := reportCompression(os.Stderr, g, parsingTable)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = outs[0].newWriter(parsingTable).Write(os.Stdout)
+		if err != nil {
+			return nil, err
+		}
+		return parsingTable, nil
 	}
 
-	g, err := ast2grammar.Convert(ast)
+	for _, out := range outs {
+		out.name = filepath.Join(*genFlags.outDir, *genFlags.prefix+out.name)
+	}
+	if !force {
+		if stamp != "" && upToDate(outs, stamp) {
+			return nil, nil
+		}
+		for _, out := range outs {
+			src, err := ioutil.ReadFile(out.name)
+			if err == nil && !out.generated(src) {
+				return nil, fmt.Errorf("%v already exists; use --force to overwrite it", out.name)
+			}
+		}
+	}
+
+	parsingTable, err := generateParsingTable(g, *genFlags.jobs)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	err = reportConflicts(os.Stderr, g, parsingTable)
+	if err != nil {
+		return nil, err
+	}
+	if *genFlags.compress {
+		err := reportCompression(cmd.OutOrStdout(), g, parsingTable)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = os.MkdirAll(*genFlags.outDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = writeOutputs(outs, parsingTable)
+	if err != nil {
+		return nil, err
 	}
+
+	return parsingTable, nil
+}
+
+// output is a file to generate.
+type output struct {
+	name      string
+	newWriter func(parsingTable *grammar.ParsingTable) writer.Writer
+
+	// generated reports whether the contents of an existing file are the output of sousa, which can be overwritten.
+	generated func(src []byte) bool
+}
+
+func outputs(g *ast2grammar.Grammar, stamp string) ([]*output, error) {
+	if *genFlags.lang != "" {
+		switch *genFlags.lang {
+		case "go":
+			opts := writer.GoOptions{
+				Package:  *genFlags.pkg,
+				Compress: *genFlags.compress,
+				Stamp:    stamp,
+			}
+			outs := []*output{}
+			if *genFlags.ast {
+				outs = append(outs, &output{
+					name: "sousa_ast.go",
+					newWriter: func(_ *grammar.ParsingTable) writer.Writer {
+						return writer.NewGoASTWriter(g, opts)
+					},
+					generated: writer.IsGoGenerated,
+				})
+			}
+			outs = append(outs, &output{
+				name: "sousa_table.go",
+				newWriter: func(parsingTable *grammar.ParsingTable) writer.Writer {
+					return writer.NewGoWriter(g, parsingTable, opts)
+				},
+				generated: writer.IsGoGenerated,
+			})
+			return outs, nil
+		}
+		return nil, fmt.Errorf("unknown language: %v", *genFlags.lang)
+	}
+
+	switch *genFlags.format {
+	case "csv":
+		return []*output{
+			{
+				name: "production",
+				newWriter: func(_ *grammar.ParsingTable) writer.Writer {
+					return writer.NewProductionsWriter(g.Productions)
+				},
+				generated: writer.IsCSV,
+			},
+			{
+				name: "action",
+				newWriter: func(parsingTable *grammar.ParsingTable) writer.Writer {
+					return writer.NewActionWriter(parsingTable, g.Productions)
+				},
+				generated: writer.IsCSV,
+			},
+			{
+				name: "goto",
+				newWriter: func(parsingTable *grammar.ParsingTable) writer.Writer {
+					return writer.NewGoToWriter(parsingTable)
+				},
+				generated: writer.IsCSV,
+			},
+			{
+				name: "start",
+				newWriter: func(parsingTable *grammar.ParsingTable) writer.Writer {
+					return writer.NewStartWriter(parsingTable)
+				},
+				generated: writer.IsCSV,
+			},
+		}, nil
+	case "json":
+		return []*output{
+			{
+				name: "sousa.json",
+				newWriter: func(parsingTable *grammar.ParsingTable) writer.Writer {
+					return writer.NewJSONWriter(g, parsingTable)
+				},
+				generated: writer.IsJSON,
+			},
+		}, nil
+	case "binary":
+		return []*output{
+			{
+				name: "sousa.tbl",
+				newWriter: func(parsingTable *grammar.ParsingTable) writer.Writer {
+					return writer.NewBinaryWriter(g, parsingTable, *genFlags.compress)
+				},
+				generated: table.IsBinary,
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown output format: %v", *genFlags.format)
+}
+
+func generateParsingTable(g *ast2grammar.Grammar, workers int) (*grammar.ParsingTable, error) {
 	first, err := grammar.GenerateFirstSets(g.Productions)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	follow, err := grammar.GenerateFollowSets(g.Productions, first)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	automaton, err := grammar.GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbol)
+	automaton, err := grammar.GenerateLR0AutomatonWithWorkers(g.SymbolTable, g.Productions, workers, g.AugmentedStartSymbols...)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	parsingTable, err := grammar.GenerateSLRParsingTable(automaton, follow)
+
+	return grammar.GenerateSLRParsingTable(automaton, follow)
+}
+
+// reportConflicts writes each conflict of a parsing table and the action that a deterministic parser takes.
+func reportConflicts(w io.Writer, g *ast2grammar.Grammar, parsingTable *grammar.ParsingTable) error {
+	t, err := table.New(g.SymbolTable, g.Productions, parsingTable, g.AugmentedStartSymbols)
 	if err != nil {
 		return err
 	}
+	symbolName := func(sym int) string {
+		if sym == table.SymbolEOF {
+			return "EOF"
+		}
+		return t.Symbols[sym].Name
+	}
+
+	for _, c := range t.Conflicts {
+		acts, _ := t.Conflict(c.State, c.Terminal)
+		kind := "reduce/reduce"
+		for _, act := range acts {
+			switch act.Type {
+			case table.ActionTypeShift:
+				kind = "shift/reduce"
+			case table.ActionTypeAccept:
+				kind = "accept/reduce"
+			}
+		}
+
+		taken := "shift"
+		switch typ, n := t.Action(c.State, c.Terminal); typ {
+		case table.ActionTypeAccept:
+			taken = "accept"
+		case table.ActionTypeReduce:
+			prod := t.Productions[n]
+			rhs := []string{}
+			for _, sym := range prod.RHS {
+				rhs = append(rhs, symbolName(sym))
+			}
+			if len(rhs) == 0 {
+				rhs = append(rhs, "ε")
+			}
+			taken = fmt.Sprintf("reduce by %v → %v", symbolName(prod.LHS), strings.Join(rhs, " "))
+		}
+		terminal := "EOF"
+		if c.Terminal != table.SymbolEOF {
+			terminal = strconv.Quote(symbolName(c.Terminal))
+		}
+		_, err := fmt.Fprintf(w, "state %v: %v conflict on %v; %v is taken\n", c.State, kind, terminal, taken)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	prodsFile, err := os.OpenFile("production", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+func reportCompression(w io.Writer, g *ast2grammar.Grammar, parsingTable *grammar.ParsingTable) error {
+	t, err := table.New(g.SymbolTable, g.Productions, parsingTable, g.AugmentedStartSymbols)
 	if err != nil {
 		return err
 	}
-	defer prodsFile.Close()
-	prodsWriter := writer.NewProductionsWriter(g.Productions)
-	prodsWriter.Write(prodsFile)
+	before := t.Size()
+	after := table.Pack(t).Size()
+	_, err = fmt.Fprintf(w, "action and goto tables: %v bytes -> %v bytes (%.1f%%)\n", before, after, 100*float64(after)/float64(before))
+
+	return err
+}
 
-	actionFile, err := os.OpenFile("action", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+func readGrammar(filepath string) (*ast2grammar.Grammar, error) {
+	ast, err := parser.ParseFile(filepath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer actionFile.Close()
-	actionWriter := writer.NewActionWriter(parsingTable, g.Productions)
-	actionWriter.Write(actionFile)
 
-	gotoFile, err := os.OpenFile("goto", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	return ast2grammar.Convert(ast, *startSymbols...)
+}
+
+// readGrammarFromStdin reads a grammar from the standard input. The files it includes are relative to the current
+// directory.
+func readGrammarFromStdin() (*ast2grammar.Grammar, error) {
+	ast, err := parser.ParseSource(stdinName, os.Stdin)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return ast2grammar.Convert(ast, *startSymbols...)
+}
+
+// readGrammarWithIncludes is like readGrammar but also returns the paths of the grammar files it read.
+func readGrammarWithIncludes(path string) (*ast2grammar.Grammar, []string, error) {
+	ast, files, err := parser.ParseFileWithIncludes(path)
+	if err != nil {
+		return nil, files, err
+	}
+	g, err := ast2grammar.Convert(ast, *startSymbols...)
+	if err != nil {
+		return nil, files, err
+	}
+
+	return g, files, nil
+}
+
+// writeOutputs writes each output to a temporary file in the directory of the output and then renames the temporary
+// files to the outputs. A reader never sees a partially written file, and the previous files stay as they were when
+// writing fails.
+func writeOutputs(outs []*output, parsingTable *grammar.ParsingTable) error {
+	tmpNames := []string{}
+	renamed := 0
+	defer func() {
+		for _, name := range tmpNames[renamed:] {
+			os.Remove(name)
+		}
+	}()
+
+	for _, out := range outs {
+		name, err := writeTempFile(out.name, out.newWriter(parsingTable))
+		if err != nil {
+			return err
+		}
+		tmpNames = append(tmpNames, name)
+	}
+	for i, out := range outs {
+		err := os.Rename(tmpNames[i], out.name)
+		if err != nil {
+			return err
+		}
+		renamed++
 	}
-	defer gotoFile.Close()
-	gotoWriter := writer.NewGoToWriter(parsingTable)
-	gotoWriter.Write(gotoFile)
 
 	return nil
 }
+
+func writeTempFile(name string, w writer.Writer) (string, error) {
+	// An existing file keeps its permission.
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return "", err
+	}
+	err = w.Write(f)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
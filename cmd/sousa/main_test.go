@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/parser"
+	"github.com/nihei9/sousa/table"
+	"github.com/nihei9/sousa/writer"
+)
+
+// runSousa runs the sousa command with args and returns the messages it printed.
+func runSousa(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := newCmd()
+	var out bytes.Buffer
+	cmd.SetOutput(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+// writeGrammar writes a grammar file including another file into a temporary directory and returns the directory
+// and the path of the grammar file.
+func writeGrammar(t *testing.T) (string, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "sousa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "term.sousa"), "term: id;\n")
+	path := filepath.Join(dir, "expr.sousa")
+	writeFile(t, path, "%include \"term.sousa\";\nexpr: expr \"+\" term | term;\n")
+	return dir, path
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// replaceStdio replaces the standard input with a file holding in and the standard output with a temporary file. The
+// returned function restores them and returns what was written to the standard output.
+func replaceStdio(t *testing.T, in string) func() []byte {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "sousa-stdio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "stdin"), in)
+	stdin, err := os.Open(filepath.Join(dir, "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origStdin, origStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+	return func() []byte {
+		os.Stdin, os.Stdout = origStdin, origStdout
+		stdin.Close()
+		stdout.Close()
+		defer os.RemoveAll(dir)
+		return readFile(t, filepath.Join(dir, "stdout"))
+	}
+}
+
+func TestRun_Outputs(t *testing.T) {
+	tests := []struct {
+		caption   string
+		args      []string
+		files     []string
+		generated func([]byte) bool
+	}{
+		{
+			caption:   "csv",
+			args:      []string{},
+			files:     []string{"production", "action", "goto", "start"},
+			generated: writer.IsCSV,
+		},
+		{
+			caption:   "json with a prefix",
+			args:      []string{"--format", "json", "--prefix", "expr_"},
+			files:     []string{"expr_sousa.json"},
+			generated: writer.IsJSON,
+		},
+		{
+			caption:   "binary",
+			args:      []string{"--format", "binary", "--compress"},
+			files:     []string{"sousa.tbl"},
+			generated: table.IsBinary,
+		},
+		{
+			caption:   "go",
+			args:      []string{"--lang", "go", "--ast", "--package", "expr"},
+			files:     []string{"sousa_ast.go", "sousa_table.go"},
+			generated: writer.IsGoGenerated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			dir, path := writeGrammar(t)
+			defer os.RemoveAll(dir)
+			outDir := filepath.Join(dir, "gen", "parser")
+			args := append([]string{"-o", outDir, path}, tt.args...)
+
+			_, err := runSousa(t, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := ioutil.ReadDir(outDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.files) {
+				t.Fatalf("unexpected number of files; temporary files may remain\nwant: %v\ngot: %v", len(tt.files), len(entries))
+			}
+			for _, name := range tt.files {
+				if !tt.generated(readFile(t, filepath.Join(outDir, name))) {
+					t.Fatalf("%v isn't recognized as an output of sousa", name)
+				}
+			}
+
+			// Running sousa again overwrites its own outputs.
+			writeFile(t, path, "%include \"term.sousa\";\nexpr: expr \"*\" term | term;\n")
+			_, err = runSousa(t, args...)
+			if err != nil {
+				t.Fatalf("failed to regenerate the outputs: %v", err)
+			}
+
+			// Files that sousa didn't generate, including empty ones, are overwritten only with --force.
+			target := filepath.Join(outDir, tt.files[0])
+			for _, content := range []string{"my notes\n", ""} {
+				writeFile(t, target, content)
+				_, err = runSousa(t, args...)
+				if err == nil || !strings.Contains(err.Error(), "--force") {
+					t.Fatalf("a file that sousa didn't generate must not be overwritten: %v", err)
+				}
+				if string(readFile(t, target)) != content {
+					t.Fatalf("the file was overwritten")
+				}
+				_, err = runSousa(t, append(args, "--force")...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !tt.generated(readFile(t, target)) {
+					t.Fatalf("the file wasn't overwritten with --force")
+				}
+			}
+		})
+	}
+}
+
+func TestRun_Stdio(t *testing.T) {
+	dir, path := writeGrammar(t)
+	defer os.RemoveAll(dir)
+	src := string(readFile(t, path))
+
+	t.Run("write json to the standard output by default", func(t *testing.T) {
+		restore := replaceStdio(t, "")
+		_, err := runSousa(t, "-o", "-", path)
+		out := restore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !writer.IsJSON(out) {
+			t.Fatalf("the standard output isn't a JSON document:\n%s", out)
+		}
+	})
+
+	t.Run("keep the compression report out of the standard output", func(t *testing.T) {
+		restore := replaceStdio(t, "")
+		_, err := runSousa(t, "-o", "-", "--format", "binary", "--compress", path)
+		out := restore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tab, err := table.Read(bytes.NewReader(out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := tab.(*table.Packed); !ok {
+			t.Fatalf("the table isn't compressed: %T", tab)
+		}
+	})
+
+	t.Run("reject multiple files", func(t *testing.T) {
+		restore := replaceStdio(t, "")
+		_, err := runSousa(t, "-o", "-", "--format", "csv", path)
+		out := restore()
+		if err == nil {
+			t.Fatal("csv must not be written to the standard output")
+		}
+		if len(out) != 0 {
+			t.Fatalf("nothing must be written to the standard output:\n%s", out)
+		}
+	})
+
+	t.Run("read the grammar from the standard input", func(t *testing.T) {
+		// The files included by the standard input are relative to the current directory.
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chdir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chdir(wd)
+
+		restore := replaceStdio(t, src)
+		_, err = runSousa(t, "-o", "-")
+		out := restore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !writer.IsJSON(out) || !bytes.Contains(out, []byte(`"term"`)) {
+			t.Fatalf("unexpected output:\n%s", out)
+		}
+
+		restore = replaceStdio(t, src)
+		_, err = runSousa(t, "-o", "gen", "--lang", "go")
+		restore()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !writer.IsGoGenerated(readFile(t, filepath.Join("gen", "sousa_table.go"))) {
+			t.Fatal("the generated code is invalid")
+		}
+	})
+}
+
+func TestRun_Stamp(t *testing.T) {
+	dir, path := writeGrammar(t)
+	defer os.RemoveAll(dir)
+	outDir := filepath.Join(dir, "gen")
+	tablePath := filepath.Join(outDir, "sousa_table.go")
+	args := []string{"--lang", "go", "-o", outDir, path}
+
+	_, err := runSousa(t, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamp := writer.ReadGoStamp(readFile(t, tablePath))
+	if !strings.Contains(stamp, "version="+version) || !strings.Contains(stamp, "package=main") {
+		t.Fatalf("unexpected stamp: %v", stamp)
+	}
+
+	// An up-to-date file is left as it is, which the appended line shows.
+	edited := string(readFile(t, tablePath)) + "// edited\n"
+	writeFile(t, tablePath, edited)
+	_, err = runSousa(t, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(readFile(t, tablePath)) != edited {
+		t.Fatal("an up-to-date file must not be regenerated")
+	}
+
+	// --force regenerates the file anyway.
+	_, err = runSousa(t, append(args, "--force")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(readFile(t, tablePath)) == edited {
+		t.Fatal("the file must be regenerated with --force")
+	}
+
+	tests := []struct {
+		caption string
+		change  func()
+		args    []string
+	}{
+		{
+			caption: "an included file changes",
+			change: func() {
+				writeFile(t, filepath.Join(dir, "term.sousa"), "term: id | num;\n")
+			},
+			args: args,
+		},
+		{
+			caption: "an option changes",
+			change:  func() {},
+			args:    append([]string{"--package", "expr"}, args...),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			before := writer.ReadGoStamp(readFile(t, tablePath))
+			tt.change()
+
+			verifyArgs := append([]string{"verify"}, tt.args...)
+			_, err := runSousa(t, verifyArgs...)
+			if err == nil || !strings.Contains(err.Error(), "stale") {
+				t.Fatalf("verify must report the stale file: %v", err)
+			}
+
+			_, err = runSousa(t, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if after := writer.ReadGoStamp(readFile(t, tablePath)); after == before {
+				t.Fatalf("the stamp must change: %v", after)
+			}
+			_, err = runSousa(t, verifyArgs...)
+			if err != nil {
+				t.Fatalf("verify must succeed after regeneration: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir, path := writeGrammar(t)
+	defer os.RemoveAll(dir)
+	outDir := filepath.Join(dir, "gen")
+
+	_, err := runSousa(t, "verify", "--format", "json", "-o", outDir, path)
+	if err == nil {
+		t.Fatal("verify must fail when the files don't exist")
+	}
+
+	_, err = runSousa(t, "--format", "json", "-o", outDir, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = runSousa(t, "verify", "--format", "json", "-o", outDir, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A hand-edited file is stale.
+	jsonPath := filepath.Join(outDir, "sousa.json")
+	writeFile(t, jsonPath, string(readFile(t, jsonPath))+"\n")
+	_, err = runSousa(t, "verify", "--format", "json", "-o", outDir, path)
+	if err == nil || !strings.Contains(err.Error(), jsonPath) {
+		t.Fatalf("verify must report the edited file: %v", err)
+	}
+}
+
+func TestReportConflicts(t *testing.T) {
+	ast, err := parser.ParseSource("conflicts.sousa", strings.NewReader(`
+s: e | a | e ";" | a ";";
+e: e "+" e | id;
+a: id;
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsingTable, err := generateParsingTable(g, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = reportConflicts(&b, g, parsingTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected number of conflicts\nwant: %v\ngot: %v\n%v", 3, len(lines), b.String())
+	}
+	for _, want := range []string{
+		`reduce/reduce conflict on EOF; reduce by e → id is taken`,
+		`reduce/reduce conflict on ";"; reduce by e → id is taken`,
+		`shift/reduce conflict on "+"; shift is taken`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("the report must contain %q\n%v", want, b.String())
+		}
+	}
+}
+
+func TestFmt(t *testing.T) {
+	const (
+		unformatted = "expr: expr \"+\" id | id;"
+		formatted   = "expr\n    : expr \"+\" id\n    | id\n    ;\n"
+	)
+
+	dir, err := ioutil.TempDir("", "sousa-fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "expr.sousa")
+	formattedPath := filepath.Join(dir, "formatted.sousa")
+
+	tests := []struct {
+		caption  string
+		args     []string
+		expected string
+		result   string
+	}{
+		{
+			caption:  "print the formatted grammar",
+			args:     []string{"fmt", path},
+			expected: formatted,
+			result:   unformatted,
+		},
+		{
+			caption:  "list unformatted files",
+			args:     []string{"fmt", "-l", path, formattedPath},
+			expected: path + "\n",
+			result:   unformatted,
+		},
+		{
+			caption: "display a diff",
+			args:    []string{"fmt", "-d", path, formattedPath},
+			expected: "--- " + path + ".orig\n+++ " + path + "\n@@ -1,1 +1,4 @@\n-" + unformatted +
+				"\n\\ No newline at end of file\n+expr\n+    : expr \"+\" id\n+    | id\n+    ;\n",
+			result: unformatted,
+		},
+		{
+			caption:  "rewrite files",
+			args:     []string{"fmt", "-w", path, formattedPath},
+			expected: "",
+			result:   formatted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			writeFile(t, path, unformatted)
+			writeFile(t, formattedPath, formatted)
+
+			out, err := runSousa(t, tt.args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.expected {
+				t.Fatalf("unexpected output\nwant:\n%v\ngot:\n%v", tt.expected, out)
+			}
+			if got := string(readFile(t, path)); got != tt.result {
+				t.Fatalf("unexpected file\nwant:\n%v\ngot:\n%v", tt.result, got)
+			}
+			if got := string(readFile(t, formattedPath)); got != formatted {
+				t.Fatalf("a formatted file must be left as it is:\n%v", got)
+			}
+		})
+	}
+
+	_, err = runSousa(t, "fmt", "-w")
+	if err == nil {
+		t.Fatal("-w must be rejected for the standard input")
+	}
+}
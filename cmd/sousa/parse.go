@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/nihei9/sousa/driver"
+	"github.com/nihei9/sousa/glr"
+	"github.com/nihei9/sousa/table"
+	"github.com/spf13/cobra"
+)
+
+var parseFlags = struct {
+	format      *string
+	trace       *bool
+	traceFormat *string
+	glr         *bool
+}{}
+
+func newParseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "parse",
+		Short: "Parse an input with a grammar",
+		Long: `Generate a parsing table in memory and parse an input with it.
+The input is split on white spaces, and each word must be the name of a terminal symbol.
+When the grammar uses the terminal symbol error, parse recovers from syntax errors and reports all of them.
+With --glr, parse takes all the conflicting actions of the parsing table and prints the parse forest, in which
+each ambiguous node lists its alternatives as #1, #2, and so on.`,
+		Example: `  sousa parse grammar.sousa input.txt`,
+		Args:    cobra.ExactArgs(2),
+		RunE:    runParse,
+	}
+	parseFlags.format = cmd.Flags().String("format", "tree", "output format of the parse tree (tree|json)")
+	parseFlags.trace = cmd.Flags().Bool("trace", false, "print every step of parsing to the standard error")
+	parseFlags.traceFormat = cmd.Flags().String("trace-format", "text", "format of the trace (text|json)")
+	parseFlags.glr = cmd.Flags().Bool("glr", false, "parse with a GLR parser for ambiguous grammars")
+
+	return cmd
+}
+
+func runParse(cmd *cobra.Command, args []string) error {
+	if *parseFlags.format != "tree" && *parseFlags.format != "json" {
+		return fmt.Errorf("unknown output format: %v", *parseFlags.format)
+	}
+	if *parseFlags.glr && (*parseFlags.format != "tree" || *parseFlags.trace) {
+		return fmt.Errorf("--glr supports neither --format json nor --trace")
+	}
+	var tracer driver.Tracer
+	if *parseFlags.trace {
+		switch *parseFlags.traceFormat {
+		case "text":
+			tracer = driver.NewTextTracer(os.Stderr)
+		case "json":
+			tracer = driver.NewJSONTracer(os.Stderr)
+		default:
+			return fmt.Errorf("unknown trace format: %v", *parseFlags.traceFormat)
+		}
+	}
+
+	g, err := readGrammar(args[0])
+	if err != nil {
+		return err
+	}
+	parsingTable, err := generateParsingTable(g, runtime.NumCPU())
+	if err != nil {
+		return err
+	}
+	t, err := table.New(g.SymbolTable, g.Productions, parsingTable, g.AugmentedStartSymbols)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	ts, err := driver.NewWordLexer(t, src)
+	if err != nil {
+		return err
+	}
+	if *parseFlags.glr {
+		return runGLRParse(cmd, t, ts, args[1])
+	}
+	p, err := driver.NewParser(t, ts)
+	if err != nil {
+		return err
+	}
+	p.SetTracer(tracer)
+	root, err := p.Parse()
+	var synErrs driver.SyntaxErrors
+	switch e := err.(type) {
+	case nil:
+	case *driver.SyntaxError:
+		synErrs = driver.SyntaxErrors{e}
+	case driver.SyntaxErrors:
+		synErrs = e
+	default:
+		return err
+	}
+	if root != nil {
+		err := printParseTree(cmd, root)
+		if err != nil {
+			return err
+		}
+	}
+	if len(synErrs) > 0 {
+		msgs := make([]string, len(synErrs))
+		for i, e := range synErrs {
+			msgs[i] = fmt.Sprintf("%v: %v", args[1], e)
+		}
+		return fmt.Errorf("%v", strings.Join(msgs, "\n"))
+	}
+
+	return nil
+}
+
+func runGLRParse(cmd *cobra.Command, t table.ParsingTable, ts driver.TokenStream, srcPath string) error {
+	p, err := glr.NewParser(t, ts)
+	if err != nil {
+		return err
+	}
+	root, err := p.Parse()
+	if err != nil {
+		if synErr, ok := err.(*driver.SyntaxError); ok {
+			return fmt.Errorf("%v: %v", srcPath, synErr)
+		}
+		return err
+	}
+
+	return glr.PrintForest(cmd.OutOrStdout(), root)
+}
+
+func printParseTree(cmd *cobra.Command, root *driver.Node) error {
+	if *parseFlags.format == "json" {
+		b, err := json.MarshalIndent(newJSONNode(root), "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(b))
+		return nil
+	}
+	return driver.PrintTree(cmd.OutOrStdout(), root)
+}
+
+type jsonNode struct {
+	Symbol   string      `json:"symbol"`
+	Text     *string     `json:"text,omitempty"`
+	Line     int         `json:"line,omitempty"`
+	Column   int         `json:"column,omitempty"`
+	Children []*jsonNode `json:"children,omitempty"`
+}
+
+func newJSONNode(node *driver.Node) *jsonNode {
+	n := &jsonNode{
+		Symbol: node.Name,
+	}
+	if node.Token != nil {
+		n.Text = &node.Token.Text
+		n.Line = node.Token.Pos.Line
+		n.Column = node.Token.Pos.Column
+	}
+	for _, child := range node.Children {
+		n.Children = append(n.Children, newJSONNode(child))
+	}
+
+	return n
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/nihei9/sousa/writer"
+)
+
+// newStamp returns the stamp of the code generated from grammar files with the current options. The stamp consists of
+// the version of sousa, the options affecting the code, and the hash of the grammar files. files are the grammar file
+// and the files it includes, the grammar file first.
+func newStamp(files []string) (string, error) {
+	// The paths are hashed relative to the grammar file so that the stamp doesn't depend on where the files are.
+	dir := filepath.Dir(files[0])
+	h := sha256.New()
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			rel = file
+		}
+		fmt.Fprintf(h, "%v\n%v\n", filepath.ToSlash(rel), len(src))
+		h.Write(src)
+	}
+
+	return fmt.Sprintf("version=%v lang=%v package=%v compress=%v ast=%v start=%v inputs=%x",
+		version, *genFlags.lang, *genFlags.pkg, *genFlags.compress, *genFlags.ast, strings.Join(*startSymbols, ","), h.Sum(nil)), nil
+}
+
+// upToDate reports whether all the outputs exist and have the stamp.
+func upToDate(outs []*output, stamp string) bool {
+	for _, out := range outs {
+		src, err := ioutil.ReadFile(out.name)
+		if err != nil || writer.ReadGoStamp(src) != stamp {
+			return false
+		}
+	}
+	return true
+}
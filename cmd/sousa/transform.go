@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/nihei9/sousa/grammar/transform"
+	"github.com/nihei9/sousa/printer"
+	"github.com/spf13/cobra"
+)
+
+var transformFlags = struct {
+	leftRecursion *bool
+	leftFactor    *bool
+}{}
+
+func newTransformCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "transform",
+		Short:   "Rewrite a grammar",
+		Long:    `Rewrite a grammar and print it in sousa's grammar syntax.`,
+		Example: `  sousa transform --left-recursion --left-factor grammar.sousa`,
+		Args:    cobra.ExactArgs(1),
+		RunE:    runTransform,
+	}
+	transformFlags.leftRecursion = cmd.Flags().Bool("left-recursion", false, "eliminate direct and indirect left recursion")
+	transformFlags.leftFactor = cmd.Flags().Bool("left-factor", false, "left-factor common prefixes of alternatives")
+
+	return cmd
+}
+
+func runTransform(cmd *cobra.Command, args []string) error {
+	g, err := readGrammar(args[0])
+	if err != nil {
+		return err
+	}
+
+	tg := transform.NewGrammar(g.Productions)
+	if *transformFlags.leftRecursion {
+		tg, err = transform.EliminateLeftRecursion(g.SymbolTable, tg)
+		if err != nil {
+			return err
+		}
+	}
+	if *transformFlags.leftFactor {
+		tg, err = transform.LeftFactor(g.SymbolTable, tg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return printer.FprintProductions(cmd.OutOrStdout(), g.SymbolTable, tg.Productions)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCmd(genCmd *cobra.Command) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify <grammar file>",
+		Short: "Verify that generated files are up to date",
+		Long: `Verify that the files generated from a grammar file are up to date.
+verify generates the files in memory with the same options as sousa and fails when the existing files
+differ from them, for example, because the grammar, the options, or the version of sousa changed.`,
+		Example: `  sousa verify --lang go --package parser grammar.sousa`,
+		Args:    cobra.ExactArgs(1),
+		RunE:    runVerify,
+	}
+	// verify shares the options affecting the output with the root command.
+	for _, name := range []string{"format", "lang", "package", "compress", "ast", "jobs", "out-dir", "prefix"} {
+		cmd.Flags().AddFlag(genCmd.Flags().Lookup(name))
+	}
+
+	return cmd
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	err := checkGenFlags(cmd)
+	if err != nil {
+		return err
+	}
+	if *genFlags.outDir == stdoutName {
+		return fmt.Errorf("cannot verify the standard output")
+	}
+
+	g, files, err := readGrammarWithIncludes(args[0])
+	if err != nil {
+		return err
+	}
+	stamp, err := newStamp(files)
+	if err != nil {
+		return err
+	}
+	outs, err := outputs(g, stamp)
+	if err != nil {
+		return err
+	}
+	parsingTable, err := generateParsingTable(g, *genFlags.jobs)
+	if err != nil {
+		return err
+	}
+
+	stale := []string{}
+	for _, out := range outs {
+		name := filepath.Join(*genFlags.outDir, *genFlags.prefix+out.name)
+		want := new(bytes.Buffer)
+		err := out.newWriter(parsingTable).Write(want)
+		if err != nil {
+			return err
+		}
+		got, err := ioutil.ReadFile(name)
+		if err != nil || !bytes.Equal(got, want.Bytes()) {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("generated files are stale; regenerate them with sousa: %v", strings.Join(stale, ", "))
+	}
+
+	return nil
+}
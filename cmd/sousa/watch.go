@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nihei9/sousa/grammar"
+	"github.com/spf13/cobra"
+)
+
+// watch regenerates the output whenever the grammar file at path or a file it includes changes. It polls the files at
+// each interval and runs until the process is stopped. When regeneration fails, it reports the error and leaves the
+// previous output as it is.
+func watch(cmd *cobra.Command, path string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive: %v", interval)
+	}
+
+	w := &watcher{
+		path:  path,
+		files: []string{path},
+	}
+	for {
+		stamps := w.regenerate(cmd)
+		for !changed(w.files, stamps) {
+			time.Sleep(interval)
+		}
+	}
+}
+
+// watcher holds the state that watch carries from one regeneration to the next.
+type watcher struct {
+	path    string
+	files   []string
+	watched string
+	prev    *tableStats
+}
+
+// regenerate regenerates the output once and returns the stamps of the files read. Like a run without --watch, it
+// leaves up-to-date files as they are and refuses to overwrite files that sousa didn't generate unless --force is
+// given.
+func (w *watcher) regenerate(cmd *cobra.Command) map[string]fileStamp {
+	// The files are stamped before they are read so that a change made while regenerating isn't missed.
+	stamps := stampFiles(w.files)
+
+	g, newFiles, err := readGrammarWithIncludes(w.path)
+	if len(newFiles) > 0 {
+		w.files = newFiles
+	}
+	for _, f := range w.files {
+		if _, ok := stamps[f]; !ok {
+			stamps[f] = stampFile(f)
+		}
+	}
+	if list := strings.Join(w.files, ", "); list != w.watched {
+		cmd.Printf("watching %v\n", list)
+		w.watched = list
+	}
+
+	stamp := ""
+	if err == nil {
+		stamp, err = newStamp(w.files)
+	}
+	var parsingTable *grammar.ParsingTable
+	if err == nil {
+		parsingTable, err = generate(cmd, g, stamp, *genFlags.force)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to regenerate; the previous output is kept: %v\n", err)
+	} else if parsingTable == nil {
+		cmd.Printf("up to date\n")
+		if w.prev == nil {
+			// The stats of the existing output are the base of the diff reported by the next regeneration.
+			parsingTable, err = generateParsingTable(g, *genFlags.jobs)
+			if err == nil {
+				w.prev = newTableStats(parsingTable)
+			}
+		}
+	} else {
+		stats := newTableStats(parsingTable)
+		cmd.Printf("regenerated: %v\n", stats.diff(w.prev))
+		w.prev = stats
+	}
+
+	return stamps
+}
+
+// fileStamp is the state of a file used to detect a change. A missing file has the zero value.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+}
+
+func stampFiles(paths []string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, path := range paths {
+		stamps[path] = stampFile(path)
+	}
+	return stamps
+}
+
+func changed(paths []string, stamps map[string]fileStamp) bool {
+	for _, path := range paths {
+		if stampFile(path) != stamps[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// tableStats is the size of a parsing table and the number of its conflicts. An accept/reduce conflict is counted as a
+// reduce/reduce conflict.
+type tableStats struct {
+	states                int
+	shiftReduceConflicts  int
+	reduceReduceConflicts int
+}
+
+func newTableStats(parsingTable *grammar.ParsingTable) *tableStats {
+	stats := &tableStats{
+		states: len(parsingTable.States()),
+	}
+	for _, as := range parsingTable.Action() {
+		for _, acts := range as.Conflicts() {
+			shift := false
+			for _, act := range acts {
+				if act.Type() == grammar.ActionTypeShift {
+					shift = true
+				}
+			}
+			if shift {
+				stats.shiftReduceConflicts++
+			} else {
+				stats.reduceReduceConflicts++
+			}
+		}
+		if as.ConflictByEOF() {
+			stats.reduceReduceConflicts++
+		}
+	}
+	return stats
+}
+
+// diff describes the stats and how they changed from prev. prev can be nil.
+func (s *tableStats) diff(prev *tableStats) string {
+	if prev == nil {
+		prev = s
+	}
+	count := func(name string, before, after int) string {
+		if before == after {
+			return fmt.Sprintf("%v: %v", name, after)
+		}
+		return fmt.Sprintf("%v: %v -> %v", name, before, after)
+	}
+	return strings.Join([]string{
+		count("states", prev.states, s.states),
+		count("shift/reduce conflicts", prev.shiftReduceConflicts, s.shiftReduceConflicts),
+		count("reduce/reduce conflicts", prev.reduceReduceConflicts, s.reduceReduceConflicts),
+	}, ", ")
+}
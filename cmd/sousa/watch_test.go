@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChanged(t *testing.T) {
+	dir, path := writeGrammar(t)
+	defer os.RemoveAll(dir)
+	missing := filepath.Join(dir, "missing.sousa")
+	paths := []string{path, missing}
+
+	tests := []struct {
+		caption string
+		change  func()
+	}{
+		{
+			caption: "a file is modified",
+			change: func() {
+				writeFile(t, path, "expr: id;\n")
+			},
+		},
+		{
+			caption: "a missing file is created",
+			change: func() {
+				writeFile(t, missing, "expr: id;\n")
+			},
+		},
+		{
+			caption: "a file is removed",
+			change: func() {
+				os.Remove(missing)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			stamps := stampFiles(paths)
+			if changed(paths, stamps) {
+				t.Fatal("the files must not be reported as changed before they change")
+			}
+			tt.change()
+			if !changed(paths, stamps) {
+				t.Fatal("the change must be detected")
+			}
+		})
+	}
+}
+
+func TestTableStats_Diff(t *testing.T) {
+	stats := &tableStats{
+		states:                10,
+		shiftReduceConflicts:  2,
+		reduceReduceConflicts: 1,
+	}
+
+	tests := []struct {
+		caption  string
+		prev     *tableStats
+		expected string
+	}{
+		{
+			caption:  "without previous stats",
+			prev:     nil,
+			expected: "states: 10, shift/reduce conflicts: 2, reduce/reduce conflicts: 1",
+		},
+		{
+			caption:  "with the same stats",
+			prev:     stats,
+			expected: "states: 10, shift/reduce conflicts: 2, reduce/reduce conflicts: 1",
+		},
+		{
+			caption: "with different stats",
+			prev: &tableStats{
+				states:                8,
+				shiftReduceConflicts:  0,
+				reduceReduceConflicts: 1,
+			},
+			expected: "states: 8 -> 10, shift/reduce conflicts: 0 -> 2, reduce/reduce conflicts: 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			if got := stats.diff(tt.prev); got != tt.expected {
+				t.Fatalf("unexpected diff\nwant: %v\ngot: %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWatcher_Regenerate(t *testing.T) {
+	dir, path := writeGrammar(t)
+	defer os.RemoveAll(dir)
+	outDir := filepath.Join(dir, "gen")
+	tablePath := filepath.Join(outDir, "sousa_table.go")
+
+	regenerate := func(w *watcher) string {
+		t.Helper()
+
+		cmd := newCmd()
+		var out bytes.Buffer
+		cmd.SetOutput(&out)
+		err := cmd.ParseFlags([]string{"--lang", "go", "-o", outDir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.regenerate(cmd)
+		return out.String()
+	}
+
+	_, err := runSousa(t, "--lang", "go", "-o", outDir, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stats of the up-to-date output are the base of the next diff.
+	w := &watcher{
+		path:  path,
+		files: []string{path},
+	}
+	out := regenerate(w)
+	if !strings.Contains(out, "up to date") {
+		t.Fatalf("the output must be up to date:\n%v", out)
+	}
+	writeFile(t, path, "%include \"term.sousa\";\nexpr: expr \"+\" term | expr \"-\" term | term;\n")
+	out = regenerate(w)
+	if !strings.Contains(out, "regenerated: states: ") || !strings.Contains(out, " -> ") {
+		t.Fatalf("the diff from the previous stats must be reported:\n%v", out)
+	}
+
+	// A file that sousa didn't generate isn't overwritten even after the output has been regenerated.
+	writeFile(t, tablePath, "my notes\n")
+	writeFile(t, path, "%include \"term.sousa\";\nexpr: expr \"+\" term | term;\n")
+	out = regenerate(w)
+	if strings.Contains(out, "regenerated") {
+		t.Fatalf("the output must not be regenerated:\n%v", out)
+	}
+	if string(readFile(t, tablePath)) != "my notes\n" {
+		t.Fatal("the file was overwritten")
+	}
+}
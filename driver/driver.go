@@ -0,0 +1,457 @@
+// Package driver provides an LR parser driven by a parsing table of the table package.
+package driver
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nihei9/sousa/table"
+)
+
+// Position is a position in the input. Both Line and Column start at 1.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("(%v, %v)", p.Line, p.Column)
+}
+
+// Token is a terminal symbol read from the input. Symbol is a symbol number of the parsing table, and
+// table.SymbolEOF means the end of input.
+type Token struct {
+	Symbol int
+	Text   string
+	Pos    Position
+
+	// Offset is the byte offset of Text in the input.
+	Offset int
+
+	// Trivia holds the white spaces and comments preceding the token. The trivia at the end of input belongs to the
+	// token of table.SymbolEOF.
+	Trivia []*Trivia
+}
+
+// TriviaKind is the kind of trivia.
+type TriviaKind string
+
+const (
+	TriviaKindWhiteSpace = TriviaKind("whitespace")
+	TriviaKindComment    = TriviaKind("comment")
+)
+
+// Trivia is a part of the input that isn't a token, such as white spaces and comments. Offset is the byte offset of
+// Text in the input.
+type Trivia struct {
+	Kind   TriviaKind
+	Text   string
+	Offset int
+	Pos    Position
+}
+
+// TokenStream supplies tokens to a parser. Next returns a token whose symbol is table.SymbolEOF at the end of input.
+type TokenStream interface {
+	Next() (*Token, error)
+}
+
+// Node is a node of a concrete syntax tree. Name is the name of the grammar symbol of the node. A node of a terminal
+// symbol has Token and no children.
+//
+// The tree is lossless: the tokens of the tree, along with their trivia and the trivia at the end of input, which
+// Trailing of the root holds, make up the whole input. Reprint writes it back.
+//
+// Production is the index of the production that a node of a non-terminal symbol was reduced by. It is meaningless
+// for a node of a terminal symbol or the error symbol. State is the state on the top of the stack when the parser
+// began parsing the node, which Reparse uses to decide whether it can reuse the node.
+type Node struct {
+	Symbol     int
+	Name       string
+	Token      *Token
+	Children   []*Node
+	Parent     *Node
+	Trailing   []*Trivia
+	Production int
+	State      int
+}
+
+// SyntaxError is an error that a parser reports when it reads a token the grammar doesn't allow.
+type SyntaxError struct {
+	Token *Token
+
+	// Expected holds the names of the terminal symbols acceptable instead of Token.
+	Expected []string
+
+	name string
+}
+
+// NewSyntaxError returns a SyntaxError at tok. name is the name of the symbol of tok, and expected holds the names of
+// the terminal symbols acceptable instead of it.
+func NewSyntaxError(tok *Token, name string, expected []string) *SyntaxError {
+	return &SyntaxError{
+		Token:    tok,
+		Expected: expected,
+		name:     name,
+	}
+}
+
+func (e *SyntaxError) Error() string {
+	expected := make([]string, len(e.Expected))
+	for i, name := range e.Expected {
+		expected[i] = quoteTerminal(name)
+	}
+
+	switch len(expected) {
+	case 0:
+		return fmt.Sprintf("unexpected %v at %v", quoteTerminal(e.name), e.Token.Pos)
+	case 1:
+		return fmt.Sprintf("unexpected %v at %v; expected %v", quoteTerminal(e.name), e.Token.Pos, expected[0])
+	}
+	return fmt.Sprintf("unexpected %v at %v; expected one of: %v", quoteTerminal(e.name), e.Token.Pos, strings.Join(expected, " "))
+}
+
+// quoteTerminal quotes the name of a terminal symbol unless it consists of letters, digits, and underscores, such as
+// IDENT, so that punctuation like ')' stands out in messages.
+func quoteTerminal(name string) string {
+	if name == nameEOF {
+		return name
+	}
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+			return "'" + name + "'"
+		}
+	}
+
+	return name
+}
+
+// SyntaxErrors is the list of the syntax errors a parser reported while recovering from them.
+type SyntaxErrors []*SyntaxError
+
+func (es SyntaxErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// nameEOF is the name of the end of input in messages.
+const nameEOF = "EOF"
+
+// Parser is an LR parser.
+type Parser struct {
+	table  table.ParsingTable
+	header *table.Header
+	ts     TokenStream
+	entry  int
+	tracer Tracer
+}
+
+// NewParser returns a parser reading tokens from ts. It parses the first start symbol of t unless SetStartSymbol
+// selects another one.
+func NewParser(t table.ParsingTable, ts TokenStream) (*Parser, error) {
+	if t == nil || ts == nil {
+		return nil, fmt.Errorf("parameters passed contains nil")
+	}
+	h := t.TableHeader()
+	if len(h.EntryPoints) <= 0 {
+		return nil, fmt.Errorf("the parsing table has no entry point")
+	}
+
+	return &Parser{
+		table:  t,
+		header: h,
+		ts:     ts,
+		entry:  h.EntryPoints[0].State,
+	}, nil
+}
+
+// SetStartSymbol selects the start symbol to parse.
+func (p *Parser) SetStartSymbol(name string) error {
+	state, ok := p.table.EntryPoint(name)
+	if !ok {
+		return fmt.Errorf("%v is not a start symbol", name)
+	}
+	p.entry = state
+
+	return nil
+}
+
+// SetTracer makes the parser report every step to tracer. A nil tracer disables tracing.
+func (p *Parser) SetTracer(tracer Tracer) {
+	p.tracer = tracer
+}
+
+// Parse parses the tokens and returns the concrete syntax tree whose root is the start symbol.
+//
+// When the grammar doesn't use the terminal symbol error, Parse returns a *SyntaxError at the first token that doesn't
+// conform to the grammar. Otherwise, it recovers from syntax errors as yacc does and returns a SyntaxErrors holding
+// all of them. If it recovers from all the errors, it returns the parse tree along with the SyntaxErrors.
+//
+// On a syntax error, the parser pops states until the top state can shift error, shifts error, and resumes parsing.
+// Until it shifts three more tokens, it discards tokens that cause a syntax error instead of reporting them. A node
+// of error has the nodes popped and the tokens discarded as children.
+func (p *Parser) Parse() (*Node, error) {
+	return p.parse(p.ts, nil)
+}
+
+// parse parses the tokens of ts. When r isn't nil, it pushes the subtrees r finds instead of parsing them again.
+func (p *Parser) parse(ts TokenStream, r *reuser) (*Node, error) {
+	states := []int{p.entry}
+	nodes := []*Node{}
+
+	errSym, recoverable := p.header.ErrorSymbol()
+	synErrs := SyntaxErrors{}
+	// recovering is the number of tokens to shift until the parser reports syntax errors again.
+	recovering := 0
+	var errNode *Node
+
+	// reductions holds the states popped by each reduction since the parser read the lookahead so that the expected
+	// terminals can be computed from the stack before the reductions.
+	reductions := [][]int{}
+
+	tok, err := ts.Next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		state := states[len(states)-1]
+		if r != nil && recovering == 0 {
+			if node, next, ok := r.reuse(p.table, state); ok {
+				if p.tracer != nil {
+					err := p.trace(states, nodes, tok, actionReuse, next)
+					if err != nil {
+						return nil, err
+					}
+				}
+				states = append(states, next)
+				nodes = append(nodes, node)
+				reductions = reductions[:0]
+
+				tok, err = ts.Next()
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		act, n := p.table.Action(state, tok.Symbol)
+		if p.tracer != nil {
+			err := p.trace(states, nodes, tok, act, n)
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch act {
+		case table.ActionTypeShift:
+			leaf := p.leaf(tok)
+			leaf.State = state
+			states = append(states, n)
+			nodes = append(nodes, leaf)
+			reductions = reductions[:0]
+			if recovering > 0 {
+				recovering--
+			}
+
+			tok, err = ts.Next()
+			if err != nil {
+				return nil, err
+			}
+		case table.ActionTypeReduce:
+			prod := p.header.Productions[n]
+			rhsLen := len(prod.RHS)
+			node := &Node{
+				Symbol:     prod.LHS,
+				Name:       p.header.Symbols[prod.LHS].Name,
+				Children:   append([]*Node{}, nodes[len(nodes)-rhsLen:]...),
+				Production: n,
+			}
+			for _, child := range node.Children {
+				child.Parent = node
+			}
+			reductions = append(reductions, append([]int{}, states[len(states)-rhsLen:]...))
+			states = states[:len(states)-rhsLen]
+			nodes = nodes[:len(nodes)-rhsLen]
+			node.State = states[len(states)-1]
+
+			next, ok := p.table.GoTo(states[len(states)-1], prod.LHS)
+			if !ok {
+				return nil, fmt.Errorf("no goto. state: %v, symbol: %v", states[len(states)-1], node.Name)
+			}
+			if p.tracer != nil {
+				err := p.trace(states, nodes, tok, actionGoTo, next)
+				if err != nil {
+					return nil, err
+				}
+			}
+			states = append(states, next)
+			nodes = append(nodes, node)
+		case table.ActionTypeAccept:
+			root := nodes[len(nodes)-1]
+			root.Parent = nil
+			root.Trailing = tok.Trivia
+			if len(synErrs) > 0 {
+				return root, synErrs
+			}
+			return root, nil
+		default:
+			if !recoverable {
+				return nil, p.syntaxError(states, reductions, tok)
+			}
+			if recovering == 0 {
+				synErrs = append(synErrs, p.syntaxError(states, reductions, tok))
+			}
+
+			if recovering == errorRecoveryTokens {
+				if tok.Symbol == table.SymbolEOF {
+					return nil, synErrs
+				}
+				if p.tracer != nil {
+					err := p.trace(states, nodes, tok, actionDiscard, 0)
+					if err != nil {
+						return nil, err
+					}
+				}
+				leaf := p.leaf(tok)
+				leaf.Parent = errNode
+				leaf.State = errNode.State
+				errNode.Children = append(errNode.Children, leaf)
+
+				tok, err = ts.Next()
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			recovering = errorRecoveryTokens
+			reductions = reductions[:0]
+
+			popped := []*Node{}
+			for {
+				act, next := p.table.Action(states[len(states)-1], errSym)
+				if act == table.ActionTypeShift {
+					if p.tracer != nil {
+						err := p.trace(states, nodes, &Token{Symbol: errSym, Pos: tok.Pos}, act, next)
+						if err != nil {
+							return nil, err
+						}
+					}
+					states = append(states, next)
+					break
+				}
+				if len(states) <= 1 {
+					return nil, synErrs
+				}
+				if p.tracer != nil {
+					err := p.trace(states, nodes, tok, actionPop, 0)
+					if err != nil {
+						return nil, err
+					}
+				}
+				popped = append([]*Node{nodes[len(nodes)-1]}, popped...)
+				states = states[:len(states)-1]
+				nodes = nodes[:len(nodes)-1]
+			}
+			errNode = &Node{
+				Symbol:   errSym,
+				Name:     p.header.Symbols[errSym].Name,
+				Children: popped,
+				State:    states[len(states)-2],
+			}
+			for _, child := range popped {
+				child.Parent = errNode
+			}
+			nodes = append(nodes, errNode)
+		}
+	}
+}
+
+// errorRecoveryTokens is the number of tokens a parser must shift after a syntax error to report another one.
+const errorRecoveryTokens = 3
+
+func (p *Parser) leaf(tok *Token) *Node {
+	return &Node{
+		Symbol: tok.Symbol,
+		Name:   p.header.Symbols[tok.Symbol].Name,
+		Token:  tok,
+	}
+}
+
+// These are pseudo action types to trace steps other than actions of the parsing table.
+const (
+	actionGoTo    = table.ActionType(-1)
+	actionPop     = table.ActionType(-2)
+	actionDiscard = table.ActionType(-3)
+	actionReuse   = table.ActionType(-4)
+)
+
+func (p *Parser) trace(states []int, nodes []*Node, tok *Token, act table.ActionType, n int) error {
+	s := &Step{
+		States:        append([]int{}, states...),
+		Symbols:       make([]string, len(nodes)),
+		Lookahead:     tok,
+		LookaheadName: p.symbolName(tok.Symbol),
+	}
+	for i, node := range nodes {
+		s.Symbols[i] = node.Name
+	}
+	switch act {
+	case table.ActionTypeShift:
+		s.Action = StepActionShift
+		s.State = n
+	case table.ActionTypeReduce:
+		s.Action = StepActionReduce
+		s.Production = n
+		s.Rule = ruleString(p.header, n)
+	case actionGoTo:
+		s.Action = StepActionGoTo
+		s.State = n
+	case actionPop:
+		s.Action = StepActionPop
+	case actionDiscard:
+		s.Action = StepActionDiscard
+	case actionReuse:
+		s.Action = StepActionReuse
+		s.State = n
+	case table.ActionTypeAccept:
+		s.Action = StepActionAccept
+	default:
+		s.Action = StepActionError
+	}
+
+	return p.tracer.Trace(s)
+}
+
+func (p *Parser) syntaxError(states []int, reductions [][]int, tok *Token) *SyntaxError {
+	// Restore the stack before the reductions taken on the lookahead.
+	states = append([]int{}, states...)
+	for i := len(reductions) - 1; i >= 0; i-- {
+		states = append(states[:len(states)-1], reductions[i]...)
+	}
+
+	expected := []string{}
+	for _, sym := range p.ExpectedTerminals(states) {
+		expected = append(expected, p.symbolName(sym))
+	}
+
+	return NewSyntaxError(tok, p.symbolName(tok.Symbol), expected)
+}
+
+// ExpectedTerminals returns the terminal symbols that the parser can shift or accept next when its state stack is
+// states. Unlike the ExpectedTerminals method of the parsing table, it follows the reductions, including default
+// reductions of a Packed, that the parser would take before it shifts a terminal symbol. The terminal symbol error is
+// excluded.
+func (p *Parser) ExpectedTerminals(states []int) []int {
+	return table.ExpectedTerminals(p.table, states)
+}
+
+func (p *Parser) symbolName(sym int) string {
+	if sym == table.SymbolEOF {
+		return nameEOF
+	}
+	return p.header.Symbols[sym].Name
+}
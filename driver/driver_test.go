@@ -0,0 +1,485 @@
+package driver
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+	"github.com/nihei9/sousa/table"
+)
+
+func genTable(t *testing.T, src string) *table.Table {
+	t.Helper()
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := grammar.GenerateFirstSets(g.Productions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := grammar.GenerateFollowSets(g.Productions, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := grammar.GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbols...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := grammar.GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab, err := table.New(g.SymbolTable, g.Productions, pt, g.AugmentedStartSymbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tab
+}
+
+func parse(t *testing.T, tab table.ParsingTable, start string, input string) (*Node, error) {
+	t.Helper()
+
+	ts, err := NewWordLexer(tab, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewParser(tab, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != "" {
+		err := p.SetStartSymbol(start)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return p.Parse()
+}
+
+const exprGrammar = `E: E "+" T | T; T: T "*" F | F; F: "(" E ")" | id;`
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		caption string
+		src     string
+		start   string
+		input   string
+		tree    string
+	}{
+		{
+			caption: "arithmetic expression",
+			src:     exprGrammar,
+			input:   "id + id * ( id )",
+			tree: `E
+  E
+    T
+      F
+        id "id"
+  + "+"
+  T
+    T
+      F
+        id "id"
+    * "*"
+    F
+      ( "("
+      E
+        T
+          F
+            id "id"
+      ) ")"
+`,
+		},
+		{
+			caption: "empty production",
+			src:     `list: list item | ; item: a | b;`,
+			input:   "a\nb",
+			tree: `list
+  list
+    list
+    item
+      a "a"
+  item
+    b "b"
+`,
+		},
+		{
+			caption: "second start symbol",
+			src:     `%start stmt expr; stmt: expr ";"; expr: expr "+" id | id;`,
+			start:   "expr",
+			input:   "id + id",
+			tree: `expr
+  expr
+    id "id"
+  + "+"
+  id "id"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			tab := genTable(t, tt.src)
+			for _, pt := range []table.ParsingTable{tab, table.Pack(tab)} {
+				root, err := parse(t, pt, tt.start, tt.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				b := new(bytes.Buffer)
+				err = PrintTree(b, root)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if b.String() != tt.tree {
+					t.Fatalf("unexpected tree\nwant:\n%v\ngot:\n%v", tt.tree, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestParser_SyntaxError(t *testing.T) {
+	tests := []struct {
+		caption  string
+		input    string
+		text     string
+		pos      Position
+		expected []string
+	}{
+		{
+			caption:  "unexpected EOF",
+			input:    "id +",
+			text:     "",
+			pos:      Position{Line: 1, Column: 5},
+			expected: []string{"(", "id"},
+		},
+		{
+			caption:  "unexpected terminal",
+			input:    "id\n+ )",
+			text:     ")",
+			pos:      Position{Line: 2, Column: 3},
+			expected: []string{"(", "id"},
+		},
+		{
+			caption:  "missing closing parenthesis",
+			input:    "( id",
+			text:     "",
+			pos:      Position{Line: 1, Column: 5},
+			expected: []string{"+", "*", ")"},
+		},
+	}
+	tab := genTable(t, exprGrammar)
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			// Default reductions of Packed must not change the expected terminals.
+			for _, pt := range []table.ParsingTable{tab, table.Pack(tab)} {
+				_, err := parse(t, pt, "", tt.input)
+				synErr, ok := err.(*SyntaxError)
+				if !ok {
+					t.Fatalf("a syntax error must occur. got: %v", err)
+				}
+				if synErr.Token.Text != tt.text || synErr.Token.Pos != tt.pos {
+					t.Fatalf("unexpected token\nwant: %q at %v\ngot: %q at %v", tt.text, tt.pos, synErr.Token.Text, synErr.Token.Pos)
+				}
+				if strings.Join(synErr.Expected, " ") != strings.Join(tt.expected, " ") {
+					t.Fatalf("unexpected expected terminals\nwant: %v\ngot: %v", tt.expected, synErr.Expected)
+				}
+			}
+		})
+	}
+}
+
+func TestSyntaxError_Error(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+		msg      string
+	}{
+		{
+			name:     ";",
+			expected: []string{")", ",", "IDENT"},
+			msg:      "unexpected ';' at (1, 2); expected one of: ')' ',' IDENT",
+		},
+		{
+			name:     "IDENT",
+			expected: []string{nameEOF},
+			msg:      "unexpected IDENT at (1, 2); expected EOF",
+		},
+		{
+			name:     nameEOF,
+			expected: []string{},
+			msg:      "unexpected EOF at (1, 2)",
+		},
+	}
+	for _, tt := range tests {
+		e := &SyntaxError{
+			Token: &Token{
+				Pos: Position{Line: 1, Column: 2},
+			},
+			Expected: tt.expected,
+			name:     tt.name,
+		}
+		if e.Error() != tt.msg {
+			t.Fatalf("unexpected message\nwant: %v\ngot: %v", tt.msg, e.Error())
+		}
+	}
+}
+
+func TestWordLexer_UnknownToken(t *testing.T) {
+	tab := genTable(t, exprGrammar)
+	for _, input := range []string{"id + x", "id + E"} {
+		_, err := parse(t, tab, "", input)
+		if err == nil {
+			t.Fatalf("an error must occur. input: %v", input)
+		}
+		if _, ok := err.(*SyntaxError); ok {
+			t.Fatalf("unknown tokens must not cause a syntax error. input: %v", input)
+		}
+	}
+}
+
+func TestParser_ErrorRecovery(t *testing.T) {
+	tab := genTable(t, `stmts: stmts stmt | stmt; stmt: id ";" | error ";";`)
+
+	tests := []struct {
+		caption string
+		input   string
+		tree    string
+		errs    []Position
+	}{
+		{
+			caption: "no error",
+			input:   "id ; id ;",
+			tree: `stmts
+  stmts
+    stmt
+      id "id"
+      ; ";"
+  stmt
+    id "id"
+    ; ";"
+`,
+		},
+		{
+			caption: "errors separated by three or more tokens",
+			input:   "id ; id id ; id ; id ; ; id ;",
+			tree: `stmts
+  stmts
+    stmts
+      stmts
+        stmts
+          stmt
+            id "id"
+            ; ";"
+        stmt
+          error
+            id "id"
+            id "id"
+          ; ";"
+      stmt
+        id "id"
+        ; ";"
+    stmt
+      error
+        id "id"
+        ; ";"
+      ; ";"
+  stmt
+    id "id"
+    ; ";"
+`,
+			errs: []Position{
+				{Line: 1, Column: 9},
+				{Line: 1, Column: 24},
+			},
+		},
+		{
+			caption: "an error right after another error is not reported",
+			input:   "id id ; ; id ;",
+			errs: []Position{
+				{Line: 1, Column: 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			for _, pt := range []table.ParsingTable{tab, table.Pack(tab)} {
+				root, err := parse(t, pt, "", tt.input)
+				if len(tt.errs) == 0 {
+					if err != nil {
+						t.Fatal(err)
+					}
+				} else {
+					synErrs, ok := err.(SyntaxErrors)
+					if !ok {
+						t.Fatalf("syntax errors must occur. got: %v", err)
+					}
+					if len(synErrs) != len(tt.errs) {
+						t.Fatalf("unexpected number of errors\nwant: %v\ngot: %v", len(tt.errs), synErrs)
+					}
+					for i, pos := range tt.errs {
+						if synErrs[i].Token.Pos != pos {
+							t.Fatalf("unexpected position of error #%v\nwant: %v\ngot: %v", i, pos, synErrs[i].Token.Pos)
+						}
+					}
+				}
+				if root == nil {
+					t.Fatalf("the parser must recover from the errors")
+				}
+				// Default reductions of Packed can change the nodes an error node consists of.
+				if tt.tree == "" || pt != table.ParsingTable(tab) {
+					continue
+				}
+				b := new(bytes.Buffer)
+				err = PrintTree(b, root)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if b.String() != tt.tree {
+					t.Fatalf("unexpected tree\nwant:\n%v\ngot:\n%v", tt.tree, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestParser_ErrorRecoveryFailure(t *testing.T) {
+	tab := genTable(t, `stmt: id ";" | error ";";`)
+
+	root, err := parse(t, tab, "", "id ; id")
+	if root != nil {
+		t.Fatalf("the parser must not recover from the error")
+	}
+	synErrs, ok := err.(SyntaxErrors)
+	if !ok || len(synErrs) != 1 {
+		t.Fatalf("a syntax error must occur. got: %v", err)
+	}
+	if strings.Join(synErrs[0].Expected, " ") != "EOF" {
+		t.Fatalf("unexpected expected terminals\nwant: %v\ngot: %v", []string{"EOF"}, synErrs[0].Expected)
+	}
+}
+
+func TestParser_ConcreteSyntaxTree(t *testing.T) {
+	tests := []struct {
+		caption string
+		src     string
+		input   string
+	}{
+		{
+			caption: "white spaces and comments",
+			src:     `list: list item | ; item: a | b | "(" list ")";`,
+			input:   "  // 最初のコメント\n\ta ( b\r\n// nested\n\t) ( )   // trailing comment",
+		},
+		{
+			caption: "empty input",
+			src:     `list: list item | ; item: a | b;`,
+			input:   " // only a comment\n",
+		},
+		{
+			caption: "error recovery",
+			src:     `stmts: stmts stmt | stmt; stmt: id ";" | error ";";`,
+			input:   "id ; id id // oops\n ; id ;\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			tab := genTable(t, tt.src)
+			root, _ := parse(t, tab, "", tt.input)
+			if root == nil {
+				t.Fatalf("the parser must return a tree")
+			}
+
+			b := new(bytes.Buffer)
+			err := Reprint(b, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.input {
+				t.Fatalf("reprinting the tree must reproduce the input\nwant: %q\ngot: %q", tt.input, b.String())
+			}
+
+			for _, tok := range root.Tokens() {
+				if got := tt.input[tok.Offset : tok.Offset+len(tok.Text)]; got != tok.Text {
+					t.Fatalf("unexpected offset of %q\ngot: %q", tok.Text, got)
+				}
+				for _, tr := range tok.Trivia {
+					if got := tt.input[tr.Offset : tr.Offset+len(tr.Text)]; got != tr.Text {
+						t.Fatalf("unexpected offset of %q\ngot: %q", tr.Text, got)
+					}
+					if tr.Kind == TriviaKindComment && !strings.HasPrefix(tr.Text, "//") {
+						t.Fatalf("unexpected comment: %q", tr.Text)
+					}
+				}
+			}
+
+			if root.Parent != nil {
+				t.Fatalf("the root must have no parent")
+			}
+			var check func(n *Node)
+			check = func(n *Node) {
+				if n.Offset() > n.End() {
+					t.Fatalf("%v ends before it begins. offset: %v, end: %v", n.Name, n.Offset(), n.End())
+				}
+				for _, child := range n.Children {
+					if child.Parent != n {
+						t.Fatalf("the parent of %v must be %v", child.Name, n.Name)
+					}
+					if child.Root() != root {
+						t.Fatalf("the root of %v must be the root of the tree", child.Name)
+					}
+					if child.Offset() < n.Offset() || child.End() > n.End() {
+						t.Fatalf("%v at [%v, %v) must be inside %v at [%v, %v)", child.Name, child.Offset(), child.End(), n.Name, n.Offset(), n.End())
+					}
+					check(child)
+				}
+			}
+			check(root)
+		})
+	}
+}
+
+func TestNode_Offset(t *testing.T) {
+	tab := genTable(t, `list: list item | ; item: a | b;`)
+	root, err := parse(t, tab, "", " a  b ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The empty list begins at a.
+	empty := root.Children[0].Children[0]
+	if len(empty.Children) != 0 {
+		t.Fatalf("unexpected tree")
+	}
+	if empty.Offset() != 1 || empty.End() != 1 {
+		t.Fatalf("unexpected span of the empty list\nwant: [1, 1)\ngot: [%v, %v)", empty.Offset(), empty.End())
+	}
+	if root.Offset() != 1 || root.End() != 5 {
+		t.Fatalf("unexpected span of the root\nwant: [1, 5)\ngot: [%v, %v)", root.Offset(), root.End())
+	}
+	item := root.Children[1]
+	if item.Offset() != 4 || item.End() != 5 {
+		t.Fatalf("unexpected span of the second item\nwant: [4, 5)\ngot: [%v, %v)", item.Offset(), item.End())
+	}
+
+	root, err = parse(t, tab, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Offset() != 2 || root.End() != 2 {
+		t.Fatalf("unexpected span of the empty root\nwant: [2, 2)\ngot: [%v, %v)", root.Offset(), root.End())
+	}
+}
@@ -0,0 +1,112 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nihei9/sousa/table"
+)
+
+type wordLexer struct {
+	header *table.Header
+	src    string
+	offset int
+	pos    Position
+}
+
+// NewWordLexer returns a TokenStream that splits src on white spaces. Each word must be the name of a terminal
+// symbol of t. A comment begins with "//" and continues until the end of the line. White spaces and comments are
+// attached to the following token as its trivia.
+func NewWordLexer(t table.ParsingTable, src io.Reader) (TokenStream, error) {
+	b, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &wordLexer{
+		header: t.TableHeader(),
+		src:    string(b),
+		pos: Position{
+			Line:   1,
+			Column: 1,
+		},
+	}, nil
+}
+
+func (l *wordLexer) Next() (*Token, error) {
+	trivia := []*Trivia{}
+	for l.offset < len(l.src) {
+		kind := TriviaKindWhiteSpace
+		pos := l.pos
+		begin := l.offset
+		switch {
+		case unicode.IsSpace(l.peek()):
+			for l.offset < len(l.src) && unicode.IsSpace(l.peek()) {
+				l.advance()
+			}
+		case strings.HasPrefix(l.src[l.offset:], "//"):
+			kind = TriviaKindComment
+			for l.offset < len(l.src) && l.src[l.offset] != '\n' {
+				l.advance()
+			}
+		}
+		if l.offset == begin {
+			break
+		}
+		trivia = append(trivia, &Trivia{
+			Kind:   kind,
+			Text:   l.src[begin:l.offset],
+			Offset: begin,
+			Pos:    pos,
+		})
+	}
+	if l.offset >= len(l.src) {
+		return &Token{
+			Symbol: table.SymbolEOF,
+			Pos:    l.pos,
+			Offset: l.offset,
+			Trivia: trivia,
+		}, nil
+	}
+
+	pos := l.pos
+	begin := l.offset
+	for l.offset < len(l.src) && !unicode.IsSpace(l.peek()) {
+		l.advance()
+	}
+	text := l.src[begin:l.offset]
+
+	sym, ok := l.header.LookupSymbol(text)
+	if !ok || sym >= l.header.Terminals {
+		return nil, fmt.Errorf("unknown token %v at %v", text, pos)
+	}
+
+	return &Token{
+		Symbol: sym,
+		Text:   text,
+		Pos:    pos,
+		Offset: begin,
+		Trivia: trivia,
+	}, nil
+}
+
+// peek returns the rune at the offset. Invalid UTF-8 is read as utf8.RuneError one byte at a time.
+func (l *wordLexer) peek() rune {
+	c, _ := utf8.DecodeRuneInString(l.src[l.offset:])
+	return c
+}
+
+func (l *wordLexer) advance() {
+	c, size := utf8.DecodeRuneInString(l.src[l.offset:])
+	if c == '\n' {
+		l.pos.Line++
+		l.pos.Column = 1
+	} else {
+		l.pos.Column++
+	}
+	l.offset += size
+}
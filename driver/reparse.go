@@ -0,0 +1,205 @@
+package driver
+
+import (
+	"fmt"
+
+	"github.com/nihei9/sousa/table"
+)
+
+// Edit is a change of an input replacing the bytes in [Offset, End) with Text.
+type Edit struct {
+	Offset int
+	End    int
+	Text   string
+}
+
+// Apply returns src changed by e.
+func (e *Edit) Apply(src string) (string, error) {
+	if e.Offset < 0 || e.Offset > e.End || e.End > len(src) {
+		return "", fmt.Errorf("invalid edit. offset: %v, end: %v, length of the input: %v", e.Offset, e.End, len(src))
+	}
+	return src[:e.Offset] + e.Text + src[e.End:], nil
+}
+
+// Reparse parses the input that edit made from the input of old, the tree Parse or Reparse returned, and returns the
+// tree of the edited input. The token stream of the parser must read the edited input.
+//
+// Reparse reads all the tokens, and instead of parsing again, pushes a subtree of old when the state on the top of the
+// stack equals the state stored on the subtree and the symbols of its tokens and the token following it are unchanged.
+// Because an LR parser takes the same actions in the same state on the same symbols, the result is the same tree as
+// Parse returns. Subtrees containing the error symbol are parsed again.
+//
+// The returned tree shares the reused subtrees with old, whose tokens are replaced with the tokens of the edited input,
+// so old must not be used afterward.
+func (p *Parser) Reparse(old *Node, edit *Edit) (*Node, error) {
+	if old == nil || edit == nil {
+		return nil, fmt.Errorf("parameters passed contains nil")
+	}
+	oldLen := old.endOfInput()
+	if edit.Offset < 0 || edit.Offset > edit.End || edit.End > oldLen {
+		return nil, fmt.Errorf("invalid edit. offset: %v, end: %v, length of the input: %v", edit.Offset, edit.End, oldLen)
+	}
+
+	toks := []*Token{}
+	for {
+		tok, err := p.ts.Next()
+		if err != nil {
+			return nil, err
+		}
+		toks = append(toks, tok)
+		if tok.Symbol == table.SymbolEOF {
+			break
+		}
+	}
+
+	errSym, ok := p.header.ErrorSymbol()
+	if !ok {
+		errSym = -1
+	}
+	r := newReuser(old, edit, toks, errSym)
+	return p.parse(r, r)
+}
+
+// reuser finds the subtrees of a previous tree that a parser can reuse, and serves the tokens of the edited input as a
+// TokenStream.
+type reuser struct {
+	toks []*Token
+	// next is the index of the token that Next returns next, so the lookahead of the parser is toks[next-1].
+	next int
+
+	// matches holds the index of the token of the previous input corresponding to each token, or -1. The tokens at
+	// the end of input correspond to each other.
+	matches []int
+	// runs holds the number of tokens from each token that correspond to consecutive tokens of the previous input.
+	runs []int
+
+	// candidates holds the reusable nodes beginning at each token of the previous input, innermost first.
+	candidates [][]*Node
+	// sizes holds the number of tokens of each reusable node.
+	sizes map[*Node]int
+}
+
+func newReuser(old *Node, edit *Edit, toks []*Token, errSym int) *reuser {
+	oldToks := old.Tokens()
+	r := &reuser{
+		toks:       toks,
+		matches:    make([]int, len(toks)),
+		runs:       make([]int, len(toks)),
+		candidates: make([][]*Node, len(oldToks)),
+		sizes:      map[*Node]int{},
+	}
+
+	// Find the tokens of the previous input at the same offsets, which are shifted by the edit after the edited
+	// range.
+	offsets := map[int]int{}
+	for i, tok := range oldToks {
+		offsets[tok.Offset] = i
+	}
+	delta := edit.End - edit.Offset - len(edit.Text)
+	for i, tok := range toks {
+		r.matches[i] = -1
+		if tok.Symbol == table.SymbolEOF {
+			r.matches[i] = len(oldToks)
+			continue
+		}
+		var offset int
+		switch {
+		case tok.Offset < edit.Offset:
+			offset = tok.Offset
+		case tok.Offset >= edit.Offset+len(edit.Text):
+			offset = tok.Offset + delta
+		default:
+			continue
+		}
+		if j, ok := offsets[offset]; ok && oldToks[j].Symbol == tok.Symbol {
+			r.matches[i] = j
+		}
+	}
+	for i := len(toks) - 1; i >= 0; i-- {
+		if r.matches[i] < 0 {
+			continue
+		}
+		r.runs[i] = 1
+		if i+1 < len(toks) && r.matches[i+1] == r.matches[i]+1 {
+			r.runs[i] += r.runs[i+1]
+		}
+	}
+
+	// Collect the nodes of non-terminal symbols having tokens and no error symbol.
+	index := 0
+	var visit func(n *Node) bool
+	visit = func(n *Node) bool {
+		if n.Token != nil {
+			index++
+			return true
+		}
+		begin := index
+		reusable := n.Symbol != errSym
+		for _, child := range n.Children {
+			if !visit(child) {
+				reusable = false
+			}
+		}
+		if reusable && index > begin {
+			r.candidates[begin] = append(r.candidates[begin], n)
+			r.sizes[n] = index - begin
+		}
+		return reusable
+	}
+	visit(old)
+
+	return r
+}
+
+func (r *reuser) Next() (*Token, error) {
+	if r.next >= len(r.toks) {
+		return nil, fmt.Errorf("no more tokens")
+	}
+	tok := r.toks[r.next]
+	r.next++
+	return tok, nil
+}
+
+// reuse returns the outermost node of the previous tree that begins at the lookahead and that the parser can push in
+// state, and the next state. The tokens of the node are replaced with the current ones, and the token following the
+// node becomes the next lookahead.
+func (r *reuser) reuse(t table.ParsingTable, state int) (*Node, int, bool) {
+	i := r.next - 1
+	j := r.matches[i]
+	if j < 0 || j >= len(r.candidates) {
+		return nil, 0, false
+	}
+	cands := r.candidates[j]
+	for k := len(cands) - 1; k >= 0; k-- {
+		node := cands[k]
+		size := r.sizes[node]
+		// The token following the node must be unchanged too because it decided the reductions of the node.
+		if node.State != state || r.runs[i] < size+1 {
+			continue
+		}
+		next, ok := t.GoTo(state, node.Symbol)
+		if !ok {
+			continue
+		}
+
+		toks := r.toks[i : i+size]
+		var replace func(n *Node)
+		replace = func(n *Node) {
+			if n.Token != nil {
+				n.Token = toks[0]
+				toks = toks[1:]
+				return
+			}
+			for _, child := range n.Children {
+				replace(child)
+			}
+		}
+		replace(node)
+		node.Trailing = nil
+		r.next = i + size
+
+		return node, next, true
+	}
+
+	return nil, 0, false
+}
@@ -0,0 +1,222 @@
+package driver
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/table"
+)
+
+type reuseCounter struct {
+	count int
+}
+
+func (c *reuseCounter) Trace(s *Step) error {
+	if s.Action == StepActionReuse {
+		c.count++
+	}
+	return nil
+}
+
+func reparse(t *testing.T, tab table.ParsingTable, old *Node, edit *Edit, input string, tracer Tracer) (*Node, error) {
+	t.Helper()
+
+	ts, err := NewWordLexer(tab, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewParser(tab, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetTracer(tracer)
+
+	return p.Reparse(old, edit)
+}
+
+// dumpTree returns a string of a tree including everything Reparse must reproduce.
+func dumpTree(t *testing.T, root *Node) string {
+	t.Helper()
+
+	var b strings.Builder
+	var dump func(n *Node, indent string)
+	dump = func(n *Node, indent string) {
+		fmt.Fprintf(&b, "%v%v state: %v", indent, n.Name, n.State)
+		if n.Token != nil {
+			fmt.Fprintf(&b, " %q %v %v", n.Token.Text, n.Token.Offset, n.Token.Pos)
+			for _, tr := range n.Token.Trivia {
+				fmt.Fprintf(&b, " %v:%q", tr.Offset, tr.Text)
+			}
+		} else if len(n.Children) > 0 || n.Production != 0 {
+			fmt.Fprintf(&b, " production: %v", n.Production)
+		}
+		fmt.Fprintf(&b, "\n")
+		for _, child := range n.Children {
+			if child.Parent != n {
+				t.Fatalf("the parent of %v must be %v", child.Name, n.Name)
+			}
+			dump(child, indent+"  ")
+		}
+	}
+	dump(root, "")
+	for _, tr := range root.Trailing {
+		fmt.Fprintf(&b, "trailing %v:%q\n", tr.Offset, tr.Text)
+	}
+	if root.Parent != nil {
+		t.Fatalf("the root must have no parent")
+	}
+
+	return b.String()
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+func TestParser_Reparse(t *testing.T) {
+	tests := []struct {
+		caption string
+		src     string
+		input   string
+		words   []string
+	}{
+		{
+			caption: "expressions",
+			src:     `E: E "+" T | T; T: T "*" F | F; F: "(" E ")" | x;`,
+			input:   "x + ( x * x ) * x + ( ( x ) )",
+			words:   []string{"x", "+", "*", "(", ")", " ", "// c\n"},
+		},
+		{
+			caption: "empty productions",
+			src:     `list: list item | ; item: a | b | "(" list ")" | opt c; opt: d | ;`,
+			input:   "a ( b c ( ) d c ) b",
+			words:   []string{"a", "b", "c", "d", "(", ")", " "},
+		},
+		{
+			caption: "error recovery",
+			src:     `stmts: stmts stmt | stmt; stmt: id ";" | "{" stmts "}" | error ";";`,
+			input:   "id ; { id ; id ; } id ;",
+			words:   []string{"id", ";", "{", "}", " "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			tab := genTable(t, tt.src)
+			testReparse(t, tab, tt.input, tt.words)
+			testReparse(t, table.Pack(tab), tt.input, tt.words)
+		})
+	}
+}
+
+// testReparse applies random edits to input one after another and checks that Reparse returns the same result as
+// Parse.
+func testReparse(t *testing.T, tab table.ParsingTable, input string, words []string) {
+	t.Helper()
+
+	rnd := rand.New(rand.NewSource(1))
+
+	src := input
+	tree, err := parse(t, tab, "", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &reuseCounter{}
+	for i := 0; i < 500; i++ {
+		offset := rnd.Intn(len(src) + 1)
+		end := offset + rnd.Intn(len(src)-offset+1)/4
+		text := ""
+		for n := rnd.Intn(3); n > 0; n-- {
+			text += " " + words[rnd.Intn(len(words))] + " "
+		}
+		edit := &Edit{
+			Offset: offset,
+			End:    end,
+			Text:   text,
+		}
+		newSrc, err := edit.Apply(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, wantErr := parseOrError(tab, newSrc)
+		got, gotErr := reparse(t, tab, tree, edit, newSrc, counter)
+		if errorString(gotErr) != errorString(wantErr) {
+			t.Fatalf("unexpected error of %q\nwant: %v\ngot: %v", newSrc, wantErr, gotErr)
+		}
+		if (got == nil) != (want == nil) {
+			t.Fatalf("unexpected tree of %q\nwant: %v\ngot: %v", newSrc, want, got)
+		}
+		if got == nil {
+			src = input
+			tree, err = parse(t, tab, "", src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if dumpTree(t, got) != dumpTree(t, want) {
+			t.Fatalf("unexpected tree of %q\nwant:\n%v\ngot:\n%v", newSrc, dumpTree(t, want), dumpTree(t, got))
+		}
+
+		src = newSrc
+		tree = got
+	}
+	if counter.count == 0 {
+		t.Fatalf("no subtree was reused")
+	}
+}
+
+func parseOrError(tab table.ParsingTable, input string) (*Node, error) {
+	ts, err := NewWordLexer(tab, strings.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewParser(tab, ts)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse()
+}
+
+func TestParser_ReparseReusesSubtrees(t *testing.T) {
+	tab := genTable(t, `list: list item | ; item: a | "(" list ")";`)
+	src := "( a a ) ( a ) a"
+	old, err := parse(t, tab, "", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := old.Children[0].Children[0].Children[1]
+
+	// Append a to the end.
+	edit := &Edit{
+		Offset: len(src),
+		End:    len(src),
+		Text:   " a",
+	}
+	newSrc, err := edit.Apply(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &reuseCounter{}
+	root, err := reparse(t, tab, old, edit, newSrc, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The list of the first two items is reused as a whole. The whole list isn't because EOF following it changed.
+	if counter.count != 1 {
+		t.Fatalf("unexpected number of reused subtrees\nwant: 1\ngot: %v", counter.count)
+	}
+	if root.Children[0].Children[0].Children[0].Children[1] != first {
+		t.Fatalf("the first item must be reused")
+	}
+
+	_, err = reparse(t, tab, root, &Edit{Offset: 0, End: len(newSrc) + 1}, newSrc, nil)
+	if err == nil {
+		t.Fatalf("an edit out of the input must cause an error")
+	}
+}
@@ -0,0 +1,141 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nihei9/sousa/table"
+)
+
+// StepAction is the kind of a step of parsing.
+type StepAction string
+
+const (
+	StepActionShift  = StepAction("shift")
+	StepActionReduce = StepAction("reduce")
+	StepActionGoTo   = StepAction("goto")
+	StepActionAccept = StepAction("accept")
+	StepActionError  = StepAction("error")
+
+	// StepActionPop pops a state to recover from a syntax error, and StepActionDiscard discards the lookahead.
+	StepActionPop     = StepAction("pop")
+	StepActionDiscard = StepAction("discard")
+
+	// StepActionReuse pushes a subtree of a previous tree beginning at the lookahead during reparsing.
+	StepActionReuse = StepAction("reuse")
+)
+
+// Step is a step of parsing. States and Symbols are the stacks before the step is taken; States has one more element
+// than Symbols because it includes the initial state.
+type Step struct {
+	States    []int
+	Symbols   []string
+	Lookahead *Token
+
+	// LookaheadName is the name of the symbol of Lookahead.
+	LookaheadName string
+
+	Action StepAction
+
+	// State is the next state of a shift, a goto, or a reuse.
+	State int
+
+	// Production is the production of a reduce, and Rule is its readable form such as E → E + T.
+	Production int
+	Rule       string
+}
+
+// Tracer receives the steps of parsing. When Trace returns an error, the parser stops and returns it.
+type Tracer interface {
+	Trace(s *Step) error
+}
+
+type textTracer struct {
+	w io.Writer
+}
+
+// NewTextTracer returns a Tracer that writes one line for each step.
+func NewTextTracer(w io.Writer) Tracer {
+	return &textTracer{
+		w: w,
+	}
+}
+
+func (t *textTracer) Trace(s *Step) error {
+	var action string
+	switch s.Action {
+	case StepActionShift, StepActionGoTo, StepActionReuse:
+		action = fmt.Sprintf("%v %v", s.Action, s.State)
+	case StepActionReduce:
+		action = fmt.Sprintf("%v %v (%v)", s.Action, s.Production, s.Rule)
+	default:
+		action = string(s.Action)
+	}
+
+	states := make([]string, len(s.States))
+	for i, state := range s.States {
+		states[i] = strconv.Itoa(state)
+	}
+
+	_, err := fmt.Fprintf(t.w, "states: [%v] symbols: [%v] lookahead: %v %v action: %v\n",
+		strings.Join(states, " "), strings.Join(s.Symbols, " "), s.LookaheadName, strconv.Quote(s.Lookahead.Text), action)
+	return err
+}
+
+type jsonTracer struct {
+	enc *json.Encoder
+}
+
+// NewJSONTracer returns a Tracer that writes a JSON object for each step, one per line.
+func NewJSONTracer(w io.Writer) Tracer {
+	return &jsonTracer{
+		enc: json.NewEncoder(w),
+	}
+}
+
+type jsonStep struct {
+	States     []int      `json:"states"`
+	Symbols    []string   `json:"symbols"`
+	Lookahead  string     `json:"lookahead"`
+	Text       string     `json:"text"`
+	Action     StepAction `json:"action"`
+	State      *int       `json:"state,omitempty"`
+	Production *int       `json:"production,omitempty"`
+	Rule       string     `json:"rule,omitempty"`
+}
+
+func (t *jsonTracer) Trace(s *Step) error {
+	js := &jsonStep{
+		States:    s.States,
+		Symbols:   s.Symbols,
+		Lookahead: s.LookaheadName,
+		Text:      s.Lookahead.Text,
+		Action:    s.Action,
+	}
+	switch s.Action {
+	case StepActionShift, StepActionGoTo, StepActionReuse:
+		js.State = &s.State
+	case StepActionReduce:
+		js.Production = &s.Production
+		js.Rule = s.Rule
+	}
+
+	return t.enc.Encode(js)
+}
+
+// ruleString returns a readable form of a production such as E → E + T.
+func ruleString(h *table.Header, prod int) string {
+	p := h.Productions[prod]
+	rhs := make([]string, len(p.RHS))
+	for i, sym := range p.RHS {
+		rhs[i] = h.Symbols[sym].Name
+	}
+	if len(rhs) == 0 {
+		rhs = []string{"ε"}
+	}
+
+	return fmt.Sprintf("%v → %v", h.Symbols[p.LHS].Name, strings.Join(rhs, " "))
+}
@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParser_Trace(t *testing.T) {
+	tab := genTable(t, `list: list a | b;`)
+
+	tests := []struct {
+		caption   string
+		input     string
+		newTracer func(*bytes.Buffer) Tracer
+		trace     string
+	}{
+		{
+			caption:   "text",
+			input:     "b a",
+			newTracer: func(b *bytes.Buffer) Tracer { return NewTextTracer(b) },
+			trace: `states: [0] symbols: [] lookahead: b "b" action: shift 2
+states: [0 2] symbols: [b] lookahead: a "a" action: reduce 2 (list → b)
+states: [0] symbols: [] lookahead: a "a" action: goto 1
+states: [0 1] symbols: [list] lookahead: a "a" action: shift 3
+states: [0 1 3] symbols: [list a] lookahead: EOF "" action: reduce 1 (list → list a)
+states: [0] symbols: [] lookahead: EOF "" action: goto 1
+states: [0 1] symbols: [list] lookahead: EOF "" action: accept
+`,
+		},
+		{
+			caption:   "JSON lines",
+			input:     "b b",
+			newTracer: func(b *bytes.Buffer) Tracer { return NewJSONTracer(b) },
+			trace: `{"states":[0],"symbols":[],"lookahead":"b","text":"b","action":"shift","state":2}
+{"states":[0,2],"symbols":["b"],"lookahead":"b","text":"b","action":"error"}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			ts, err := NewWordLexer(tab, strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := NewParser(tab, ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := new(bytes.Buffer)
+			p.SetTracer(tt.newTracer(b))
+			p.Parse()
+			if b.String() != tt.trace {
+				t.Fatalf("unexpected trace\nwant:\n%v\ngot:\n%v", tt.trace, b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// PrintTree prints a parse tree with one node per line, indenting children by two spaces. A terminal symbol is
+// followed by the quoted text of its token.
+func PrintTree(w io.Writer, node *Node) error {
+	return printTree(w, node, "")
+}
+
+func printTree(w io.Writer, node *Node, indent string) error {
+	var err error
+	if node.Token != nil {
+		_, err = fmt.Fprintf(w, "%v%v %v\n", indent, node.Name, strconv.Quote(node.Token.Text))
+	} else {
+		_, err = fmt.Fprintf(w, "%v%v\n", indent, node.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, child := range node.Children {
+		err := printTree(w, child, indent+"  ")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reprint writes the source text of a tree: the trivia and the text of each token in order, followed by Trailing of
+// root. Reprinting the tree of a whole input reproduces the input exactly.
+func Reprint(w io.Writer, root *Node) error {
+	for _, tok := range root.Tokens() {
+		err := writeTrivia(w, tok.Trivia)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, tok.Text)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeTrivia(w, root.Trailing)
+}
+
+func writeTrivia(w io.Writer, trivia []*Trivia) error {
+	for _, t := range trivia {
+		_, err := io.WriteString(w, t.Text)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tokens returns the tokens of the leaves under n in order.
+func (n *Node) Tokens() []*Token {
+	toks := []*Token{}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if n.Token != nil {
+			toks = append(toks, n.Token)
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	visit(n)
+
+	return toks
+}
+
+// Offset returns the byte offset at which n begins. Offsets exclude trivia, so a node begins at its first token.
+// An empty node begins at the token following it, or at the end of input when no token follows it.
+func (n *Node) Offset() int {
+	if tok := n.firstToken(); tok != nil {
+		return tok.Offset
+	}
+
+	for node := n; node.Parent != nil; node = node.Parent {
+		siblings := node.Parent.Children
+		i := 0
+		for i < len(siblings) && siblings[i] != node {
+			i++
+		}
+		for _, sibling := range siblings[i+1:] {
+			if tok := sibling.firstToken(); tok != nil {
+				return tok.Offset
+			}
+		}
+	}
+
+	return n.Root().endOfInput()
+}
+
+// End returns the byte offset just after the last token of n. It is equal to Offset for an empty node.
+func (n *Node) End() int {
+	if tok := n.lastToken(); tok != nil {
+		return tok.Offset + len(tok.Text)
+	}
+	return n.Offset()
+}
+
+// Root returns the root of the tree that n belongs to.
+func (n *Node) Root() *Node {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n
+}
+
+// endOfInput returns the byte offset of the end of input when n is the root of a whole input.
+func (n *Node) endOfInput() int {
+	if len(n.Trailing) > 0 {
+		last := n.Trailing[len(n.Trailing)-1]
+		return last.Offset + len(last.Text)
+	}
+	if tok := n.lastToken(); tok != nil {
+		return tok.Offset + len(tok.Text)
+	}
+	return 0
+}
+
+func (n *Node) firstToken() *Token {
+	if n.Token != nil {
+		return n.Token
+	}
+	for _, child := range n.Children {
+		if tok := child.firstToken(); tok != nil {
+			return tok
+		}
+	}
+	return nil
+}
+
+func (n *Node) lastToken() *Token {
+	if n.Token != nil {
+		return n.Token
+	}
+	for i := len(n.Children) - 1; i >= 0; i-- {
+		if tok := n.Children[i].lastToken(); tok != nil {
+			return tok
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package glr
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nihei9/sousa/driver"
+)
+
+// Node is a node of a shared packed parse forest. It stands for all the derivations of Symbol that span the tokens
+// from Start to End - 1, and the nodes of a symbol that span the same tokens are shared.
+//
+// A node of a terminal symbol has Token and no alternatives. A node of a non-terminal symbol has one alternative for
+// each derivation, so it's ambiguous when it has two or more alternatives.
+type Node struct {
+	Symbol       int
+	Name         string
+	Token        *driver.Token
+	Start        int
+	End          int
+	Alternatives []*Alternative
+}
+
+// Alternative is a derivation of a node by Production. Children may be shared with other alternatives.
+type Alternative struct {
+	Production int
+	Children   []*Node
+}
+
+// Ambiguous reports whether the node has two or more alternatives.
+func (n *Node) Ambiguous() bool {
+	return len(n.Alternatives) > 1
+}
+
+func (n *Node) addAlternative(prod int, children []*Node) {
+	for _, alt := range n.Alternatives {
+		if alt.Production != prod || len(alt.Children) != len(children) {
+			continue
+		}
+		same := true
+		for i, child := range alt.Children {
+			if child != children[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+
+	n.Alternatives = append(n.Alternatives, &Alternative{
+		Production: prod,
+		Children:   children,
+	})
+}
+
+// Ambiguities returns the ambiguous nodes reachable from root in depth-first order. Each node appears once.
+func Ambiguities(root *Node) []*Node {
+	nodes := []*Node{}
+	visited := map[*Node]bool{}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if visited[n] {
+			return
+		}
+		visited[n] = true
+		if n.Ambiguous() {
+			nodes = append(nodes, n)
+		}
+		for _, alt := range n.Alternatives {
+			for _, child := range alt.Children {
+				visit(child)
+			}
+		}
+	}
+	visit(root)
+
+	return nodes
+}
+
+// Chooser chooses one of the alternatives of an ambiguous node and returns its index. It returns an error to reject
+// all of them.
+type Chooser func(n *Node) (int, error)
+
+// ChooseFirst is a Chooser that always chooses the first alternative, the one found first while parsing.
+func ChooseFirst(n *Node) (int, error) {
+	return 0, nil
+}
+
+// PreferProductions returns a Chooser that chooses the alternative whose production comes first in prods. When none
+// of the alternatives has such a production, it falls back on fallback.
+//
+// For example, given E → E + E (production 1) and E → E * E (production 2), the node of id + id * id has two
+// alternatives. Preferring production 1 chooses E + E whose right operand is id * id, so * binds tighter than +.
+func PreferProductions(fallback Chooser, prods ...int) Chooser {
+	return func(n *Node) (int, error) {
+		for _, prod := range prods {
+			for i, alt := range n.Alternatives {
+				if alt.Production == prod {
+					return i, nil
+				}
+			}
+		}
+		return fallback(n)
+	}
+}
+
+// Tree converts the forest into a parse tree, calling choose on each ambiguous node to select its derivation. Shared
+// nodes of the forest become distinct nodes of the tree. Tree returns an error when choose does or when the chosen
+// derivations form a cycle, which happens with a grammar deriving a symbol from itself such as A → A.
+func Tree(root *Node, choose Chooser) (*driver.Node, error) {
+	return tree(root, choose, map[*Node]bool{})
+}
+
+func tree(n *Node, choose Chooser, ancestors map[*Node]bool) (*driver.Node, error) {
+	if n.Token != nil {
+		return &driver.Node{
+			Symbol: n.Symbol,
+			Name:   n.Name,
+			Token:  n.Token,
+		}, nil
+	}
+	if ancestors[n] {
+		return nil, fmt.Errorf("the derivation of %v is cyclic", n.Name)
+	}
+	if len(n.Alternatives) == 0 {
+		return nil, fmt.Errorf("%v has no alternative", n.Name)
+	}
+
+	alt := n.Alternatives[0]
+	if n.Ambiguous() {
+		i, err := choose(n)
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= len(n.Alternatives) {
+			return nil, fmt.Errorf("invalid alternative of %v. alternatives: %v, got: %v", n.Name, len(n.Alternatives), i)
+		}
+		alt = n.Alternatives[i]
+	}
+
+	ancestors[n] = true
+	defer delete(ancestors, n)
+
+	node := &driver.Node{
+		Symbol:     n.Symbol,
+		Name:       n.Name,
+		Children:   make([]*driver.Node, len(alt.Children)),
+		Production: alt.Production,
+	}
+	for i, child := range alt.Children {
+		c, err := tree(child, choose, ancestors)
+		if err != nil {
+			return nil, err
+		}
+		c.Parent = node
+		node.Children[i] = c
+	}
+
+	return node, nil
+}
+
+// PrintForest prints a forest in the format of driver.PrintTree. The alternatives of an ambiguous node are printed
+// below it as numbered lines such as "#1", each followed by the children of the alternative. A node that derives
+// itself is printed as "..." the second time.
+func PrintForest(w io.Writer, root *Node) error {
+	return printForest(w, root, "", map[*Node]bool{})
+}
+
+func printForest(w io.Writer, n *Node, indent string, ancestors map[*Node]bool) error {
+	if n.Token != nil {
+		_, err := fmt.Fprintf(w, "%v%v %v\n", indent, n.Name, strconv.Quote(n.Token.Text))
+		return err
+	}
+	if ancestors[n] {
+		_, err := fmt.Fprintf(w, "%v%v ...\n", indent, n.Name)
+		return err
+	}
+	ancestors[n] = true
+	defer delete(ancestors, n)
+
+	_, err := fmt.Fprintf(w, "%v%v\n", indent, n.Name)
+	if err != nil {
+		return err
+	}
+
+	if !n.Ambiguous() {
+		for _, alt := range n.Alternatives {
+			for _, child := range alt.Children {
+				err := printForest(w, child, indent+"  ", ancestors)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for i, alt := range n.Alternatives {
+		_, err := fmt.Fprintf(w, "%v  #%v\n", indent, i+1)
+		if err != nil {
+			return err
+		}
+		for _, child := range alt.Children {
+			err := printForest(w, child, indent+"    ", ancestors)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+// Package glr provides a GLR parser for ambiguous grammars. It takes all the conflicting actions of a parsing table
+// of the table package, keeping the parsers that follow them in a graph-structured stack, and returns all the parse
+// trees as a shared packed parse forest.
+package glr
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nihei9/sousa/driver"
+	"github.com/nihei9/sousa/table"
+)
+
+// nameEOF is the name of the end of input in messages, the same as the one of the driver package.
+const nameEOF = "EOF"
+
+// Parser is a GLR parser.
+type Parser struct {
+	table  table.ParsingTable
+	header *table.Header
+	ts     driver.TokenStream
+	entry  int
+}
+
+// NewParser returns a parser reading tokens from ts. It parses the first start symbol of t unless SetStartSymbol
+// selects another one.
+func NewParser(t table.ParsingTable, ts driver.TokenStream) (*Parser, error) {
+	if t == nil || ts == nil {
+		return nil, fmt.Errorf("parameters passed contains nil")
+	}
+	h := t.TableHeader()
+	if len(h.EntryPoints) <= 0 {
+		return nil, fmt.Errorf("the parsing table has no entry point")
+	}
+
+	return &Parser{
+		table:  t,
+		header: h,
+		ts:     ts,
+		entry:  h.EntryPoints[0].State,
+	}, nil
+}
+
+// SetStartSymbol selects the start symbol to parse.
+func (p *Parser) SetStartSymbol(name string) error {
+	state, ok := p.table.EntryPoint(name)
+	if !ok {
+		return fmt.Errorf("%v is not a start symbol", name)
+	}
+	p.entry = state
+
+	return nil
+}
+
+// stackNode is a node of the graph-structured stack. It stands for the stacks whose top state is state after the
+// parser has read pos tokens, and its edges point to the nodes below it.
+type stackNode struct {
+	state int
+	pos   int
+	edges []*stackEdge
+}
+
+// stackEdge is an edge of the graph-structured stack labeled with the node of the forest it shifted or reduced.
+type stackEdge struct {
+	to   *stackNode
+	node *Node
+}
+
+func (n *stackNode) edgeTo(to *stackNode) *stackEdge {
+	for _, e := range n.edges {
+		if e.to == to {
+			return e
+		}
+	}
+	return nil
+}
+
+// level holds the top nodes of the graph-structured stack after the parser has read pos tokens. It has at most one
+// node for each state.
+type level struct {
+	pos   int
+	nodes map[int]*stackNode
+	order []*stackNode
+}
+
+func newLevel(pos int) *level {
+	return &level{
+		pos:   pos,
+		nodes: map[int]*stackNode{},
+		order: []*stackNode{},
+	}
+}
+
+// node returns the node of state, creating it if the level doesn't have one yet. The second value reports whether
+// the node was created.
+func (l *level) node(state int) (*stackNode, bool) {
+	if n, ok := l.nodes[state]; ok {
+		return n, false
+	}
+	n := &stackNode{
+		state: state,
+		pos:   l.pos,
+		edges: []*stackEdge{},
+	}
+	l.nodes[state] = n
+	l.order = append(l.order, n)
+	return n, true
+}
+
+// forestKey identifies a node of the forest. The nodes of a symbol that span the same tokens are shared.
+type forestKey struct {
+	symbol int
+	start  int
+	end    int
+}
+
+// Parse parses the tokens and returns the root of the shared packed parse forest, which holds all the parse trees
+// of the start symbol. It returns a *driver.SyntaxError when no parser can read a token.
+//
+// The parser doesn't recover from syntax errors, and the terminal symbol error is never shifted.
+func (p *Parser) Parse() (*Node, error) {
+	cur := newLevel(0)
+	cur.node(p.entry)
+	forest := map[forestKey]*Node{}
+
+	for {
+		tok, err := p.ts.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		p.reduceAll(cur, tok, forest)
+
+		if tok.Symbol == table.SymbolEOF {
+			for _, n := range cur.order {
+				for _, a := range table.AllActions(p.table, n.state, tok.Symbol) {
+					if a.Type == table.ActionTypeAccept && len(n.edges) > 0 {
+						return n.edges[0].node, nil
+					}
+				}
+			}
+			return nil, p.syntaxError(cur, tok)
+		}
+
+		next := p.shiftAll(cur, tok)
+		if len(next.order) == 0 {
+			return nil, p.syntaxError(cur, tok)
+		}
+		cur = next
+	}
+}
+
+// reduceAll takes all the reductions on tok in the level until no more edge is added. Because a reduction may add an
+// edge to a node whose reductions have already been taken, the parser takes the reductions of all the nodes again
+// whenever that happens. The forest shares nodes, so taking a reduction again adds nothing but the edges missing.
+func (p *Parser) reduceAll(l *level, tok *driver.Token, forest map[forestKey]*Node) {
+	for {
+		added := false
+		for i := 0; i < len(l.order); i++ {
+			n := l.order[i]
+			for _, a := range table.AllActions(p.table, n.state, tok.Symbol) {
+				if a.Type != table.ActionTypeReduce {
+					continue
+				}
+				prod := p.header.Productions[a.N]
+				for _, path := range paths(n, len(prod.RHS)) {
+					if p.reduce(l, path, a.N, forest) {
+						added = true
+					}
+				}
+			}
+		}
+		if !added {
+			return
+		}
+	}
+}
+
+// reduce reduces the nodes on path by prod. It reports whether an edge was added to a node that already existed.
+func (p *Parser) reduce(l *level, path *path, prod int, forest map[forestKey]*Node) bool {
+	lhs := p.header.Productions[prod].LHS
+	next, ok := p.table.GoTo(path.bottom.state, lhs)
+	if !ok {
+		return false
+	}
+
+	key := forestKey{
+		symbol: lhs,
+		start:  path.bottom.pos,
+		end:    l.pos,
+	}
+	node, ok := forest[key]
+	if !ok {
+		node = &Node{
+			Symbol:       lhs,
+			Name:         p.header.Symbols[lhs].Name,
+			Start:        key.start,
+			End:          key.end,
+			Alternatives: []*Alternative{},
+		}
+		forest[key] = node
+	}
+	node.addAlternative(prod, path.children)
+
+	top, created := l.node(next)
+	if top.edgeTo(path.bottom) != nil {
+		return false
+	}
+	top.edges = append(top.edges, &stackEdge{
+		to:   path.bottom,
+		node: node,
+	})
+
+	return !created
+}
+
+// shiftAll shifts tok on all the nodes of the level that can shift it and returns the next level.
+func (p *Parser) shiftAll(l *level, tok *driver.Token) *level {
+	next := newLevel(l.pos + 1)
+	leaf := &Node{
+		Symbol: tok.Symbol,
+		Name:   p.header.Symbols[tok.Symbol].Name,
+		Token:  tok,
+		Start:  l.pos,
+		End:    l.pos + 1,
+	}
+	for _, n := range l.order {
+		for _, a := range table.AllActions(p.table, n.state, tok.Symbol) {
+			if a.Type != table.ActionTypeShift {
+				continue
+			}
+			top, _ := next.node(a.N)
+			if top.edgeTo(n) != nil {
+				continue
+			}
+			top.edges = append(top.edges, &stackEdge{
+				to:   n,
+				node: leaf,
+			})
+		}
+	}
+
+	return next
+}
+
+// path is a path of length len(children) in the graph-structured stack from a top node to bottom.
+type path struct {
+	bottom   *stackNode
+	children []*Node
+}
+
+// paths returns all the paths of length length from n.
+func paths(n *stackNode, length int) []*path {
+	if length == 0 {
+		return []*path{
+			{
+				bottom:   n,
+				children: []*Node{},
+			},
+		}
+	}
+
+	ps := []*path{}
+	for _, e := range n.edges {
+		for _, p := range paths(e.to, length-1) {
+			ps = append(ps, &path{
+				bottom:   p.bottom,
+				children: append(append([]*Node{}, p.children...), e.node),
+			})
+		}
+	}
+
+	return ps
+}
+
+// syntaxError returns a syntax error at tok. The expected terminals are the ones on which any node of the level has
+// an action.
+func (p *Parser) syntaxError(l *level, tok *driver.Token) *driver.SyntaxError {
+	errSym, recoverable := p.header.ErrorSymbol()
+	terms := map[int]bool{}
+	for _, n := range l.order {
+		for _, sym := range p.table.ExpectedTerminals(n.state) {
+			if recoverable && sym == errSym {
+				continue
+			}
+			terms[sym] = true
+		}
+	}
+	syms := make([]int, 0, len(terms))
+	for sym := range terms {
+		syms = append(syms, sym)
+	}
+	sort.Ints(syms)
+
+	expected := make([]string, len(syms))
+	for i, sym := range syms {
+		expected[i] = p.symbolName(sym)
+	}
+
+	return driver.NewSyntaxError(tok, p.symbolName(tok.Symbol), expected)
+}
+
+func (p *Parser) symbolName(sym int) string {
+	if sym == table.SymbolEOF {
+		return nameEOF
+	}
+	return p.header.Symbols[sym].Name
+}
@@ -0,0 +1,304 @@
+package glr
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/driver"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+	"github.com/nihei9/sousa/table"
+)
+
+func genTable(t *testing.T, src string) *table.Table {
+	t.Helper()
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := grammar.GenerateFirstSets(g.Productions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := grammar.GenerateFollowSets(g.Productions, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := grammar.GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbols...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := grammar.GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tab, err := table.New(g.SymbolTable, g.Productions, pt, g.AugmentedStartSymbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tab
+}
+
+func parse(t *testing.T, tab table.ParsingTable, input string) (*Node, error) {
+	t.Helper()
+
+	ts, err := driver.NewWordLexer(tab, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewParser(tab, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p.Parse()
+}
+
+func forestString(t *testing.T, root *Node) string {
+	t.Helper()
+
+	b := new(bytes.Buffer)
+	err := PrintForest(b, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestParser_Parse(t *testing.T) {
+	tests := []struct {
+		caption     string
+		src         string
+		input       string
+		forest      string
+		ambiguities int
+	}{
+		{
+			caption: "unambiguous grammar",
+			src:     `E: E "+" T | T; T: T "*" F | F; F: "(" E ")" | id;`,
+			input:   "id + id",
+			forest: `E
+  E
+    T
+      F
+        id "id"
+  + "+"
+  T
+    F
+      id "id"
+`,
+		},
+		{
+			caption: "shift/reduce conflict",
+			src:     `E: E "+" E | E "*" E | id;`,
+			input:   "id + id * id",
+			forest: `E
+  #1
+    E
+      E
+        id "id"
+      + "+"
+      E
+        id "id"
+    * "*"
+    E
+      id "id"
+  #2
+    E
+      id "id"
+    + "+"
+    E
+      E
+        id "id"
+      * "*"
+      E
+        id "id"
+`,
+			ambiguities: 1,
+		},
+		{
+			caption: "reduce/reduce conflict",
+			src:     `S: E | A; E: id; A: id;`,
+			input:   "id",
+			forest: `S
+  #1
+    E
+      id "id"
+  #2
+    A
+      id "id"
+`,
+			ambiguities: 1,
+		},
+		{
+			caption: "empty productions",
+			src:     `S: A S b | x; A: ;`,
+			input:   "x b b",
+			forest: `S
+  A
+  S
+    A
+    S
+      x "x"
+    b "b"
+  b "b"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			tab := genTable(t, tt.src)
+			for _, pt := range []table.ParsingTable{tab, table.Pack(tab)} {
+				root, err := parse(t, pt, tt.input)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if forest := forestString(t, root); forest != tt.forest {
+					t.Fatalf("unexpected forest\nwant:\n%v\ngot:\n%v", tt.forest, forest)
+				}
+				if ambs := Ambiguities(root); len(ambs) != tt.ambiguities {
+					t.Fatalf("unexpected number of ambiguities\nwant: %v\ngot: %v", tt.ambiguities, len(ambs))
+				}
+			}
+		})
+	}
+}
+
+func TestParser_CyclicForest(t *testing.T) {
+	// list derives list itself by list → list item and item → ε, so the forest has a cycle.
+	tab := genTable(t, `list: list item | ; item: a | ;`)
+
+	root, err := parse(t, tab, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(Ambiguities(root)) == 0 {
+		t.Fatalf("the forest must be ambiguous")
+	}
+	if forest := forestString(t, root); !strings.Contains(forest, "list ...") {
+		t.Fatalf("a cycle must be printed as ...\n%v", forest)
+	}
+
+	_, err = Tree(root, ChooseFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParser_SyntaxError(t *testing.T) {
+	tab := genTable(t, `E: E "+" E | E "*" E | id;`)
+
+	_, err := parse(t, tab, "id + * id")
+	synErr, ok := err.(*driver.SyntaxError)
+	if !ok {
+		t.Fatalf("a syntax error must occur. got: %v", err)
+	}
+	if synErr.Token.Text != "*" || strings.Join(synErr.Expected, " ") != "id" {
+		t.Fatalf("unexpected syntax error: %v", synErr)
+	}
+}
+
+func TestTree(t *testing.T) {
+	tab := genTable(t, `E: E "+" E | E "*" E | id;`)
+	root, err := parse(t, tab, "id + id * id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plus, _ := tab.LookupSymbol("+")
+	var prodPlus int
+	for i, prod := range tab.Productions {
+		if len(prod.RHS) == 3 && prod.RHS[1] == plus {
+			prodPlus = i
+		}
+	}
+
+	tests := []struct {
+		caption string
+		choose  Chooser
+		tree    string
+	}{
+		{
+			caption: "* binds tighter than +",
+			choose:  PreferProductions(ChooseFirst, prodPlus),
+			tree: `E
+  E
+    id "id"
+  + "+"
+  E
+    E
+      id "id"
+    * "*"
+    E
+      id "id"
+`,
+		},
+		{
+			caption: "first alternative",
+			choose:  ChooseFirst,
+			tree: `E
+  E
+    E
+      id "id"
+    + "+"
+    E
+      id "id"
+  * "*"
+  E
+    id "id"
+`,
+		},
+		{
+			caption: "custom chooser",
+			choose: func(n *Node) (int, error) {
+				return len(n.Alternatives) - 1, nil
+			},
+			tree: `E
+  E
+    id "id"
+  + "+"
+  E
+    E
+      id "id"
+    * "*"
+    E
+      id "id"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			node, err := Tree(root, tt.choose)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := new(bytes.Buffer)
+			err = driver.PrintTree(b, node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.tree {
+				t.Fatalf("unexpected tree\nwant:\n%v\ngot:\n%v", tt.tree, b.String())
+			}
+		})
+	}
+
+	_, err = Tree(root, func(n *Node) (int, error) {
+		return len(n.Alternatives), nil
+	})
+	if err == nil {
+		t.Fatalf("an invalid alternative must cause an error")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type LR0ItemFingerprint string
@@ -121,6 +124,36 @@ func (i *LR0Item) IsKernel() bool {
 	return i.initial || i.dot > 0
 }
 
+func (i *LR0Item) Fingerprint() LR0ItemFingerprint {
+	return i.fingerprint
+}
+
+func (i *LR0Item) Production() *Production {
+	return i.prod
+}
+
+// Dot returns the position of the dot. The dot of a reducible item equals the length of the RHS.
+func (i *LR0Item) Dot() int {
+	return i.dot
+}
+
+func (i *LR0Item) IsInitial() bool {
+	return i.initial
+}
+
+func (i *LR0Item) IsReducible() bool {
+	return i.reducible
+}
+
+// NextSymbol returns the symbol following the dot. The second value is false when the item is reducible.
+func (i *LR0Item) NextSymbol() (SymbolID, bool) {
+	if i.dot >= i.prod.rhsLen {
+		return symbolIDNil, false
+	}
+
+	return i.prod.rhs[i.dot], true
+}
+
 type KernelFingerprint string
 
 const (
@@ -170,29 +203,39 @@ func (k *KernelItems) Fingerprint() KernelFingerprint {
 	for _, i := range k.items {
 		s = append(s, i)
 	}
-	sort.SliceStable(s, func(i, j int) bool {
+	sort.Slice(s, func(i, j int) bool {
 		i1 := s[i]
 		i2 := s[j]
 
-		if i1.prod.lhs == i2.prod.lhs {
-			return i1.dot < i2.dot
+		if i1.prod.id != i2.prod.id {
+			return i1.prod.id < i2.prod.id
+		}
+		if i1.prod.fingerprint != i2.prod.fingerprint {
+			return i1.prod.fingerprint < i2.prod.fingerprint
 		}
-		return i1.prod.lhs < i2.prod.lhs
+		return i1.dot < i2.dot
 	})
 
-	fp := ""
-	for _, i := range s {
-		if fp != "" {
-			fp += "/"
+	return kernelFingerprint(s)
+}
+
+// kernelFingerprint returns the fingerprint of the kernel consisting of items sorted by production ID and dot.
+func kernelFingerprint(items []*LR0Item) KernelFingerprint {
+	var b strings.Builder
+	for n, i := range items {
+		if n > 0 {
+			b.WriteString("/")
 		}
+		b.WriteString(string(i.prod.fingerprint))
 		if i.reducible {
-			fp += fmt.Sprintf("%v-#", i.prod.fingerprint)
+			b.WriteString("-#")
 		} else {
-			fp += fmt.Sprintf("%v-%v", i.prod.fingerprint, i.dot)
+			b.WriteString("-")
+			b.WriteString(strconv.Itoa(i.dot))
 		}
 	}
 
-	return KernelFingerprint(fp)
+	return KernelFingerprint(b.String())
 }
 
 type StateID int
@@ -201,27 +244,51 @@ func (id StateID) String() string {
 	return strconv.Itoa(int(id))
 }
 
-type stateIDGenerator struct {
-	id StateID
+type LR0ItemSet struct {
+	ID          StateID
+	Fingerprint KernelFingerprint
+	Items       map[LR0ItemFingerprint]*LR0Item
+	GoTo        map[SymbolID]KernelFingerprint
+}
+
+// Transition is a transition from a state to the next state on a symbol.
+type Transition struct {
+	Symbol SymbolID
+	Next   KernelFingerprint
 }
 
-func newStateIDGenerator() *stateIDGenerator {
-	return &stateIDGenerator{
-		id: StateID(0),
+// SortedItems returns the items of the state sorted by production ID and dot.
+func (is *LR0ItemSet) SortedItems() []*LR0Item {
+	items := make([]*LR0Item, 0, len(is.Items))
+	for _, i := range is.Items {
+		items = append(items, i)
 	}
-}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].prod.id != items[j].prod.id {
+			return items[i].prod.id < items[j].prod.id
+		}
+		return items[i].dot < items[j].dot
+	})
 
-func (gen *stateIDGenerator) next() StateID {
-	id := gen.id
-	gen.id = StateID(int(gen.id) + 1)
-	return id
+	return items
 }
 
-type LR0ItemSet struct {
-	ID          StateID
-	Fingerprint KernelFingerprint
-	Items       map[LR0ItemFingerprint]*LR0Item
-	GoTo        map[SymbolID]KernelFingerprint
+// Transitions returns the transitions of the state in the order in which their symbols were interned.
+func (is *LR0ItemSet) Transitions() []*Transition {
+	ts := make([]*Transition, 0, len(is.GoTo))
+	for sym, next := range is.GoTo {
+		ts = append(ts, &Transition{
+			Symbol: sym,
+			Next:   next,
+		})
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		bi, _ := ts[i].Symbol.bareID()
+		bj, _ := ts[j].Symbol.bareID()
+		return bi < bj
+	})
+
+	return ts
 }
 
 func NewLR0ItemSet(k *KernelItems) (*LR0ItemSet, error) {
@@ -291,95 +358,447 @@ func (is *LR0ItemSet) ComputeClosure(st *SymbolTable, prods Productions) error {
 }
 
 type LR0Automaton struct {
-	initialState KernelFingerprint
-	states       map[KernelFingerprint]*LR0ItemSet
+	// initialState is the initial state of the first augmented start symbol.
+	initialState  KernelFingerprint
+	initialStates map[SymbolID]KernelFingerprint
+	states        map[KernelFingerprint]*LR0ItemSet
 }
 
-func GenerateLR0Automaton(st *SymbolTable, prods Productions, augmentedStartSymbol SymbolID) (*LR0Automaton, error) {
+// InitialState returns the initial state of the first augmented start symbol.
+func (a *LR0Automaton) InitialState() KernelFingerprint {
+	return a.initialState
+}
+
+// InitialStates returns the initial state of each augmented start symbol.
+func (a *LR0Automaton) InitialStates() map[SymbolID]KernelFingerprint {
+	states := make(map[SymbolID]KernelFingerprint, len(a.initialStates))
+	for sym, state := range a.initialStates {
+		states[sym] = state
+	}
+
+	return states
+}
+
+// States returns the states in the order of their IDs.
+func (a *LR0Automaton) States() []*LR0ItemSet {
+	states := make([]*LR0ItemSet, 0, len(a.states))
+	for _, state := range a.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+
+	return states
+}
+
+// State returns the state having the kernel fp, or nil when there is no such state.
+func (a *LR0Automaton) State(fp KernelFingerprint) *LR0ItemSet {
+	return a.states[fp]
+}
+
+// GenerateLR0Automaton generates a LR(0) automaton that has an initial state for each augmented start symbol.
+// The states reachable from more than one initial state are shared.
+//
+// The states are numbered in the order in which they are found by a breadth-first search from the initial states,
+// visiting the transitions of a state in the order in which their symbols first appear in the productions.
+func GenerateLR0Automaton(st *SymbolTable, prods Productions, augmentedStartSymbols ...SymbolID) (*LR0Automaton, error) {
+	return GenerateLR0AutomatonWithWorkers(st, prods, 1, augmentedStartSymbols...)
+}
+
+// GenerateLR0AutomatonWithWorkers is like GenerateLR0Automaton but computes the closures and the transitions of
+// states with up to workers goroutines. The result is the same as that of GenerateLR0Automaton regardless of the
+// number of workers.
+func GenerateLR0AutomatonWithWorkers(st *SymbolTable, prods Productions, workers int, augmentedStartSymbols ...SymbolID) (*LR0Automaton, error) {
 	if st == nil {
 		return nil, fmt.Errorf("symbol table passed is nil")
 	}
-	if augmentedStartSymbol.IsNil() || !augmentedStartSymbol.Kind().IsStartSymbol() {
-		return nil, fmt.Errorf("symbold passed is nil or not start symbol")
+	if len(augmentedStartSymbols) <= 0 {
+		return nil, fmt.Errorf("no start symbol was passed")
+	}
+	for _, sym := range augmentedStartSymbols {
+		if sym.IsNil() || !sym.Kind().IsStartSymbol() {
+			return nil, fmt.Errorf("symbold passed is nil or not start symbol")
+		}
+		if len(prods.Get(sym)) != 1 {
+			return nil, fmt.Errorf("an augmented start symbol must have just one production. symbol: %v", sym)
+		}
+	}
+	if workers < 1 {
+		return nil, fmt.Errorf("the number of workers must be 1 or more. workers: %v", workers)
 	}
 
-	automaton := &LR0Automaton{
-		states: map[KernelFingerprint]*LR0ItemSet{},
+	g, err := newLR0Grammar(prods)
+	if err != nil {
+		return nil, err
 	}
-	idGen := newStateIDGenerator()
+	b := newLR0Builder(g)
 
-	// append the initial item to automaton.states
-	{
-		i0Kernel := NewKernelItems()
-		initialItem, err := NewInitialLR0Item(prods.Get(augmentedStartSymbol)[0])
-		if err != nil {
-			return nil, err
+	initialStates := make([]int, len(augmentedStartSymbols))
+	for i, sym := range augmentedStartSymbols {
+		initialStates[i] = b.intern([]int{g.itemBase[g.prodNums[prods.Get(sym)[0]]]})
+	}
+	b.build(workers)
+
+	return b.automaton(augmentedStartSymbols, initialStates), nil
+}
+
+// lr0Grammar is a dense representation of productions. Symbols, productions, and items are numbered from 0.
+// The items of a production are numbered consecutively; the item of production p with dot d is itemBase[p] + d.
+type lr0Grammar struct {
+	prods    []*Production
+	prodNums map[*Production]int
+	lhs      []int
+	rhs      [][]int
+
+	symbols     []SymbolID
+	nonTerminal []bool
+
+	// prodsOf holds the productions of each symbol.
+	prodsOf [][]int
+
+	itemBase  []int
+	itemProd  []int
+	itemDot   []int
+	itemCount int
+
+	// predictions holds the items with the dot at the beginning that the closure of an item gains when the symbol
+	// following the dot is the non-terminal symbol.
+	predictions [][]int
+
+	items []*LR0Item
+}
+
+func newLR0Grammar(prods Productions) (*lr0Grammar, error) {
+	g := &lr0Grammar{
+		prods:    []*Production{},
+		prodNums: map[*Production]int{},
+	}
+	for _, ps := range prods.All() {
+		g.prods = append(g.prods, ps...)
+	}
+	sort.Slice(g.prods, func(i, j int) bool {
+		if g.prods[i].id != g.prods[j].id {
+			return g.prods[i].id < g.prods[j].id
 		}
-		err = i0Kernel.Append(initialItem)
-		if err != nil {
-			return nil, err
+		return g.prods[i].fingerprint < g.prods[j].fingerprint
+	})
+
+	symNums := map[SymbolID]int{}
+	symNum := func(sym SymbolID) (int, error) {
+		if n, ok := symNums[sym]; ok {
+			return n, nil
+		}
+		kind := sym.Kind()
+		if kind.IsNil() {
+			return 0, fmt.Errorf("invalid symbol")
 		}
+		n := len(g.symbols)
+		symNums[sym] = n
+		g.symbols = append(g.symbols, sym)
+		g.nonTerminal = append(g.nonTerminal, kind.IsNonTerminalSymbol())
+		g.prodsOf = append(g.prodsOf, nil)
+		return n, nil
+	}
+
+	g.lhs = make([]int, len(g.prods))
+	g.rhs = make([][]int, len(g.prods))
+	g.itemBase = make([]int, len(g.prods))
+	for p, prod := range g.prods {
+		g.prodNums[prod] = p
 
-		i0, err := NewLR0ItemSet(i0Kernel)
+		lhs, err := symNum(prod.lhs)
 		if err != nil {
 			return nil, err
 		}
-		i0.ID = idGen.next()
+		g.lhs[p] = lhs
+		g.prodsOf[lhs] = append(g.prodsOf[lhs], p)
+
+		g.rhs[p] = make([]int, len(prod.rhs))
+		for i, sym := range prod.rhs {
+			n, err := symNum(sym)
+			if err != nil {
+				return nil, err
+			}
+			g.rhs[p][i] = n
+		}
 
-		automaton.initialState = i0Kernel.Fingerprint()
-		automaton.states[i0.Fingerprint] = i0
+		g.itemBase[p] = g.itemCount
+		for dot := 0; dot <= len(prod.rhs); dot++ {
+			g.itemProd = append(g.itemProd, p)
+			g.itemDot = append(g.itemDot, dot)
+		}
+		g.itemCount += len(prod.rhs) + 1
 	}
+	g.items = make([]*LR0Item, g.itemCount)
+
+	// The predictions of a non-terminal symbol A are the first items of the productions of the non-terminal symbols
+	// that are left corners of A, including A itself.
+	g.predictions = make([][]int, len(g.symbols))
+	visited := newBitset(len(g.symbols))
+	for sym := range g.symbols {
+		if !g.nonTerminal[sym] {
+			continue
+		}
 
-	uncheckedStates := map[KernelFingerprint]*LR0ItemSet{}
-	for fp, is := range automaton.states {
-		uncheckedStates[fp] = is
+		items := []int{}
+		stack := []int{sym}
+		visited.add(sym)
+		touched := []int{sym}
+		for len(stack) > 0 {
+			a := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, p := range g.prodsOf[a] {
+				items = append(items, g.itemBase[p])
+				if len(g.rhs[p]) == 0 {
+					continue
+				}
+				if first := g.rhs[p][0]; g.nonTerminal[first] && visited.add(first) {
+					stack = append(stack, first)
+					touched = append(touched, first)
+				}
+			}
+		}
+		for _, a := range touched {
+			visited.remove(a)
+		}
+		sort.Ints(items)
+		g.predictions[sym] = items
 	}
 
-	for len(uncheckedStates) > 0 {
-		nextUncheckedStates := map[KernelFingerprint]*LR0ItemSet{}
+	return g, nil
+}
 
-		for _, state := range uncheckedStates {
-			state.ComputeClosure(st, prods)
+// nextSymbol returns the symbol following the dot of item, or -1 when item is reducible.
+func (g *lr0Grammar) nextSymbol(item int) int {
+	rhs := g.rhs[g.itemProd[item]]
+	dot := g.itemDot[item]
+	if dot >= len(rhs) {
+		return -1
+	}
+	return rhs[dot]
+}
 
-			kernelMap := map[SymbolID]*KernelItems{}
-			for _, item := range state.Items {
-				if item.reducible {
-					continue
-				}
+// item returns the LR0Item corresponding to item. An LR0Item is created once for each item.
+func (g *lr0Grammar) item(item int) *LR0Item {
+	if i := g.items[item]; i != nil {
+		return i
+	}
 
-				kItem, err := NewLR0Item(item.prod, item.dot+1)
-				if err != nil {
-					return nil, err
-				}
+	prod := g.prods[g.itemProd[item]]
+	dot := g.itemDot[item]
+	i := &LR0Item{
+		prod:      prod,
+		dot:       dot,
+		initial:   dot == 0 && prod.lhs.Kind().IsStartSymbol(),
+		reducible: dot == prod.rhsLen,
+	}
+	i.fingerprint = generateLR0ItemFingerprint(i)
+	g.items[item] = i
 
-				nextSym := item.prod.rhs[item.dot]
-				if k, ok := kernelMap[nextSym]; ok {
-					k.Append(kItem)
-				} else {
-					k := NewKernelItems()
-					k.Append(kItem)
-					kernelMap[nextSym] = k
-				}
+	return i
+}
+
+type lr0State struct {
+	// kernel holds the kernel items in ascending order.
+	kernel []int
+	items  []int
+
+	// symbols and next hold the transitions in the order of symbols.
+	symbols []int
+	next    []int
+}
+
+// lr0Builder builds the states of an automaton. Kernels are hash-consed so that each kernel has a single state.
+type lr0Builder struct {
+	g      *lr0Grammar
+	states []*lr0State
+	index  map[string]int
+
+	// work area reused across states
+	key []byte
+}
+
+func newLR0Builder(g *lr0Grammar) *lr0Builder {
+	return &lr0Builder{
+		g:      g,
+		states: []*lr0State{},
+		index:  map[string]int{},
+	}
+}
+
+// intern returns the state having kernel, creating it when no state has it yet. kernel must be sorted.
+func (b *lr0Builder) intern(kernel []int) int {
+	b.key = b.key[:0]
+	for _, item := range kernel {
+		b.key = append(b.key, byte(item), byte(item>>8), byte(item>>16), byte(item>>24))
+	}
+	if n, ok := b.index[string(b.key)]; ok {
+		return n
+	}
+
+	n := len(b.states)
+	b.index[string(b.key)] = n
+	b.states = append(b.states, &lr0State{
+		kernel: append([]int{}, kernel...),
+	})
+
+	return n
+}
+
+// build expands the states in waves. A wave consists of the states found while the previous wave was expanded.
+// The states of a wave are expanded concurrently, and then the kernels of their transitions are interned in the
+// order of the states, so the states are numbered as they would be by a serial breadth-first search.
+func (b *lr0Builder) build(workers int) {
+	ws := make([]*lr0Worker, workers)
+	for i := range ws {
+		ws[i] = newLR0Worker(b.g)
+	}
+
+	for lo := 0; lo < len(b.states); {
+		hi := len(b.states)
+		kernels := make([][][]int, hi-lo)
+
+		n := workers
+		if hi-lo < n {
+			n = hi - lo
+		}
+		if n <= 1 {
+			for i := lo; i < hi; i++ {
+				kernels[i-lo] = ws[0].expand(b.states[i])
+			}
+		} else {
+			next := int64(lo)
+			var wg sync.WaitGroup
+			for _, w := range ws[:n] {
+				wg.Add(1)
+				go func(w *lr0Worker) {
+					defer wg.Done()
+					for {
+						i := int(atomic.AddInt64(&next, 1) - 1)
+						if i >= hi {
+							return
+						}
+						kernels[i-lo] = w.expand(b.states[i])
+					}
+				}(w)
 			}
+			wg.Wait()
+		}
 
-			for nextSym, kItems := range kernelMap {
-				is, err := NewLR0ItemSet(kItems)
-				if err != nil {
-					return nil, err
-				}
+		for i := lo; i < hi; i++ {
+			state := b.states[i]
+			state.next = make([]int, len(kernels[i-lo]))
+			for j, kernel := range kernels[i-lo] {
+				state.next[j] = b.intern(kernel)
+			}
+		}
+		lo = hi
+	}
+}
 
-				if _, exist := automaton.states[is.Fingerprint]; !exist {
-					is.ID = idGen.next()
-					automaton.states[is.Fingerprint] = is
-					nextUncheckedStates[is.Fingerprint] = is
-				}
+// lr0Worker computes closures and transitions. Each worker has its own work areas.
+type lr0Worker struct {
+	g       *lr0Grammar
+	closure bitset
+	kernels [][]int
+}
+
+func newLR0Worker(g *lr0Grammar) *lr0Worker {
+	return &lr0Worker{
+		g:       g,
+		closure: newBitset(g.itemCount),
+		kernels: make([][]int, len(g.symbols)),
+	}
+}
+
+// expand computes the closure and the transition symbols of state, and returns the sorted kernels of the
+// transitions in the order of the symbols.
+func (w *lr0Worker) expand(state *lr0State) [][]int {
+	g := w.g
 
-				state.GoTo[nextSym] = is.Fingerprint
+	items := append([]int{}, state.kernel...)
+	for _, item := range state.kernel {
+		w.closure.add(item)
+	}
+	for _, item := range state.kernel {
+		sym := g.nextSymbol(item)
+		if sym < 0 || !g.nonTerminal[sym] {
+			continue
+		}
+		for _, predicted := range g.predictions[sym] {
+			if w.closure.add(predicted) {
+				items = append(items, predicted)
 			}
 		}
+	}
+	for _, item := range items {
+		w.closure.remove(item)
+	}
+	state.items = items
+
+	symbols := []int{}
+	for _, item := range items {
+		sym := g.nextSymbol(item)
+		if sym < 0 {
+			continue
+		}
+		if len(w.kernels[sym]) == 0 {
+			symbols = append(symbols, sym)
+		}
+		w.kernels[sym] = append(w.kernels[sym], item+1)
+	}
+	sort.Ints(symbols)
+
+	state.symbols = symbols
+	kernels := make([][]int, len(symbols))
+	for i, sym := range symbols {
+		kernel := w.kernels[sym]
+		sort.Ints(kernel)
+		kernels[i] = append([]int{}, kernel...)
+		w.kernels[sym] = kernel[:0]
+	}
+
+	return kernels
+}
+
+// automaton converts the states into the public representation.
+func (b *lr0Builder) automaton(augmentedStartSymbols []SymbolID, initialStates []int) *LR0Automaton {
+	g := b.g
 
-		uncheckedStates = nextUncheckedStates
+	fps := make([]KernelFingerprint, len(b.states))
+	for n, state := range b.states {
+		kernel := make([]*LR0Item, len(state.kernel))
+		for i, item := range state.kernel {
+			kernel[i] = g.item(item)
+		}
+		fps[n] = kernelFingerprint(kernel)
+	}
+
+	automaton := &LR0Automaton{
+		initialState:  fps[initialStates[0]],
+		initialStates: map[SymbolID]KernelFingerprint{},
+		states:        map[KernelFingerprint]*LR0ItemSet{},
+	}
+	for i, sym := range augmentedStartSymbols {
+		automaton.initialStates[sym] = fps[initialStates[i]]
+	}
+	for n, state := range b.states {
+		is := &LR0ItemSet{
+			ID:          StateID(n),
+			Fingerprint: fps[n],
+			Items:       make(map[LR0ItemFingerprint]*LR0Item, len(state.items)),
+			GoTo:        make(map[SymbolID]KernelFingerprint, len(state.symbols)),
+		}
+		for _, item := range state.items {
+			i := g.item(item)
+			is.Items[i.fingerprint] = i
+		}
+		for i, sym := range state.symbols {
+			is.GoTo[g.symbols[sym]] = fps[state.next[i]]
+		}
+		automaton.states[fps[n]] = is
 	}
 
-	return automaton, nil
+	return automaton
 }
@@ -1,6 +1,7 @@
 package grammar
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -251,6 +252,188 @@ func TestGenerateLR0Automaton(t *testing.T) {
 	}
 }
 
+func TestGenerateLR0Automaton_Deterministic(t *testing.T) {
+	st, prods, start := genLargeGrammar(10)
+
+	var expected map[KernelFingerprint]StateID
+	for i := 0; i < 10; i++ {
+		automaton, err := GenerateLR0Automaton(st, prods, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		states := map[KernelFingerprint]StateID{}
+		for fp, state := range automaton.states {
+			states[fp] = state.ID
+		}
+		if expected == nil {
+			expected = states
+			continue
+		}
+		if len(states) != len(expected) {
+			t.Fatalf("unexpected number of states\nwant: %v\ngot: %v", len(expected), len(states))
+		}
+		for fp, id := range expected {
+			if states[fp] != id {
+				t.Fatalf("unexpected state ID of %v\nwant: %v\ngot: %v", fp, id, states[fp])
+			}
+		}
+	}
+}
+
+func TestLR0Automaton_Accessors(t *testing.T) {
+	st := NewSymbolTable()
+
+	prods := newProds(st, "E'", []*Prod{
+		newProd("E'", "E"),
+		newProd("E", "E", "+", "T"),
+		newProd("E", "T"),
+		newProd("T", "id"),
+	})
+
+	V := newSymbolGetter(st)
+	P := newProductionGetter(st, prods)
+
+	automaton, err := GenerateLR0Automaton(st, prods, V("E'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if automaton.InitialStates()[V("E'")] != automaton.InitialState() {
+		t.Fatalf("unexpected initial state of E'\nwant: %v\ngot: %v", automaton.InitialState(), automaton.InitialStates()[V("E'")])
+	}
+
+	states := automaton.States()
+	if len(states) != len(automaton.states) {
+		t.Fatalf("unexpected number of states\nwant: %v\ngot: %v", len(automaton.states), len(states))
+	}
+	for i, state := range states {
+		if state.ID != StateID(i) {
+			t.Fatalf("states must be in the order of their IDs\nwant: %v\ngot: %v", i, state.ID)
+		}
+		if automaton.State(state.Fingerprint) != state {
+			t.Fatalf("failed to look up state %v", state.ID)
+		}
+	}
+
+	initial := automaton.State(automaton.InitialState())
+	if initial.ID != 0 {
+		t.Fatalf("unexpected ID of the initial state\nwant: %v\ngot: %v", 0, initial.ID)
+	}
+
+	expectedItems := []struct {
+		prod *Production
+		dot  int
+	}{
+		{prod: P("E'", 0), dot: 0},
+		{prod: P("E", 0), dot: 0},
+		{prod: P("E", 1), dot: 0},
+		{prod: P("T", 0), dot: 0},
+	}
+	items := initial.SortedItems()
+	if len(items) != len(expectedItems) {
+		t.Fatalf("unexpected number of items\nwant: %v\ngot: %v", len(expectedItems), len(items))
+	}
+	for i, expected := range expectedItems {
+		item := items[i]
+		if item.Production() != expected.prod || item.Dot() != expected.dot {
+			t.Fatalf("unexpected item\nwant: %v (dot: %v)\ngot: %v", expected.prod, expected.dot, item)
+		}
+		if item.IsReducible() {
+			t.Fatalf("an item must not be reducible: %v", item)
+		}
+		if item.Fingerprint() != generateLR0ItemFingerprint(item) {
+			t.Fatalf("unexpected fingerprint\nwant: %v\ngot: %v", generateLR0ItemFingerprint(item), item.Fingerprint())
+		}
+	}
+	if !items[0].IsInitial() || items[1].IsInitial() {
+		t.Fatalf("only E' →・E must be initial")
+	}
+	if sym, ok := items[0].NextSymbol(); !ok || sym != V("E") {
+		t.Fatalf("unexpected next symbol\nwant: %v\ngot: %v", V("E"), sym)
+	}
+	if items[0].Production().LHS() != V("E'") {
+		t.Fatalf("unexpected LHS\nwant: %v\ngot: %v", V("E'"), items[0].Production().LHS())
+	}
+
+	expectedSyms := []SymbolID{V("E"), V("T"), V("id")}
+	ts := initial.Transitions()
+	if len(ts) != len(expectedSyms) {
+		t.Fatalf("unexpected number of transitions\nwant: %v\ngot: %v", len(expectedSyms), len(ts))
+	}
+	for i, tr := range ts {
+		if tr.Symbol != expectedSyms[i] {
+			t.Fatalf("unexpected transition symbol\nwant: %v\ngot: %v", expectedSyms[i], tr.Symbol)
+		}
+		if tr.Next != initial.GoTo[tr.Symbol] {
+			t.Fatalf("unexpected next state\nwant: %v\ngot: %v", initial.GoTo[tr.Symbol], tr.Next)
+		}
+	}
+
+	for _, state := range states {
+		for _, item := range state.SortedItems() {
+			if !item.IsReducible() {
+				continue
+			}
+			if _, ok := item.NextSymbol(); ok {
+				t.Fatalf("a reducible item must have no next symbol: %v", item)
+			}
+			_, rhsLen := item.Production().RHS()
+			if item.Dot() != rhsLen {
+				t.Fatalf("unexpected dot of a reducible item\nwant: %v\ngot: %v", rhsLen, item.Dot())
+			}
+		}
+	}
+}
+
+func TestGenerateLR0AutomatonWithWorkers(t *testing.T) {
+	st, prods, start := genLargeGrammar(50)
+	expected, err := GenerateLR0Automaton(st, prods, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{2, 4, 16} {
+		t.Run(fmt.Sprintf("%v workers", workers), func(t *testing.T) {
+			automaton, err := GenerateLR0AutomatonWithWorkers(st, prods, workers, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if automaton.initialState != expected.initialState {
+				t.Fatalf("unexpected initial state\nwant: %v\ngot: %v", expected.initialState, automaton.initialState)
+			}
+			if len(automaton.states) != len(expected.states) {
+				t.Fatalf("unexpected number of states\nwant: %v\ngot: %v", len(expected.states), len(automaton.states))
+			}
+			for fp, expectedState := range expected.states {
+				state, ok := automaton.states[fp]
+				if !ok {
+					t.Fatalf("a state was not found: %v", fp)
+				}
+				if state.ID != expectedState.ID {
+					t.Fatalf("unexpected state ID of %v\nwant: %v\ngot: %v", fp, expectedState.ID, state.ID)
+				}
+				if len(state.Items) != len(expectedState.Items) {
+					t.Fatalf("unexpected number of items of %v\nwant: %v\ngot: %v", fp, len(expectedState.Items), len(state.Items))
+				}
+				if len(state.GoTo) != len(expectedState.GoTo) {
+					t.Fatalf("unexpected number of transitions of %v\nwant: %v\ngot: %v", fp, len(expectedState.GoTo), len(state.GoTo))
+				}
+				for sym, next := range expectedState.GoTo {
+					if state.GoTo[sym] != next {
+						t.Fatalf("unexpected transition of %v on %v\nwant: %v\ngot: %v", fp, sym, next, state.GoTo[sym])
+					}
+				}
+			}
+		})
+	}
+
+	_, err = GenerateLR0AutomatonWithWorkers(st, prods, 0, start)
+	if err == nil {
+		t.Fatal("an error must occur when the number of workers is 0")
+	}
+}
+
 func TestKernelItems(t *testing.T) {
 	st := NewSymbolTable()
 
@@ -322,6 +505,30 @@ func TestKernelItems(t *testing.T) {
 		}
 	})
 
+	t.Run("fingerprint doesn't depend on the order of items", func(t *testing.T) {
+		// Both items have the same LHS and dot.
+		items := []lr0Item{
+			{lhs: "E", num: 0, dot: 1},
+			{lhs: "E", num: 1, reducible: true},
+		}
+
+		var expected KernelFingerprint
+		for i := 0; i < 20; i++ {
+			k, err := genKernel([]lr0Item{items[i%2], items[(i+1)%2]}, st, prods)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fp := k.Fingerprint()
+			if i == 0 {
+				expected = fp
+				continue
+			}
+			if fp != expected {
+				t.Fatalf("unexpected fingerprint\nwant: %v\ngot: %v", expected, fp)
+			}
+		}
+	})
+
 	t.Run("non-kernel item append to KernelItems", func(t *testing.T) {
 		kernelItems := NewKernelItems()
 
@@ -344,3 +551,45 @@ func TestKernelItems(t *testing.T) {
 		}
 	})
 }
+
+func TestGenerateLR0Automaton_MultipleStartSymbols(t *testing.T) {
+	st := NewSymbolTable()
+	st.Intern("E'", SymbolKindStart)
+
+	prods := newProds(st, "S'", []*Prod{
+		newProd("S'", "S"),
+		newProd("E'", "E"),
+		newProd("S", "E", ";"),
+		newProd("E", "E", "+", "id"),
+		newProd("E", "id"),
+	})
+
+	V := newSymbolGetter(st)
+
+	automaton, err := GenerateLR0Automaton(st, prods, V("S'"), V("E'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, start := range []string{"S'", "E'"} {
+		k, err := genInitialKernel(V(start), st, prods)
+		if err != nil {
+			t.Fatal(err)
+		}
+		kernelFp, ok := automaton.initialStates[V(start)]
+		if !ok || kernelFp != k.Fingerprint() {
+			t.Fatalf("unexpected initial state of %v\nwant: %v\ngot: %v", start, k.Fingerprint(), kernelFp)
+		}
+		if automaton.states[kernelFp].ID != StateID(i) {
+			t.Fatalf("unexpected state ID of %v\nwant: %v\ngot: %v", start, i, automaton.states[kernelFp].ID)
+		}
+	}
+	if automaton.initialState != automaton.initialStates[V("S'")] {
+		t.Fatalf("initial state is not the one of the first start symbol")
+	}
+
+	// S' alone has 7 states and E' alone has 5 states. E -> id. , E -> E + . id, and E -> E + id . are shared.
+	if len(automaton.states) != 9 {
+		t.Fatalf("unexpected number of states\nwant: %v\ngot: %v", 9, len(automaton.states))
+	}
+}
@@ -0,0 +1,87 @@
+package grammar
+
+import (
+	"fmt"
+	"testing"
+)
+
+// genLargeGrammar generates a grammar of expressions with n precedence levels. It has 2n+2 productions.
+//
+// E0 → E0 op0 E1 | E1
+// E1 → E1 op1 E2 | E2
+// ...
+// En → ( E0 ) | id
+func genLargeGrammar(n int) (*SymbolTable, Productions, SymbolID) {
+	st := NewSymbolTable()
+	level := func(i int) string {
+		return fmt.Sprintf("E%v", i)
+	}
+
+	prods := []*Prod{
+		newProd("S'", level(0)),
+	}
+	for i := 0; i < n; i++ {
+		prods = append(prods, newProd(level(i), level(i), fmt.Sprintf("op%v", i), level(i+1)))
+		prods = append(prods, newProd(level(i), level(i+1)))
+	}
+	prods = append(prods, newProd(level(n), "(", level(0), ")"))
+	prods = append(prods, newProd(level(n), "id"))
+
+	ps := newProds(st, "S'", prods)
+
+	return st, ps, st.LookupByString("S'")
+}
+
+func BenchmarkGenerateLR0Automaton(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		st, prods, start := genLargeGrammar(n)
+		b.Run(fmt.Sprintf("%v productions", 2*n+3), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := GenerateLR0Automaton(st, prods, start)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateLR0AutomatonWithWorkers(b *testing.B) {
+	st, prods, start := genLargeGrammar(500)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("%v workers", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := GenerateLR0AutomatonWithWorkers(st, prods, workers, start)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateSLRParsingTable(b *testing.B) {
+	for _, n := range []int{10, 100, 500} {
+		st, prods, start := genLargeGrammar(n)
+		first, err := GenerateFirstSets(prods)
+		if err != nil {
+			b.Fatal(err)
+		}
+		follow, err := GenerateFollowSets(prods, first)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("%v productions", 2*n+3), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				automaton, err := GenerateLR0Automaton(st, prods, start)
+				if err != nil {
+					b.Fatal(err)
+				}
+				_, err = GenerateSLRParsingTable(automaton, follow)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package grammar
+
+// bitset is a set of non-negative integers.
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+// add adds n to the set and reports whether n was not in the set.
+func (bs bitset) add(n int) bool {
+	w, b := n/64, uint(n%64)
+	if bs[w]&(1<<b) != 0 {
+		return false
+	}
+	bs[w] |= 1 << b
+	return true
+}
+
+func (bs bitset) has(n int) bool {
+	return bs[n/64]&(1<<uint(n%64)) != 0
+}
+
+func (bs bitset) remove(n int) {
+	bs[n/64] &^= 1 << uint(n%64)
+}
@@ -0,0 +1,216 @@
+package grammar
+
+import (
+	"fmt"
+)
+
+// Grammar is a grammar built by a Builder.
+type Grammar struct {
+	SymbolTable *SymbolTable
+	Productions Productions
+
+	// AugmentedStartSymbols holds an augmented start symbol S' for each start symbol S. S' has just one production
+	// S' → S.
+	AugmentedStartSymbols []SymbolID
+}
+
+// Builder builds a Grammar in code. Symbols must be declared by Terminal or NonTerminal before Build is called, and
+// every non-terminal symbol must have one or more productions.
+//
+//	g, err := grammar.NewBuilder().
+//		Terminal("+", "id").
+//		NonTerminal("E", "T").
+//		Rule("E", "E", "+", "T").
+//		Rule("E", "T").
+//		Rule("T", "id").
+//		Build()
+//
+// The methods other than Build record the first error and return the Builder so that calls can be chained. Build
+// returns the error.
+type Builder struct {
+	kinds        map[string]SymbolKind
+	terminals    []string
+	nonTerminals []string
+	starts       []string
+	rules        []*builderRule
+	err          error
+}
+
+type builderRule struct {
+	lhs string
+	rhs []string
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{
+		kinds:        map[string]SymbolKind{},
+		terminals:    []string{},
+		nonTerminals: []string{},
+		starts:       []string{},
+		rules:        []*builderRule{},
+	}
+}
+
+func (b *Builder) declare(kind SymbolKind, names []string) {
+	for _, name := range names {
+		if b.err != nil {
+			return
+		}
+		if name == "" {
+			b.err = fmt.Errorf("a symbol name is empty")
+			return
+		}
+		if name == ErrorSymbolName && !kind.IsTerminalSymbol() {
+			b.err = fmt.Errorf("%v is reserved as a terminal symbol", name)
+			return
+		}
+		if k, ok := b.kinds[name]; ok {
+			if k != kind {
+				b.err = fmt.Errorf("symbol %v is declared as both a terminal and a non-terminal symbol", name)
+			}
+			continue
+		}
+		b.kinds[name] = kind
+		if kind.IsTerminalSymbol() {
+			b.terminals = append(b.terminals, name)
+		} else {
+			b.nonTerminals = append(b.nonTerminals, name)
+		}
+	}
+}
+
+// Terminal declares terminal symbols.
+func (b *Builder) Terminal(names ...string) *Builder {
+	b.declare(SymbolKindTerminal, names)
+	return b
+}
+
+// NonTerminal declares non-terminal symbols.
+func (b *Builder) NonTerminal(names ...string) *Builder {
+	b.declare(SymbolKindNonTerminal, names)
+	return b
+}
+
+// Start sets start symbols. When no start symbol is set, the LHS of the first rule is the start symbol.
+func (b *Builder) Start(names ...string) *Builder {
+	for _, name := range names {
+		if b.err == nil && name == "" {
+			b.err = fmt.Errorf("a start symbol name is empty")
+		}
+	}
+	b.starts = append(b.starts, names...)
+	return b
+}
+
+// Rule adds a production lhs → rhs. An empty rhs means an empty production.
+func (b *Builder) Rule(lhs string, rhs ...string) *Builder {
+	b.rules = append(b.rules, &builderRule{
+		lhs: lhs,
+		rhs: append([]string{}, rhs...),
+	})
+	return b
+}
+
+// Build validates the declarations and the rules, and returns the grammar. Each start symbol S is augmented with a
+// production S' → S.
+func (b *Builder) Build() (*Grammar, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.rules) <= 0 {
+		return nil, fmt.Errorf("the grammar contains no production")
+	}
+
+	hasRule := map[string]bool{}
+	for _, r := range b.rules {
+		if !b.kinds[r.lhs].IsNonTerminalSymbol() {
+			return nil, fmt.Errorf("LHS %v is not a declared non-terminal symbol", r.lhs)
+		}
+		for _, sym := range r.rhs {
+			if b.kinds[sym].IsNil() {
+				return nil, fmt.Errorf("symbol %v in a production of %v is not declared", sym, r.lhs)
+			}
+		}
+		hasRule[r.lhs] = true
+	}
+	for _, name := range b.nonTerminals {
+		if !hasRule[name] {
+			return nil, fmt.Errorf("non-terminal symbol %v has no production", name)
+		}
+	}
+
+	starts := b.starts
+	if len(starts) <= 0 {
+		starts = []string{b.rules[0].lhs}
+	}
+
+	st := NewSymbolTable()
+	prods := NewProductions()
+	g := &Grammar{
+		SymbolTable:           st,
+		Productions:           prods,
+		AugmentedStartSymbols: []SymbolID{},
+	}
+
+	for _, name := range b.nonTerminals {
+		st.Intern(name, SymbolKindNonTerminal)
+	}
+
+	augmented := map[string]bool{}
+	for _, start := range starts {
+		if !b.kinds[start].IsNonTerminalSymbol() {
+			return nil, fmt.Errorf("start symbol %v is not a declared non-terminal symbol", start)
+		}
+		if augmented[start] {
+			continue
+		}
+		augmented[start] = true
+
+		name := fmt.Sprintf("%s'", start)
+		if !b.kinds[name].IsNil() {
+			return nil, fmt.Errorf("symbol %v conflicts with the augmented start symbol of %v", name, start)
+		}
+		augmentedStartID := st.Intern(name, SymbolKindStart)
+		prod, err := NewProduction(augmentedStartID, []SymbolID{st.LookupByString(start)})
+		if err != nil {
+			return nil, err
+		}
+		prods.Append(prod)
+
+		g.AugmentedStartSymbols = append(g.AugmentedStartSymbols, augmentedStartID)
+	}
+
+	for _, name := range b.terminals {
+		st.Intern(name, SymbolKindTerminal)
+	}
+
+	for _, r := range b.rules {
+		rhs := make([]SymbolID, len(r.rhs))
+		for i, sym := range r.rhs {
+			rhs[i] = st.LookupByString(sym)
+		}
+		prod, err := NewProduction(st.LookupByString(r.lhs), rhs)
+		if err != nil {
+			return nil, err
+		}
+		if prods.LookupByFingerprint(prod.fingerprint) != nil {
+			return nil, fmt.Errorf("production %v is duplicated", r.String())
+		}
+		prods.Append(prod)
+	}
+
+	return g, nil
+}
+
+func (r *builderRule) String() string {
+	rhs := ""
+	for _, sym := range r.rhs {
+		rhs += " " + sym
+	}
+	if rhs == "" {
+		rhs = " ε"
+	}
+
+	return r.lhs + " →" + rhs
+}
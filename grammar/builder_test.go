@@ -0,0 +1,164 @@
+package grammar
+
+import (
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	g, err := NewBuilder().
+		Terminal("+", "*", "(", ")", "id").
+		NonTerminal("E", "T", "F").
+		Rule("E", "E", "+", "T").
+		Rule("E", "T").
+		Rule("T", "T", "*", "F").
+		Rule("T", "F").
+		Rule("F", "(", "E", ")").
+		Rule("F", "id").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(g.AugmentedStartSymbols) != 1 {
+		t.Fatalf("unexpected number of start symbols. want: %v, got: %v", 1, len(g.AugmentedStartSymbols))
+	}
+	start := g.AugmentedStartSymbols[0]
+	if start != g.SymbolTable.LookupByString("E'") {
+		t.Fatalf("unexpected start symbol. want: %v, got: %v", g.SymbolTable.LookupByString("E'"), start)
+	}
+
+	expectedProds := []struct {
+		lhs string
+		rhs []string
+	}{
+		{lhs: "E'", rhs: []string{"E"}},
+		{lhs: "E", rhs: []string{"E", "+", "T"}},
+		{lhs: "E", rhs: []string{"T"}},
+		{lhs: "T", rhs: []string{"T", "*", "F"}},
+		{lhs: "T", rhs: []string{"F"}},
+		{lhs: "F", rhs: []string{"(", "E", ")"}},
+		{lhs: "F", rhs: []string{"id"}},
+	}
+	all := sortedProductions(g.Productions)
+	if len(all) != len(expectedProds) {
+		t.Fatalf("unexpected number of productions. want: %v, got: %v", len(expectedProds), len(all))
+	}
+	for i, expected := range expectedProds {
+		prod := all[i]
+		if lhs, _ := g.SymbolTable.ToString(prod.lhs); lhs != expected.lhs {
+			t.Fatalf("unexpected LHS of production #%v. want: %v, got: %v", i, expected.lhs, lhs)
+		}
+		if len(prod.rhs) != len(expected.rhs) {
+			t.Fatalf("unexpected length of RHS of production #%v. want: %v, got: %v", i, len(expected.rhs), len(prod.rhs))
+		}
+		for j, sym := range prod.rhs {
+			if sym != g.SymbolTable.LookupByString(expected.rhs[j]) {
+				t.Fatalf("unexpected RHS of production #%v. want: %v, got: %v", i, expected.rhs, prod.rhs)
+			}
+		}
+	}
+	if !g.SymbolTable.LookupByString("id").Kind().IsTerminalSymbol() {
+		t.Fatalf("id must be a terminal symbol")
+	}
+
+	first, err := GenerateFirstSets(g.Productions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := GenerateFollowSets(g.Productions, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbols...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuilder_StartSymbols(t *testing.T) {
+	g, err := NewBuilder().
+		Terminal("a", "b").
+		NonTerminal("A", "B").
+		Start("B", "A", "B").
+		Rule("A", "a").
+		Rule("B", "b").
+		Rule("B").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"B'", "A'"}
+	if len(g.AugmentedStartSymbols) != len(expected) {
+		t.Fatalf("unexpected number of start symbols. want: %v, got: %v", len(expected), len(g.AugmentedStartSymbols))
+	}
+	for i, sym := range g.AugmentedStartSymbols {
+		if sym != g.SymbolTable.LookupByString(expected[i]) || !sym.Kind().IsStartSymbol() {
+			t.Fatalf("unexpected start symbol. want: %v, got: %v", expected[i], sym)
+		}
+		if len(g.Productions.Get(sym)) != 1 {
+			t.Fatalf("an augmented start symbol must have just one production. symbol: %v", sym)
+		}
+	}
+}
+
+func TestBuilder_Error(t *testing.T) {
+	tests := map[string]*Builder{
+		"no production": NewBuilder().
+			NonTerminal("A"),
+		"empty symbol name": NewBuilder().
+			Terminal(""),
+		"symbol declared as both terminal and non-terminal": NewBuilder().
+			Terminal("a").
+			NonTerminal("a"),
+		"error declared as non-terminal": NewBuilder().
+			NonTerminal("error"),
+		"undeclared LHS": NewBuilder().
+			Terminal("a").
+			Rule("A", "a"),
+		"terminal LHS": NewBuilder().
+			Terminal("a").
+			Rule("a", "a"),
+		"undeclared RHS symbol": NewBuilder().
+			NonTerminal("A").
+			Rule("A", "a"),
+		"non-terminal without production": NewBuilder().
+			Terminal("a").
+			NonTerminal("A", "B").
+			Rule("A", "a"),
+		"duplicated production": NewBuilder().
+			Terminal("a").
+			NonTerminal("A").
+			Rule("A", "a").
+			Rule("A", "a"),
+		"start symbol is terminal": NewBuilder().
+			Terminal("a").
+			NonTerminal("A").
+			Start("a").
+			Rule("A", "a"),
+		"undeclared start symbol": NewBuilder().
+			Terminal("a").
+			NonTerminal("A").
+			Start("B").
+			Rule("A", "a"),
+		"symbol conflicting with augmented start symbol": NewBuilder().
+			Terminal("A'").
+			NonTerminal("A").
+			Rule("A", "A'"),
+	}
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			g, err := b.Build()
+			if err == nil {
+				t.Fatalf("an error must occur")
+			}
+			if g != nil {
+				t.Fatalf("a grammar must be nil when an error occurs")
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package grammar
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// randomGrammar is a grammar for property tests. Symbols are bytes: 0 to terminals-1 are terminal symbols and the
+// rest are non-terminal symbols. The non-terminal symbol terminals+0 is the augmented start symbol.
+type randomGrammar struct {
+	terminals    int
+	nonTerminals int
+	alts         map[byte][]string
+}
+
+// genRandomGrammar generates a grammar in which every non-terminal symbol is reachable from the start symbol and
+// derives some string of terminal symbols.
+func genRandomGrammar(r *rand.Rand) *randomGrammar {
+	g := &randomGrammar{
+		terminals:    1 + r.Intn(3),
+		nonTerminals: 2 + r.Intn(3),
+		alts:         map[byte][]string{},
+	}
+	start := byte(g.terminals)
+	randomSym := func() byte {
+		// The start symbol never appears in RHSs.
+		if r.Intn(2) == 0 {
+			return byte(r.Intn(g.terminals))
+		}
+		return byte(g.terminals + 1 + r.Intn(g.nonTerminals-1))
+	}
+
+	g.alts[start] = []string{string([]byte{start + 1})}
+	for i := 1; i < g.nonTerminals; i++ {
+		lhs := byte(g.terminals + i)
+
+		// A short alternative of terminal symbols keeps the symbol productive.
+		base := []byte{}
+		if r.Intn(2) == 0 {
+			base = append(base, byte(r.Intn(g.terminals)))
+		}
+		g.alts[lhs] = append(g.alts[lhs], string(base))
+
+		for n := r.Intn(3); n > 0; n-- {
+			rhs := []byte{}
+			for l := r.Intn(4); l > 0; l-- {
+				rhs = append(rhs, randomSym())
+			}
+			g.alts[lhs] = append(g.alts[lhs], string(rhs))
+		}
+
+		// Make the symbol reachable from an earlier one.
+		if i > 1 {
+			from := byte(g.terminals + 1 + r.Intn(i-1))
+			g.alts[from] = append(g.alts[from], string([]byte{randomSym(), lhs}))
+		}
+	}
+
+	return g
+}
+
+func (g *randomGrammar) isTerminal(sym byte) bool {
+	return int(sym) < g.terminals
+}
+
+func (g *randomGrammar) name(sym byte) string {
+	if g.isTerminal(sym) {
+		return fmt.Sprintf("t%v", sym)
+	}
+	return fmt.Sprintf("N%v", int(sym)-g.terminals)
+}
+
+func (g *randomGrammar) String() string {
+	s := ""
+	for i := 0; i < g.nonTerminals; i++ {
+		lhs := byte(g.terminals + i)
+		for _, alt := range g.alts[lhs] {
+			s += g.name(lhs) + " →"
+			for _, sym := range []byte(alt) {
+				s += " " + g.name(sym)
+			}
+			s += "\n"
+		}
+	}
+	return s
+}
+
+func (g *randomGrammar) productions(st *SymbolTable) Productions {
+	prods := []*Prod{}
+	for i := 0; i < g.nonTerminals; i++ {
+		lhs := byte(g.terminals + i)
+		for _, alt := range g.alts[lhs] {
+			rhs := []string{}
+			for _, sym := range []byte(alt) {
+				rhs = append(rhs, g.name(sym))
+			}
+			prods = append(prods, newProd(g.name(lhs), rhs...))
+		}
+	}
+
+	return newProds(st, g.name(byte(g.terminals)), prods)
+}
+
+// maxFormLen bounds the length of sentential forms the oracles explore.
+const maxFormLen = 7
+
+// oracleFirst explores the leftmost derivations from form and returns the terminal symbols that can begin a
+// sentential form, and whether form derives the empty string.
+func (g *randomGrammar) oracleFirst(form string) (map[byte]bool, bool) {
+	first := map[byte]bool{}
+	nullable := false
+	visited := map[string]bool{}
+	queue := []string{form}
+	for len(queue) > 0 {
+		form := queue[0]
+		queue = queue[1:]
+		if visited[form] {
+			continue
+		}
+		visited[form] = true
+
+		if len(form) == 0 {
+			nullable = true
+			continue
+		}
+		if g.isTerminal(form[0]) {
+			first[form[0]] = true
+			continue
+		}
+		for _, alt := range g.alts[form[0]] {
+			next := alt + form[1:]
+			if len(next) <= maxFormLen {
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return first, nullable
+}
+
+// oracleFollow explores the derivations from the start symbol and returns, for each non-terminal symbol, the
+// terminal symbols that immediately follow it in a sentential form and whether it can end a sentential form.
+func (g *randomGrammar) oracleFollow() (map[byte]map[byte]bool, map[byte]bool) {
+	follow := map[byte]map[byte]bool{}
+	eof := map[byte]bool{}
+	for i := 0; i < g.nonTerminals; i++ {
+		follow[byte(g.terminals+i)] = map[byte]bool{}
+	}
+
+	visited := map[string]bool{}
+	queue := []string{string([]byte{byte(g.terminals)})}
+	for len(queue) > 0 {
+		form := queue[0]
+		queue = queue[1:]
+		if visited[form] {
+			continue
+		}
+		visited[form] = true
+
+		for i := 0; i < len(form); i++ {
+			sym := form[i]
+			if g.isTerminal(sym) {
+				continue
+			}
+			if i+1 == len(form) {
+				eof[sym] = true
+			} else if g.isTerminal(form[i+1]) {
+				follow[sym][form[i+1]] = true
+			}
+			for _, alt := range g.alts[sym] {
+				next := form[:i] + alt + form[i+1:]
+				if len(next) <= maxFormLen {
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+
+	return follow, eof
+}
+
+func TestFirstAndFollowSets_RandomGrammars(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		g := genRandomGrammar(r)
+		t.Run(fmt.Sprintf("#%v", i), func(t *testing.T) {
+			st := NewSymbolTable()
+			prods := g.productions(st)
+			first, err := GenerateFirstSets(prods)
+			if err != nil {
+				t.Fatal(err)
+			}
+			follow, err := GenerateFollowSets(prods, first)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lookup := func(sym byte) SymbolID {
+				return st.LookupByString(g.name(sym))
+			}
+			testSet := func(kind string, sym byte, want map[byte]bool, got SymbolSet) {
+				t.Helper()
+				if len(got) != len(want) {
+					t.Fatalf("%v(%v) has unexpected symbols. want: %v, got: %v\n%v", kind, g.name(sym), want, got, g)
+				}
+				for s := range want {
+					if !got.Contains(lookup(s)) {
+						t.Fatalf("%v(%v) lacks %v. got: %v\n%v", kind, g.name(sym), g.name(s), got, g)
+					}
+				}
+			}
+
+			wantFollow, wantEOF := g.oracleFollow()
+			for n := 0; n < g.nonTerminals; n++ {
+				sym := byte(g.terminals + n)
+				wantFirst, wantNullable := g.oracleFirst(string([]byte{sym}))
+
+				fs := first.FirstOfSymbol(lookup(sym))
+				testSet("FIRST", sym, wantFirst, fs.Symbols())
+				if fs.Empty() != wantNullable || first.Nullable().Contains(lookup(sym)) != wantNullable {
+					t.Fatalf("nullability of %v is mismatched. want: %v\n%v", g.name(sym), wantNullable, g)
+				}
+
+				flw := follow.Get(lookup(sym))
+				testSet("FOLLOW", sym, wantFollow[sym], flw.Symbols())
+				if flw.EOF() != wantEOF[sym] {
+					t.Fatalf("FOLLOW(%v) has unexpected EOF. want: %v, got: %v\n%v", g.name(sym), wantEOF[sym], flw.EOF(), g)
+				}
+			}
+
+			for _, alts := range g.alts {
+				for _, alt := range alts {
+					seq := []SymbolID{}
+					for _, sym := range []byte(alt) {
+						seq = append(seq, lookup(sym))
+					}
+					wantFirst, wantNullable := g.oracleFirst(alt)
+
+					fs := first.FirstOfSequence(seq)
+					if len(fs.Symbols()) != len(wantFirst) || fs.Empty() != wantNullable {
+						t.Fatalf("FIRST of %v is mismatched. want: %v (nullable: %v), got: %v\n%v", seq, wantFirst, wantNullable, fs, g)
+					}
+					for s := range wantFirst {
+						if !fs.Symbols().Contains(lookup(s)) {
+							t.Fatalf("FIRST of %v lacks %v. got: %v\n%v", seq, g.name(s), fs, g)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package grammar
 
 import (
-	"fmt"
+	"sort"
 )
 
 // SymbolSet is a set of SymbolIDs.
@@ -9,7 +9,7 @@ type SymbolSet map[SymbolID]struct{}
 
 func (ss SymbolSet) String() string {
 	s := ""
-	for sym, _ := range ss {
+	for _, sym := range sortSymbols(ss.Slice()) {
 		if s != "" {
 			s += " "
 		}
@@ -23,14 +23,34 @@ func (ss SymbolSet) put(sym SymbolID) {
 	ss[sym] = struct{}{}
 }
 
+// Slice returns the symbols in the set in no particular order.
 func (ss SymbolSet) Slice() []SymbolID {
-	s := make([]SymbolID, len(ss))
-	for sym, _ := range ss {
+	s := make([]SymbolID, 0, len(ss))
+	for sym := range ss {
 		s = append(s, sym)
 	}
 	return s
 }
 
+// Contains reports whether sym is in the set.
+func (ss SymbolSet) Contains(sym SymbolID) bool {
+	_, ok := ss[sym]
+	return ok
+}
+
+// merge adds the symbols of target to the set and reports whether the set has changed.
+func (ss SymbolSet) merge(target SymbolSet) bool {
+	changed := false
+	for sym := range target {
+		if _, ok := ss[sym]; !ok {
+			ss[sym] = struct{}{}
+			changed = true
+		}
+	}
+
+	return changed
+}
+
 // FirstSet represents a FIRST set.
 type FirstSet struct {
 	symbols SymbolSet
@@ -54,6 +74,16 @@ func (fs *FirstSet) String() string {
 	return s
 }
 
+// Symbols returns the terminal symbols in the set.
+func (fs *FirstSet) Symbols() SymbolSet {
+	return fs.symbols
+}
+
+// Empty reports whether the set contains ε.
+func (fs *FirstSet) Empty() bool {
+	return fs.empty
+}
+
 func (fs *FirstSet) put(syms ...SymbolID) {
 	for _, sym := range syms {
 		fs.symbols.put(sym)
@@ -64,221 +94,156 @@ func (fs *FirstSet) putEmpty() {
 	fs.empty = true
 }
 
-func (fs *FirstSet) merge(target *FirstSet) {
-	for sym, _ := range target.symbols {
-		fs.symbols.put(sym)
-	}
+func (fs *FirstSet) merge(target *FirstSet) bool {
+	return fs.symbols.merge(target.symbols)
 }
 
-// FirstSet represents a set of FIRST sets.
-type FirstSets map[ProductionFingerprint][]*FirstSet
+// FirstSets holds the FIRST sets of the symbols and of the suffixes of the productions of a grammar.
+type FirstSets struct {
+	nullable SymbolSet
+	symbols  map[SymbolID]*FirstSet
+	prods    map[ProductionFingerprint][]*FirstSet
+}
 
-func newFirstSets(prods Productions) FirstSets {
-	fss := FirstSets{}
+// GenerateFirstSets computes the FIRST sets of prods.
+//
+// The nullable non-terminal symbols and the FIRST sets of the non-terminal symbols are computed as the least fixed
+// points of the following equations, iterated until no set changes.
+//
+// A is nullable ⇔ there is a production A → X1 X2 ... Xn whose symbols are all nullable
+// FIRST(A) = ∪ FIRST(X1 X2 ... Xn) for each production A → X1 X2 ... Xn
+func GenerateFirstSets(prods Productions) (*FirstSets, error) {
+	fss := &FirstSets{
+		nullable: SymbolSet{},
+		symbols:  map[SymbolID]*FirstSet{},
+		prods:    map[ProductionFingerprint][]*FirstSet{},
+	}
 
-	for _, ps := range prods.All() {
-		for _, p := range ps {
-			len := p.rhsLen
-			if p.isEmpty() {
-				len = 1
-			}
-			fss[p.fingerprint] = make([]*FirstSet, len)
+	all := sortedProductions(prods)
+	for _, p := range all {
+		if _, ok := fss.symbols[p.lhs]; !ok {
+			fss.symbols[p.lhs] = newFirstSet()
 		}
 	}
 
-	return fss
-}
-
-func (fss FirstSets) Get(prod *Production, head int) *FirstSet {
-	fs := fss[prod.fingerprint][head]
-
-	//	log.Printf("[FirstSets.Get] fss[%v]: %v", prod.fingerprint, fss[prod.fingerprint])
-	//	log.Printf("[FirstSets.Get] return fss[%v][%v] %v", prod.fingerprint, head, fs)
-
-	return fs
-}
-
-func (fss FirstSets) put(fs *FirstSet, prod *Production, head int) {
-	fss[prod.fingerprint][head] = fs
-
-	//	log.Printf("[FirstSets.put] fss[%v]: %v", prod.fingerprint, fss[prod.fingerprint])
-}
-
-// FirstSetComputationContext is a context of computation of the FIRST set.
-type FirstSetComputationContext struct {
-	prods Productions
-	first FirstSets
-	stack FirstSetComputationStack
-}
-
-type FirstSetComputationStack []*FirstSetFrame
-
-func (cs FirstSetComputationStack) String() string {
-	s := ""
-	for _, frame := range cs {
-		if s != "" {
-			s += " "
+	for changed := true; changed; {
+		changed = false
+		for _, p := range all {
+			if fss.nullable.Contains(p.lhs) {
+				continue
+			}
+			if fss.isNullableSequence(p.rhs) {
+				fss.nullable.put(p.lhs)
+				fss.symbols[p.lhs].putEmpty()
+				changed = true
+			}
 		}
-		s += fmt.Sprintf("%v@%v", frame.prodFingerprint, frame.head)
 	}
 
-	return "{" + s + "}"
-}
-
-func (cs *FirstSetComputationStack) push(frame *FirstSetFrame) {
-	*cs = append(*cs, frame)
-}
-
-func (cs *FirstSetComputationStack) pop() {
-	*cs = []*FirstSetFrame(*cs)[:len(*cs)-1]
-}
-
-type FirstSetFrame struct {
-	prodFingerprint ProductionFingerprint
-	head            int
-}
-
-func newFirstSetComputationContext(prods Productions) *FirstSetComputationContext {
-	return &FirstSetComputationContext{
-		prods: prods,
-		first: newFirstSets(prods),
-		stack: []*FirstSetFrame{},
+	for changed := true; changed; {
+		changed = false
+		for _, p := range all {
+			lhsFirst := fss.symbols[p.lhs]
+			for _, sym := range p.rhs {
+				if sym.Kind().IsTerminalSymbol() {
+					if !lhsFirst.symbols.Contains(sym) {
+						lhsFirst.put(sym)
+						changed = true
+					}
+					break
+				}
+				if symFirst, ok := fss.symbols[sym]; ok && lhsFirst.merge(symFirst) {
+					changed = true
+				}
+				if !fss.nullable.Contains(sym) {
+					break
+				}
+			}
+		}
 	}
-}
 
-func (cc *FirstSetComputationContext) push(prod *Production, head int) {
-	cc.stack.push(&FirstSetFrame{
-		prodFingerprint: prod.fingerprint,
-		head:            head,
-	})
-}
-
-func (cc *FirstSetComputationContext) pop() {
-	cc.stack.pop()
-}
-
-func (cc *FirstSetComputationContext) isAlreadyStacked(prod *Production, head int) bool {
-	for _, f := range cc.stack {
-		if f.prodFingerprint == prod.fingerprint && f.head == head {
-			return true
+	for _, p := range all {
+		n := p.rhsLen
+		if p.isEmpty() {
+			n = 1
+		}
+		fs := make([]*FirstSet, n)
+		for head := 0; head < n; head++ {
+			fs[head] = fss.FirstOfSequence(p.rhs[head:])
 		}
+		fss.prods[p.fingerprint] = fs
 	}
 
-	return false
+	return fss, nil
 }
 
-func GenerateFirstSets(prods Productions) (FirstSets, error) {
-	cc := newFirstSetComputationContext(prods)
-
+func sortedProductions(prods Productions) []*Production {
+	all := []*Production{}
 	for _, ps := range prods.All() {
-		for _, p := range ps {
-			if p.isEmpty() {
-				_, err := first(p, 0, cc)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				for i := 0; i < p.rhsLen; i++ {
-					_, err := first(p, i, cc)
-					if err != nil {
-						return nil, err
-					}
-				}
-			}
-		}
+		all = append(all, ps...)
 	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].id < all[j].id
+	})
 
-	return cc.first, nil
+	return all
 }
 
-func first(prod *Production, head int, cc *FirstSetComputationContext) (*FirstSet, error) {
-	//	log.Printf("[first] %v @%v %v", prod.fingerprint, head, cc.stack)
-	//	defer log.Printf("[first] return")
-
-	// validations
-	{
-		if prod.isEmpty() {
-			if head != 0 {
-				return nil, fmt.Errorf("a production passed is the empty rule but head is not 0. got: %v", head)
-			}
-		} else {
-			if head < 0 || head >= prod.rhsLen {
-				return nil, fmt.Errorf("head is out of bounds. head must be between 0 and %v. got: %v", prod.rhsLen-1, head)
-			}
+func (fss *FirstSets) isNullableSequence(syms []SymbolID) bool {
+	for _, sym := range syms {
+		if !fss.nullable.Contains(sym) {
+			return false
 		}
 	}
 
-	// guards for avoiding the infinite recursion
-	{
-		// already computed
-		if fs := cc.first.Get(prod, head); fs != nil {
-			//			log.Printf("[first] already computed")
-
-			return fs, nil
-		}
-
-		if cc.isAlreadyStacked(prod, head) {
-			//			log.Printf("[first] already stacked")
+	return true
+}
 
-			return newFirstSet(), nil
-		}
+// Get returns the FIRST set of the suffix of prod starting at head. The FIRST set of an empty production is at
+// head 0.
+func (fss *FirstSets) Get(prod *Production, head int) *FirstSet {
+	fs, ok := fss.prods[prod.fingerprint]
+	if !ok || head < 0 || head >= len(fs) {
+		return nil
 	}
 
-	cc.push(prod, head)
-	defer cc.pop()
+	return fs[head]
+}
 
-	symbols := []SymbolID{}
-	if !prod.isEmpty() {
-		symbols = prod.rhs[head:]
-	}
+// Nullable returns the set of non-terminal symbols that derive the empty string.
+func (fss *FirstSets) Nullable() SymbolSet {
+	return fss.nullable
+}
 
-	// When symbols is empty, its FIRST set contains the only EMPTY symbol.
-	if len(symbols) <= 0 {
+// FirstOfSymbol returns the FIRST set of sym. The FIRST set of a terminal symbol contains only the symbol itself.
+// It returns nil when sym is a non-terminal symbol that has no production.
+func (fss *FirstSets) FirstOfSymbol(sym SymbolID) *FirstSet {
+	if sym.Kind().IsTerminalSymbol() {
 		fs := newFirstSet()
-		fs.putEmpty()
-		cc.first.put(fs, prod, head)
-
-		return fs, nil
+		fs.put(sym)
+		return fs
 	}
 
-	sym := symbols[0]
+	return fss.symbols[sym]
+}
 
-	{
-		symKind := sym.Kind()
-		if symKind.IsNil() {
-			return nil, fmt.Errorf("invalid symbol")
-		}
-		if symKind.IsTerminalSymbol() {
-			fs := newFirstSet()
+// FirstOfSequence returns the FIRST set of a sequence of symbols. The set contains ε when all the symbols are
+// nullable, including when syms is empty.
+func (fss *FirstSets) FirstOfSequence(syms []SymbolID) *FirstSet {
+	fs := newFirstSet()
+	for _, sym := range syms {
+		if sym.Kind().IsTerminalSymbol() {
 			fs.put(sym)
-			cc.first.put(fs, prod, head)
-
-			return fs, nil
+			return fs
 		}
-	}
-
-	fs := newFirstSet()
-	for _, symProd := range cc.prods.Get(sym) {
-		symFs, err := first(symProd, 0, cc)
-		if err != nil {
-			return nil, err
+		if symFirst, ok := fss.symbols[sym]; ok {
+			fs.merge(symFirst)
 		}
-
-		fs.merge(symFs)
-
-		if symFs.empty {
-			if symProd.isEmpty() {
-				fs.putEmpty()
-			} else {
-				f, err := first(symProd, head+1, cc)
-				if err != nil {
-					return nil, err
-				}
-				fs.merge(f)
-			}
+		if !fss.nullable.Contains(sym) {
+			return fs
 		}
 	}
-	cc.first.put(fs, prod, head)
+	fs.putEmpty()
 
-	return fs, nil
+	return fs
 }
@@ -14,10 +14,6 @@ func (fss FollowSets) Get(sym SymbolID) *FollowSet {
 	return fss[sym]
 }
 
-func (fss FollowSets) put(fs *FollowSet, sym SymbolID) {
-	fss[sym] = fs
-}
-
 type FollowSet struct {
 	symbols SymbolSet
 	eof     bool
@@ -38,6 +34,16 @@ func (fs *FollowSet) String() string {
 	return fs.symbols.String()
 }
 
+// Symbols returns the terminal symbols in the set.
+func (fs *FollowSet) Symbols() SymbolSet {
+	return fs.symbols
+}
+
+// EOF reports whether the set contains the end of input.
+func (fs *FollowSet) EOF() bool {
+	return fs.eof
+}
+
 func (fs *FollowSet) put(sym SymbolID) {
 	fs.symbols.put(sym)
 }
@@ -46,151 +52,80 @@ func (fs *FollowSet) putEOF() {
 	fs.eof = true
 }
 
-func (fs *FollowSet) merge(fst *FirstSet, flw *FollowSet) {
-	if fst != nil {
-		for sym, _ := range fst.symbols {
-			fs.symbols.put(sym)
-		}
+// merge adds the symbols of fst and flw to the set and reports whether the set has changed.
+func (fs *FollowSet) merge(fst *FirstSet, flw *FollowSet) bool {
+	changed := false
+	if fst != nil && fs.symbols.merge(fst.symbols) {
+		changed = true
 	}
 
 	if flw != nil {
-		for sym, _ := range flw.symbols {
-			fs.symbols.put(sym)
+		if fs.symbols.merge(flw.symbols) {
+			changed = true
 		}
 
-		if flw.eof {
+		if flw.eof && !fs.eof {
 			fs.putEOF()
+			changed = true
 		}
 	}
-}
-
-// FollowSetComputationContext is a context of computation of the FOLLOW set.
-type FollowSetComputationContext struct {
-	prods  Productions
-	first  FirstSets
-	follow FollowSets
-	stack  FollowSetComputationStack
-}
-
-type FollowSetComputationStack []SymbolID
-
-func (cs FollowSetComputationStack) String() string {
-	s := ""
-	for _, frame := range cs {
-		if s != "" {
-			s += " "
-		}
-		s += fmt.Sprintf("%v", frame)
-	}
-
-	return "{" + s + "}"
-}
-
-func (cs *FollowSetComputationStack) push(frame SymbolID) {
-	*cs = append(*cs, frame)
-}
 
-func (cs *FollowSetComputationStack) pop() {
-	*cs = []SymbolID(*cs)[:len(*cs)-1]
+	return changed
 }
 
-func newFollowSetComputationContext(prods Productions, first FirstSets) *FollowSetComputationContext {
-	return &FollowSetComputationContext{
-		prods:  prods,
-		first:  first,
-		follow: newFollowSets(),
-		stack:  []SymbolID{},
+// GenerateFollowSets computes the FOLLOW sets of the LHSs of prods.
+//
+// The FOLLOW set of an augmented start symbol contains EOF. The sets are computed as the least fixed points of the
+// following rule, applied until no set changes.
+//
+// For each production A → α B β,
+// FOLLOW(B) ⊇ FIRST(β) - {ε}
+// FOLLOW(B) ⊇ FOLLOW(A) when β is nullable
+func GenerateFollowSets(prods Productions, first *FirstSets) (FollowSets, error) {
+	if first == nil {
+		return nil, fmt.Errorf("FIRST sets passed is nil")
 	}
-}
-
-func (cc *FollowSetComputationContext) push(sym SymbolID) {
-	cc.stack.push(sym)
-}
 
-func (cc *FollowSetComputationContext) pop() {
-	cc.stack.pop()
-}
-
-func (cc *FollowSetComputationContext) isAlreadyStacked(sym SymbolID) bool {
-	for _, f := range cc.stack {
-		if f == sym {
-			return true
+	follow := newFollowSets()
+	all := sortedProductions(prods)
+	for _, p := range all {
+		if _, ok := follow[p.lhs]; ok {
+			continue
 		}
-	}
-
-	return false
-}
-
-func GenerateFollowSets(prods Productions, first FirstSets) (FollowSets, error) {
-	cc := newFollowSetComputationContext(prods, first)
-
-	for _, ps := range prods.All() {
-		for _, p := range ps {
-			_, err := follow(p.lhs, cc)
-			if err != nil {
-				return nil, err
-			}
-		}
-	}
-
-	return cc.follow, nil
-}
-
-func follow(sym SymbolID, cc *FollowSetComputationContext) (*FollowSet, error) {
-	// validations
-	{
-		if sym.IsNil() {
-			return nil, fmt.Errorf("symbol is nil")
-		}
-	}
-
-	// guards for avoiding the infinite recursion
-	{
-		// already computed
-		if fs := cc.follow.Get(sym); fs != nil {
-			return fs, nil
-		}
-
-		if cc.isAlreadyStacked(sym) {
-			return newFollowSet(), nil
+		fs := newFollowSet()
+		if p.lhs.Kind().IsStartSymbol() {
+			fs.putEOF()
 		}
+		follow[p.lhs] = fs
 	}
 
-	cc.push(sym)
-	defer cc.pop()
-
-	fs := newFollowSet()
-
-	if sym.Kind().IsStartSymbol() {
-		fs.putEOF()
-	}
+	for changed := true; changed; {
+		changed = false
+		for _, p := range all {
+			for i, sym := range p.rhs {
+				fs, ok := follow[sym]
+				if !ok {
+					continue
+				}
 
-	for _, ps := range cc.prods.All() {
-		for _, p := range ps {
-			for i, rhsSym := range p.rhs {
-				if rhsSym == sym {
-					if i+1 < p.rhsLen {
-						fst := cc.first.Get(p, i+1)
-						if fst == nil {
-							return nil, fmt.Errorf("failed to get a FIRST set. %v-%v", p.fingerprint, i)
-						}
-						fs.merge(fst, nil)
-
-						if !fst.empty {
-							continue
-						}
-					}
+				fst := first.Get(p, i+1)
+				if i+1 >= p.rhsLen {
+					fst = nil
+				} else if fst == nil {
+					return nil, fmt.Errorf("failed to get a FIRST set. %v-%v", p.fingerprint, i+1)
+				}
 
-					flw, err := follow(p.lhs, cc)
-					if err != nil {
-						return nil, err
+				if fs.merge(fst, nil) {
+					changed = true
+				}
+				if fst == nil || fst.empty {
+					if fs.merge(nil, follow[p.lhs]) {
+						changed = true
 					}
-					fs.merge(nil, flw)
 				}
 			}
 		}
 	}
-	cc.follow.put(fs, sym)
 
-	return fs, nil
+	return follow, nil
 }
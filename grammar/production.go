@@ -128,6 +128,14 @@ func (prod *Production) ID() ProductionID {
 	return prod.id
 }
 
+func (prod *Production) Fingerprint() ProductionFingerprint {
+	return prod.fingerprint
+}
+
+func (prod *Production) LHS() SymbolID {
+	return prod.lhs
+}
+
 func (prod *Production) RHS() ([]SymbolID, int) {
 	return prod.rhs, prod.rhsLen
 }
@@ -2,6 +2,7 @@ package grammar
 
 import (
 	"fmt"
+	"sort"
 )
 
 type ActionType int
@@ -40,29 +41,76 @@ func (a *Action) Production() ProductionFingerprint {
 	return a.prod
 }
 
+func (a *Action) equal(b *Action) bool {
+	return a.t == b.t && a.nextState == b.nextState && a.prod == b.prod
+}
+
 type Actions struct {
 	actions     map[SymbolID]*Action
 	acceptable  bool
 	reduceByEOF ProductionFingerprint
+
+	// conflicts holds all the actions on each symbol that has two or more actions, and reducesByEOF holds all the
+	// productions to reduce by EOF.
+	conflicts    map[SymbolID][]*Action
+	reducesByEOF []ProductionFingerprint
 }
 
+func newActions() *Actions {
+	return &Actions{
+		actions:      map[SymbolID]*Action{},
+		acceptable:   false,
+		conflicts:    map[SymbolID][]*Action{},
+		reducesByEOF: []ProductionFingerprint{},
+	}
+}
+
+// Actions returns the action on each symbol. When a symbol has conflicting actions, it returns the one that a
+// deterministic parser takes: a shift takes precedence over a reduction, and a reduction by an earlier production
+// takes precedence over a later one, as in yacc.
 func (as *Actions) Actions() map[SymbolID]*Action {
 	return as.actions
 }
 
+// Conflicts returns all the actions on each symbol that has two or more actions. A GLR parser takes all of them.
+func (as *Actions) Conflicts() map[SymbolID][]*Action {
+	return as.conflicts
+}
+
 func (as *Actions) Acceptable() bool {
 	return as.acceptable
 }
 
+// ReduceByEOF returns the production to reduce by EOF. When there are two or more, it returns the earliest one of
+// them. When the state also accepts EOF, the accept action takes precedence, and ReduceByEOF reports false.
 func (as *Actions) ReduceByEOF() (ProductionFingerprint, bool) {
+	if as.acceptable {
+		return "", false
+	}
 	return as.reduceByEOF, !as.reduceByEOF.IsNil()
 }
 
+// ReducesByEOF returns all the productions to reduce by EOF.
+func (as *Actions) ReducesByEOF() []ProductionFingerprint {
+	return as.reducesByEOF
+}
+
+// ConflictByEOF reports whether the actions by EOF conflict, that is, there are two or more reductions or there are
+// both an accept action and a reduction.
+func (as *Actions) ConflictByEOF() bool {
+	n := len(as.reducesByEOF)
+	if as.acceptable {
+		n++
+	}
+	return n > 1
+}
+
 type ParsingTable struct {
-	states       map[KernelFingerprint]StateID
-	initialState KernelFingerprint
-	action       map[KernelFingerprint]*Actions
-	goTo         map[KernelFingerprint]map[SymbolID]KernelFingerprint
+	states        map[KernelFingerprint]StateID
+	initialState  KernelFingerprint
+	initialStates map[SymbolID]KernelFingerprint
+	action        map[KernelFingerprint]*Actions
+	goTo          map[KernelFingerprint]map[SymbolID]KernelFingerprint
 }
 
 func newParsingTable(automaton *LR0Automaton) *ParsingTable {
@@ -71,11 +119,17 @@ func newParsingTable(automaton *LR0Automaton) *ParsingTable {
 		states[kernelFp] = state.ID
 	}
 
+	initialStates := map[SymbolID]KernelFingerprint{}
+	for sym, kernelFp := range automaton.initialStates {
+		initialStates[sym] = kernelFp
+	}
+
 	return &ParsingTable{
-		states:       states,
-		initialState: automaton.initialState,
-		action:       map[KernelFingerprint]*Actions{},
-		goTo:         map[KernelFingerprint]map[SymbolID]KernelFingerprint{},
+		states:        states,
+		initialState:  automaton.initialState,
+		initialStates: initialStates,
+		action:        map[KernelFingerprint]*Actions{},
+		goTo:          map[KernelFingerprint]map[SymbolID]KernelFingerprint{},
 	}
 }
 
@@ -83,14 +137,43 @@ func (pt *ParsingTable) States() map[KernelFingerprint]StateID {
 	return pt.states
 }
 
+// InitialState returns the initial state of the first augmented start symbol.
 func (pt *ParsingTable) InitialState() KernelFingerprint {
 	return pt.initialState
 }
 
+// InitialStates returns the initial state of each augmented start symbol.
+func (pt *ParsingTable) InitialStates() map[SymbolID]KernelFingerprint {
+	return pt.initialStates
+}
+
 func (pt *ParsingTable) Action() map[KernelFingerprint]*Actions {
 	return pt.action
 }
 
+// ExpectedTerminals returns the terminal symbols on which state has an action in the order in which they were
+// interned. The second value reports whether state has an action on EOF, that is, whether it accepts or reduces by
+// EOF.
+func (pt *ParsingTable) ExpectedTerminals(state KernelFingerprint) ([]SymbolID, bool) {
+	as, ok := pt.action[state]
+	if !ok {
+		return []SymbolID{}, false
+	}
+
+	syms := make([]SymbolID, 0, len(as.actions))
+	for sym := range as.actions {
+		syms = append(syms, sym)
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		bi, _ := syms[i].bareID()
+		bj, _ := syms[j].bareID()
+		return bi < bj
+	})
+	_, reduceByEOF := as.ReduceByEOF()
+
+	return syms, as.acceptable || reduceByEOF
+}
+
 func (pt *ParsingTable) GoTo() map[KernelFingerprint]map[SymbolID]KernelFingerprint {
 	return pt.goTo
 }
@@ -113,21 +196,19 @@ func (pt *ParsingTable) appendReduceAction(state KernelFingerprint, sym SymbolID
 
 func (pt *ParsingTable) appendReduceActionByEOF(state KernelFingerprint, prod ProductionFingerprint) {
 	if _, ok := pt.action[state]; !ok {
-		pt.action[state] = &Actions{
-			actions:    map[SymbolID]*Action{},
-			acceptable: false,
-		}
+		pt.action[state] = newActions()
 	}
 
-	pt.action[state].reduceByEOF = prod
+	as := pt.action[state]
+	if as.reduceByEOF.IsNil() {
+		as.reduceByEOF = prod
+	}
+	as.reducesByEOF = append(as.reducesByEOF, prod)
 }
 
 func (pt *ParsingTable) appendAcceptAction(state KernelFingerprint) {
 	if _, ok := pt.action[state]; !ok {
-		pt.action[state] = &Actions{
-			actions:    map[SymbolID]*Action{},
-			acceptable: false,
-		}
+		pt.action[state] = newActions()
 	}
 
 	pt.action[state].acceptable = true
@@ -139,13 +220,31 @@ func (pt *ParsingTable) appendAction(a *Action, state KernelFingerprint, sym Sym
 	}
 
 	if _, ok := pt.action[state]; !ok {
-		pt.action[state] = &Actions{
-			actions:    map[SymbolID]*Action{},
-			acceptable: false,
-		}
+		pt.action[state] = newActions()
 	}
 
-	pt.action[state].actions[sym] = a
+	as := pt.action[state]
+	if cur, ok := as.actions[sym]; ok {
+		if len(as.conflicts[sym]) == 0 {
+			as.conflicts[sym] = []*Action{cur}
+		}
+		for _, c := range as.conflicts[sym] {
+			if c.equal(a) {
+				if len(as.conflicts[sym]) == 1 {
+					delete(as.conflicts, sym)
+				}
+				return nil
+			}
+		}
+		as.conflicts[sym] = append(as.conflicts[sym], a)
+
+		// The actions are appended in order of production, so the current reduction is by the earlier production.
+		if cur.t == ActionTypeReduce && a.t == ActionTypeShift {
+			as.actions[sym] = a
+		}
+		return nil
+	}
+	as.actions[sym] = a
 
 	return nil
 }
@@ -164,6 +263,9 @@ func (pt *ParsingTable) appendGoTo(state KernelFingerprint, sym SymbolID, nextSt
 	return nil
 }
 
+// GenerateSLRParsingTable generates an SLR(1) parsing table. When a cell has conflicting actions, the table keeps all
+// of them, and a deterministic parser takes the one that yacc takes: the shift of a shift/reduce conflict and the
+// reduction by the earliest production of a reduce/reduce conflict.
 func GenerateSLRParsingTable(automaton *LR0Automaton, follow FollowSets) (*ParsingTable, error) {
 	if automaton == nil || follow == nil {
 		return nil, fmt.Errorf("parameters passed contains nil")
@@ -172,7 +274,7 @@ func GenerateSLRParsingTable(automaton *LR0Automaton, follow FollowSets) (*Parsi
 	pt := newParsingTable(automaton)
 
 	for _, state := range automaton.states {
-		for _, item := range state.Items {
+		for _, item := range state.SortedItems() {
 			if item.reducible {
 				if item.prod.lhs.Kind().IsStartSymbol() {
 					pt.appendAcceptAction(state.Fingerprint)
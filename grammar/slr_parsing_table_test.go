@@ -50,7 +50,7 @@ func TestParsingTable(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	automaton, err := GenerateLR0Automaton(st, prods, st.lookupByString("E'"))
+	automaton, err := GenerateLR0Automaton(st, prods, st.LookupByString("E'"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -358,3 +358,208 @@ func TestParsingTable(t *testing.T) {
 		}
 	}
 }
+
+func TestParsingTable_MultipleStartSymbols(t *testing.T) {
+	st := NewSymbolTable()
+	st.Intern("E'", SymbolKindStart)
+
+	prods := newProds(st, "S'", []*Prod{
+		newProd("S'", "S"),
+		newProd("E'", "E"),
+		newProd("S", "E", ";"),
+		newProd("E", "E", "+", "id"),
+		newProd("E", "id"),
+	})
+
+	V := newSymbolGetter(st)
+
+	first, err := GenerateFirstSets(prods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := GenerateFollowSets(prods, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := GenerateLR0Automaton(st, prods, V("S'"), V("E'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slrPT, err := GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initialStates := slrPT.InitialStates()
+	if len(initialStates) != 2 {
+		t.Fatalf("unexpected number of initial states\nwant: %v\ngot: %v", 2, len(initialStates))
+	}
+	for start, nonTerm := range map[string]string{"S'": "S", "E'": "E"} {
+		initialState, ok := initialStates[V(start)]
+		if !ok {
+			t.Fatalf("an initial state of %v was not found", start)
+		}
+		nextState, ok := slrPT.GoTo()[initialState][V(nonTerm)]
+		if !ok {
+			t.Fatalf("a goto of %v was not found in the initial state of %v", nonTerm, start)
+		}
+		if !slrPT.Action()[nextState].Acceptable() {
+			t.Fatalf("the state after %v is not acceptable", nonTerm)
+		}
+	}
+}
+
+func TestParsingTable_ExpectedTerminals(t *testing.T) {
+	st := NewSymbolTable()
+
+	prods := newProds(st, "E'", []*Prod{
+		newProd("E'", "E"),
+		newProd("E", "E", "+", "T"),
+		newProd("E", "T"),
+		newProd("T", "T", "*", "F"),
+		newProd("T", "F"),
+		newProd("F", "(", "E", ")"),
+		newProd("F", "id"),
+	})
+
+	V := newSymbolGetter(st)
+
+	first, err := GenerateFirstSets(prods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := GenerateFollowSets(prods, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := GenerateLR0Automaton(st, prods, V("E'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slrPT, err := GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initial := slrPT.InitialState()
+	tests := map[string]struct {
+		state    KernelFingerprint
+		expected []string
+		eof      bool
+	}{
+		"initial state": {
+			state:    initial,
+			expected: []string{"(", "id"},
+		},
+		"state accepting E": {
+			state:    slrPT.GoTo()[initial][V("E")],
+			expected: []string{"+"},
+			eof:      true,
+		},
+		"state reducing F → id": {
+			state:    slrPT.Action()[initial].Actions()[V("id")].NextState(),
+			expected: []string{"+", "*", ")"},
+			eof:      true,
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			syms, eof := slrPT.ExpectedTerminals(tt.state)
+			if len(syms) != len(tt.expected) {
+				t.Fatalf("unexpected terminals\nwant: %v\ngot: %v", tt.expected, syms)
+			}
+			for i, name := range tt.expected {
+				if syms[i] != V(name) {
+					t.Fatalf("unexpected terminals\nwant: %v\ngot: %v", tt.expected, syms)
+				}
+			}
+			if eof != tt.eof {
+				t.Fatalf("unexpected EOF\nwant: %v\ngot: %v", tt.eof, eof)
+			}
+		})
+	}
+}
+
+func TestParsingTable_Conflicts(t *testing.T) {
+	st := NewSymbolTable()
+
+	prods := newProds(st, "S'", []*Prod{
+		newProd("S'", "S"),
+		newProd("S", "E"),
+		newProd("S", "A"),
+		newProd("S", "E", ";"),
+		newProd("S", "A", ";"),
+		newProd("E", "E", "+", "E"),
+		newProd("E", "id"),
+		newProd("A", "id"),
+	})
+
+	V := newSymbolGetter(st)
+	P := newProductionGetter(st, prods)
+
+	first, err := GenerateFirstSets(prods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := GenerateFollowSets(prods, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := GenerateLR0Automaton(st, prods, V("S'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slrPT, err := GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initial := slrPT.InitialState()
+	afterE := slrPT.GoTo()[initial][V("E")]
+	afterPlus := slrPT.Action()[afterE].Actions()[V("+")].NextState()
+	afterEPlusE := slrPT.GoTo()[afterPlus][V("E")]
+
+	// E → E + E . and E → E . + E conflict on +.
+	as := slrPT.Action()[afterEPlusE]
+	conflicts := as.Conflicts()[V("+")]
+	if len(conflicts) != 2 {
+		t.Fatalf("unexpected number of actions on +\nwant: %v\ngot: %v", 2, len(conflicts))
+	}
+	if conflicts[0].Type() != ActionTypeShift || conflicts[1].Type() != ActionTypeReduce {
+		t.Fatalf("unexpected actions on +\nwant: [shift reduce]\ngot: [%v %v]", conflicts[0].Type(), conflicts[1].Type())
+	}
+	if conflicts[0].NextState() != afterPlus {
+		t.Fatalf("unexpected next state\nwant: %v\ngot: %v", afterPlus, conflicts[0].NextState())
+	}
+	if as.Actions()[V("+")] != conflicts[0] {
+		t.Fatalf("a deterministic parser must take the shift action")
+	}
+	if as.ConflictByEOF() {
+		t.Fatalf("the actions by EOF must not conflict")
+	}
+
+	// E → id . and A → id . conflict on ; and EOF, and a deterministic parser takes the earlier production E → id.
+	afterID := slrPT.Action()[initial].Actions()[V("id")].NextState()
+	as = slrPT.Action()[afterID]
+	conflicts = as.Conflicts()[V(";")]
+	if len(as.Conflicts()) != 1 || len(conflicts) != 2 {
+		t.Fatalf("unexpected conflicts: %v", as.Conflicts())
+	}
+	if conflicts[0].Production() != P("E", 1).Fingerprint() || conflicts[1].Production() != P("A", 0).Fingerprint() {
+		t.Fatalf("unexpected actions on ;\nwant: [%v %v]\ngot: [%v %v]", P("E", 1).Fingerprint(), P("A", 0).Fingerprint(), conflicts[0].Production(), conflicts[1].Production())
+	}
+	if as.Actions()[V(";")] != conflicts[0] {
+		t.Fatalf("a deterministic parser must take the reduction by the earlier production")
+	}
+	if !as.ConflictByEOF() || len(as.ReducesByEOF()) != 2 {
+		t.Fatalf("the actions by EOF must conflict. got: %v", as.ReducesByEOF())
+	}
+	if prod, _ := as.ReduceByEOF(); prod != P("E", 1).Fingerprint() {
+		t.Fatalf("unexpected production to reduce by EOF\nwant: %v\ngot: %v", P("E", 1).Fingerprint(), prod)
+	}
+
+	// Actions without conflicts are kept as they are.
+	if len(slrPT.Action()[initial].Conflicts()) != 0 {
+		t.Fatalf("unexpected conflicts: %v", slrPT.Action()[initial].Conflicts())
+	}
+}
@@ -2,6 +2,7 @@ package grammar
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 )
 
@@ -30,6 +31,10 @@ func (id *bareSymbolID) next() bareSymbolID {
 	return nextID
 }
 
+// ErrorSymbolName is the name of the reserved terminal symbol error. An alternative containing error matches an
+// erroneous part of an input, and parsers recover from syntax errors by shifting it.
+const ErrorSymbolName = "error"
+
 type SymbolKind string
 
 const (
@@ -119,10 +124,24 @@ func (id SymbolID) Kind() SymbolKind {
 	return SymbolKindNil
 }
 
+func (id SymbolID) bareID() (bareSymbolID, error) {
+	if id.IsNil() {
+		return bareSymbolIDNil, fmt.Errorf("symbol id is nil")
+	}
+
+	n, err := strconv.Atoi(string(id[1:]))
+	if err != nil {
+		return bareSymbolIDNil, err
+	}
+
+	return bareSymbolID(n), nil
+}
+
 type Symbol struct {
 	id     SymbolID
 	bareID bareSymbolID
 	kind   SymbolKind
+	text   string
 }
 
 type SymbolTable struct {
@@ -153,6 +172,7 @@ func (st *SymbolTable) Intern(str string, kind SymbolKind) SymbolID {
 		id:     id,
 		bareID: bareID,
 		kind:   kind,
+		text:   str,
 	}
 
 	st.str2Sym[str] = sym
@@ -161,7 +181,7 @@ func (st *SymbolTable) Intern(str string, kind SymbolKind) SymbolID {
 	return id
 }
 
-func (st *SymbolTable) lookupByString(str string) SymbolID {
+func (st *SymbolTable) LookupByString(str string) SymbolID {
 	if str == "" {
 		return symbolIDNil
 	}
@@ -172,3 +192,35 @@ func (st *SymbolTable) lookupByString(str string) SymbolID {
 
 	return symbolIDNil
 }
+
+func (st *SymbolTable) ToString(id SymbolID) (string, bool) {
+	bareID, err := id.bareID()
+	if err != nil {
+		return "", false
+	}
+
+	sym, ok := st.id2Sym[bareID]
+	if !ok || sym.id != id {
+		return "", false
+	}
+
+	return sym.text, true
+}
+
+// Symbols returns all symbols in the order in which they were interned.
+func (st *SymbolTable) Symbols() []SymbolID {
+	syms := make([]*Symbol, 0, len(st.id2Sym))
+	for _, sym := range st.id2Sym {
+		syms = append(syms, sym)
+	}
+	sort.Slice(syms, func(i, j int) bool {
+		return syms[i].bareID < syms[j].bareID
+	})
+
+	ids := make([]SymbolID, len(syms))
+	for i, sym := range syms {
+		ids[i] = sym.id
+	}
+
+	return ids
+}
@@ -41,6 +41,48 @@ func TestSymbolTable(t *testing.T) {
 	t.Run("Intern invalid symbols as a start symbol", func(t *testing.T) {
 		testInvalidSymbols(t, invalidSymbols, SymbolKindStart)
 	})
+
+	t.Run("Convert symbol IDs to strings", func(t *testing.T) {
+		st := NewSymbolTable()
+		for _, sym := range validSymbols {
+			sid := st.Intern(sym, SymbolKindTerminal)
+			text, ok := st.ToString(sid)
+			if !ok {
+				t.Fatalf("failed to convert a symbol ID to a string. symbol ID: %v", sid)
+			}
+			if text != sym {
+				t.Fatalf("unexpected string\nwant: %v\ngot: %v", sym, text)
+			}
+			if st.LookupByString(text) != sid {
+				t.Fatalf("unexpected symbol ID\nwant: %v\ngot: %v", sid, st.LookupByString(text))
+			}
+		}
+
+		if _, ok := st.ToString(SymbolID("n100")); ok {
+			t.Fatal("an unknown symbol ID was converted to a string")
+		}
+	})
+
+	t.Run("List symbols in the order in which they were interned", func(t *testing.T) {
+		st := NewSymbolTable()
+		expected := []SymbolID{
+			st.Intern("E'", SymbolKindStart),
+			st.Intern("E", SymbolKindNonTerminal),
+			st.Intern("+", SymbolKindTerminal),
+			st.Intern("id", SymbolKindTerminal),
+		}
+		st.Intern("E", SymbolKindNonTerminal)
+
+		syms := st.Symbols()
+		if len(syms) != len(expected) {
+			t.Fatalf("unexpected number of symbols\nwant: %v\ngot: %v", len(expected), len(syms))
+		}
+		for i, sym := range syms {
+			if sym != expected[i] {
+				t.Fatalf("unexpected symbol\nwant: %v\ngot: %v", expected[i], sym)
+			}
+		}
+	})
 }
 
 func testValidSymbols(t *testing.T, symbols []string, kind SymbolKind) {
@@ -49,7 +49,7 @@ func sortSymbols(syms []SymbolID) []SymbolID {
 
 func newSymbolGetter(st *SymbolTable) func(string) SymbolID {
 	return func(str string) SymbolID {
-		return st.lookupByString(str)
+		return st.LookupByString(str)
 	}
 }
 
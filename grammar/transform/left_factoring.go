@@ -0,0 +1,129 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/nihei9/sousa/grammar"
+)
+
+// LeftFactor factors out the longest common prefix of alternatives sharing the same first symbol.
+// For each factored prefix, it generates a non-terminal named like A_lf.
+//
+// A → α β1 | α β2 | γ  becomes  A → α A_lf | γ; A_lf → β1 | β2
+func LeftFactor(st *grammar.SymbolTable, g *Grammar) (*Grammar, error) {
+	if st == nil || g == nil {
+		return nil, fmt.Errorf("parameters passed contains nil")
+	}
+
+	rs, err := newRules(g)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generated non-terminals are named after the non-terminal of the original grammar.
+	bases := map[grammar.SymbolID]grammar.SymbolID{}
+
+	// Rules generated while factoring are inserted after the rule being factored, so they are visited later in this loop.
+	for i := 0; i < len(rs.list); i++ {
+		r := rs.list[i]
+		if r.lhs.Kind().IsStartSymbol() {
+			continue
+		}
+
+		prev := r
+		for {
+			group := findFactorableGroup(r)
+			if group == nil {
+				break
+			}
+
+			base, ok := bases[r.lhs]
+			if !ok {
+				base = r.lhs
+			}
+			tail, err := newNonTerminal(st, base, "_lf")
+			if err != nil {
+				return nil, err
+			}
+			bases[tail] = base
+
+			prefix := commonPrefix(group)
+			tailAlts := make([]*alternative, len(group))
+			for n, alt := range group {
+				tailAlts[n] = &alternative{
+					rhs:    alt.rhs[len(prefix):],
+					origin: alt.origin,
+				}
+			}
+
+			alts := []*alternative{}
+			for _, alt := range r.alts {
+				if alt == group[0] {
+					alts = append(alts, &alternative{
+						rhs:    concat(prefix, []grammar.SymbolID{tail}),
+						origin: alt.origin,
+					})
+					continue
+				}
+				if contains(group, alt) {
+					continue
+				}
+				alts = append(alts, alt)
+			}
+			r.alts = alts
+
+			tailRule := &rule{
+				lhs:  tail,
+				alts: tailAlts,
+			}
+			rs.insertAfter(prev, tailRule)
+			prev = tailRule
+		}
+	}
+
+	return rs.toGrammar()
+}
+
+// findFactorableGroup returns the first group of two or more alternatives that begin with the same symbol.
+func findFactorableGroup(r *rule) []*alternative {
+	for i, alt := range r.alts {
+		if len(alt.rhs) == 0 {
+			continue
+		}
+
+		group := []*alternative{alt}
+		for _, other := range r.alts[i+1:] {
+			if len(other.rhs) > 0 && other.rhs[0] == alt.rhs[0] {
+				group = append(group, other)
+			}
+		}
+		if len(group) >= 2 {
+			return group
+		}
+	}
+
+	return nil
+}
+
+func commonPrefix(alts []*alternative) []grammar.SymbolID {
+	prefix := alts[0].rhs
+	for _, alt := range alts[1:] {
+		n := 0
+		for n < len(prefix) && n < len(alt.rhs) && prefix[n] == alt.rhs[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	return prefix
+}
+
+func contains(alts []*alternative, target *alternative) bool {
+	for _, alt := range alts {
+		if alt == target {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package transform
+
+import (
+	"fmt"
+
+	"github.com/nihei9/sousa/grammar"
+)
+
+// EliminateLeftRecursion rewrites direct and indirect left recursion into right recursion.
+// For each left-recursive non-terminal A, it generates a non-terminal named like A_lr.
+//
+// A → A α | β  becomes  A → β A_lr; A_lr → α A_lr | ε
+//
+// Like the classic algorithm, it does not detect left recursion hidden behind nullable symbols.
+func EliminateLeftRecursion(st *grammar.SymbolTable, g *Grammar) (*Grammar, error) {
+	if st == nil || g == nil {
+		return nil, fmt.Errorf("parameters passed contains nil")
+	}
+
+	rs, err := newRules(g)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := []*rule{}
+	for _, r := range rs.list {
+		if r.lhs.Kind().IsStartSymbol() {
+			continue
+		}
+		ordered = append(ordered, r)
+	}
+
+	for i, ri := range ordered {
+		for substituted := true; substituted; {
+			substituted = false
+			for _, rj := range ordered[:i] {
+				if !rs.leftCorners(rj.lhs)[ri.lhs] {
+					continue
+				}
+
+				alts := []*alternative{}
+				for _, alt := range ri.alts {
+					if len(alt.rhs) == 0 || alt.rhs[0] != rj.lhs {
+						alts = append(alts, alt)
+						continue
+					}
+
+					for _, b := range rj.alts {
+						alts = append(alts, &alternative{
+							rhs:    concat(b.rhs, alt.rhs[1:]),
+							origin: alt.origin,
+						})
+					}
+					substituted = true
+				}
+				ri.alts = alts
+			}
+		}
+
+		err := eliminateDirectLeftRecursion(st, rs, ri)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rs.toGrammar()
+}
+
+func eliminateDirectLeftRecursion(st *grammar.SymbolTable, rs *rules, r *rule) error {
+	recursive := false
+	recAlts := []*alternative{}
+	otherAlts := []*alternative{}
+	for _, alt := range r.alts {
+		if len(alt.rhs) > 0 && alt.rhs[0] == r.lhs {
+			recursive = true
+			// A → A derives nothing new, so it is dropped.
+			if len(alt.rhs) > 1 {
+				recAlts = append(recAlts, alt)
+			}
+			continue
+		}
+		otherAlts = append(otherAlts, alt)
+	}
+	if !recursive {
+		return nil
+	}
+	if len(recAlts) <= 0 {
+		r.alts = otherAlts
+		return nil
+	}
+	if len(otherAlts) <= 0 {
+		text, _ := st.ToString(r.lhs)
+		return fmt.Errorf("all alternatives of %v are left-recursive", text)
+	}
+
+	tail, err := newNonTerminal(st, r.lhs, "_lr")
+	if err != nil {
+		return err
+	}
+
+	alts := make([]*alternative, len(otherAlts))
+	for i, alt := range otherAlts {
+		alts[i] = &alternative{
+			rhs:    concat(alt.rhs, []grammar.SymbolID{tail}),
+			origin: alt.origin,
+		}
+	}
+
+	tailAlts := make([]*alternative, 0, len(recAlts)+1)
+	for _, alt := range recAlts {
+		tailAlts = append(tailAlts, &alternative{
+			rhs:    concat(alt.rhs[1:], []grammar.SymbolID{tail}),
+			origin: alt.origin,
+		})
+	}
+	tailAlts = append(tailAlts, &alternative{
+		rhs:    []grammar.SymbolID{},
+		origin: recAlts[0].origin,
+	})
+
+	r.alts = alts
+	rs.insertAfter(r, &rule{
+		lhs:  tail,
+		alts: tailAlts,
+	})
+
+	return nil
+}
+
+// leftCorners returns the non-terminals that can appear at the leftmost position of sentential forms derived from sym.
+func (rs *rules) leftCorners(sym grammar.SymbolID) map[grammar.SymbolID]bool {
+	corners := map[grammar.SymbolID]bool{}
+	unchecked := []grammar.SymbolID{sym}
+	for len(unchecked) > 0 {
+		s := unchecked[len(unchecked)-1]
+		unchecked = unchecked[:len(unchecked)-1]
+
+		r := rs.get(s)
+		if r == nil {
+			continue
+		}
+		for _, alt := range r.alts {
+			if len(alt.rhs) == 0 {
+				continue
+			}
+			c := alt.rhs[0]
+			if !c.Kind().IsNonTerminalSymbol() || corners[c] {
+				continue
+			}
+			corners[c] = true
+			unchecked = append(unchecked, c)
+		}
+	}
+
+	return corners
+}
@@ -0,0 +1,179 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nihei9/sousa/grammar"
+)
+
+// Grammar is a set of productions paired with the productions of the original grammar they derive from.
+type Grammar struct {
+	Productions grammar.Productions
+	origins     map[*grammar.Production]*grammar.Production
+}
+
+// NewGrammar returns a Grammar whose productions are their own origins.
+func NewGrammar(prods grammar.Productions) *Grammar {
+	origins := map[*grammar.Production]*grammar.Production{}
+	for _, ps := range prods.All() {
+		for _, p := range ps {
+			origins[p] = p
+		}
+	}
+
+	return &Grammar{
+		Productions: prods,
+		origins:     origins,
+	}
+}
+
+// Origin returns the production of the original grammar that prod derives from.
+func (g *Grammar) Origin(prod *grammar.Production) *grammar.Production {
+	return g.origins[prod]
+}
+
+type alternative struct {
+	rhs    []grammar.SymbolID
+	origin *grammar.Production
+}
+
+type rule struct {
+	lhs  grammar.SymbolID
+	alts []*alternative
+}
+
+// rules holds productions grouped by LHS in the order in which each LHS first appears.
+type rules struct {
+	list []*rule
+}
+
+func newRules(g *Grammar) (*rules, error) {
+	all := []*grammar.Production{}
+	lhsOf := map[*grammar.Production]grammar.SymbolID{}
+	for lhs, ps := range g.Productions.All() {
+		for _, p := range ps {
+			all = append(all, p)
+			lhsOf[p] = lhs
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID() < all[j].ID()
+	})
+
+	rs := &rules{
+		list: []*rule{},
+	}
+	index := map[grammar.SymbolID]*rule{}
+	for _, p := range all {
+		origin := g.Origin(p)
+		if origin == nil {
+			return nil, fmt.Errorf("a production has no origin. production: %v", p)
+		}
+
+		lhs := lhsOf[p]
+		r, ok := index[lhs]
+		if !ok {
+			r = &rule{
+				lhs:  lhs,
+				alts: []*alternative{},
+			}
+			index[lhs] = r
+			rs.list = append(rs.list, r)
+		}
+
+		rhs, _ := p.RHS()
+		r.alts = append(r.alts, &alternative{
+			rhs:    rhs,
+			origin: origin,
+		})
+	}
+
+	return rs, nil
+}
+
+func (rs *rules) get(lhs grammar.SymbolID) *rule {
+	for _, r := range rs.list {
+		if r.lhs == lhs {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (rs *rules) insertAfter(prev *rule, r *rule) {
+	for i, pr := range rs.list {
+		if pr == prev {
+			list := make([]*rule, 0, len(rs.list)+1)
+			list = append(list, rs.list[:i+1]...)
+			list = append(list, r)
+			list = append(list, rs.list[i+1:]...)
+			rs.list = list
+			return
+		}
+	}
+
+	rs.list = append(rs.list, r)
+}
+
+func (rs *rules) toGrammar() (*Grammar, error) {
+	prods := grammar.NewProductions()
+	origins := map[*grammar.Production]*grammar.Production{}
+	for _, r := range rs.list {
+		for _, alt := range r.alts {
+			p, err := grammar.NewProduction(r.lhs, alt.rhs)
+			if err != nil {
+				return nil, err
+			}
+
+			duplicated := false
+			for _, q := range prods.Get(r.lhs) {
+				if q.Equal(p) {
+					duplicated = true
+					break
+				}
+			}
+			if duplicated {
+				continue
+			}
+
+			prods.Append(p)
+			origins[p] = alt.origin
+		}
+	}
+
+	return &Grammar{
+		Productions: prods,
+		origins:     origins,
+	}, nil
+}
+
+// newNonTerminal interns a fresh non-terminal symbol whose name is derived from base.
+func newNonTerminal(st *grammar.SymbolTable, base grammar.SymbolID, suffix string) (grammar.SymbolID, error) {
+	baseText, ok := st.ToString(base)
+	if !ok {
+		return "", fmt.Errorf("failed to get the text of a symbol. symbol: %v", base)
+	}
+
+	text := baseText + suffix
+	for n := 2; !st.LookupByString(text).IsNil(); n++ {
+		text = fmt.Sprintf("%s%s%v", baseText, suffix, n)
+	}
+
+	return st.Intern(text, grammar.SymbolKindNonTerminal), nil
+}
+
+func concat(syms ...[]grammar.SymbolID) []grammar.SymbolID {
+	l := 0
+	for _, s := range syms {
+		l += len(s)
+	}
+
+	c := make([]grammar.SymbolID, 0, l)
+	for _, s := range syms {
+		c = append(c, s...)
+	}
+
+	return c
+}
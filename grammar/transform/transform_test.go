@@ -0,0 +1,242 @@
+package transform
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+)
+
+func TestEliminateLeftRecursion(t *testing.T) {
+	tests := map[string]struct {
+		src         string
+		productions []string
+		origins     map[string]string
+		err         bool
+	}{
+		"direct left recursion": {
+			src: `E: E "+" T | T; T: T "*" F | F; F: "(" E ")" | id;`,
+			productions: []string{
+				"E' → E",
+				"E → T E_lr",
+				"E_lr → + T E_lr",
+				"E_lr → ε",
+				"T → F T_lr",
+				"T_lr → * F T_lr",
+				"T_lr → ε",
+				"F → ( E )",
+				"F → id",
+			},
+			origins: map[string]string{
+				"E → T E_lr":      "E → T",
+				"E_lr → + T E_lr": "E → E + T",
+				"E_lr → ε":        "E → E + T",
+			},
+		},
+		"indirect left recursion": {
+			src: `S: A a | b; A: A c | S d | e;`,
+			productions: []string{
+				"S' → S",
+				"S → A a",
+				"S → b",
+				"A → b d A_lr",
+				"A → e A_lr",
+				"A_lr → c A_lr",
+				"A_lr → a d A_lr",
+				"A_lr → ε",
+			},
+			origins: map[string]string{
+				"A → b d A_lr":    "A → S d",
+				"A_lr → a d A_lr": "A → S d",
+			},
+		},
+		"a generated name doesn't conflict with existing symbols": {
+			src: `E: E x | E_lr; E_lr: y;`,
+			productions: []string{
+				"E' → E",
+				"E → E_lr E_lr2",
+				"E_lr2 → x E_lr2",
+				"E_lr2 → ε",
+				"E_lr → y",
+			},
+		},
+		"all alternatives are left-recursive": {
+			src: `E: E x;`,
+			err: true,
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			g := parse(t, tt.src)
+			orig := NewGrammar(g.Productions)
+			transformed, err := EliminateLeftRecursion(g.SymbolTable, orig)
+			if tt.err {
+				if err == nil {
+					t.Fatal("an error was not returned")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testProductions(t, g.SymbolTable, g.Productions, transformed, tt.productions, tt.origins)
+		})
+	}
+}
+
+func TestLeftFactor(t *testing.T) {
+	tests := map[string]struct {
+		src         string
+		productions []string
+		origins     map[string]string
+	}{
+		"alternatives share a common prefix": {
+			src: `S: if E then S | if E then S else S | a; E: b;`,
+			productions: []string{
+				"S' → S",
+				"S → if E then S S_lf",
+				"S → a",
+				"S_lf → ε",
+				"S_lf → else S",
+				"E → b",
+			},
+			origins: map[string]string{
+				"S → if E then S S_lf": "S → if E then S",
+				"S_lf → ε":             "S → if E then S",
+				"S_lf → else S":        "S → if E then S else S",
+			},
+		},
+		"factored alternatives share a common prefix again": {
+			src: `S: a b c | a b d | a e;`,
+			productions: []string{
+				"S' → S",
+				"S → a S_lf",
+				"S_lf → b S_lf2",
+				"S_lf → e",
+				"S_lf2 → c",
+				"S_lf2 → d",
+			},
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			g := parse(t, tt.src)
+			transformed, err := LeftFactor(g.SymbolTable, NewGrammar(g.Productions))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testProductions(t, g.SymbolTable, g.Productions, transformed, tt.productions, tt.origins)
+		})
+	}
+}
+
+func TestTransformChain(t *testing.T) {
+	g := parse(t, `E: E "+" T | E "-" T | T; T: id;`)
+	lr, err := EliminateLeftRecursion(g.SymbolTable, NewGrammar(g.Productions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf, err := LeftFactor(g.SymbolTable, lr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testProductions(t, g.SymbolTable, g.Productions, lf, []string{
+		"E' → E",
+		"E → T E_lr",
+		"E_lr → + T E_lr",
+		"E_lr → - T E_lr",
+		"E_lr → ε",
+		"T → id",
+	}, map[string]string{
+		"E_lr → - T E_lr": "E → E - T",
+	})
+}
+
+func parse(t *testing.T, src string) *ast2grammar.Grammar {
+	t.Helper()
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func testProductions(t *testing.T, st *grammar.SymbolTable, orig grammar.Productions, g *Grammar, expected []string, origins map[string]string) {
+	t.Helper()
+
+	lhss := lhsMap(g.Productions)
+	for p, lhs := range lhsMap(orig) {
+		lhss[p] = lhs
+	}
+
+	prods := []*grammar.Production{}
+	for _, ps := range g.Productions.All() {
+		prods = append(prods, ps...)
+	}
+	sort.Slice(prods, func(i, j int) bool {
+		return prods[i].ID() < prods[j].ID()
+	})
+
+	actual := make([]string, len(prods))
+	for i, p := range prods {
+		actual[i] = prodToString(st, lhss[p], p)
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected productions\nwant:\n%v\ngot:\n%v", strings.Join(expected, "\n"), strings.Join(actual, "\n"))
+	}
+
+	for i, p := range prods {
+		origin := g.Origin(p)
+		if origin == nil {
+			t.Fatalf("a production has no origin. production: %v", actual[i])
+		}
+		eOrigin, ok := origins[actual[i]]
+		if !ok {
+			continue
+		}
+		if aOrigin := prodToString(st, lhss[origin], origin); aOrigin != eOrigin {
+			t.Errorf("unexpected origin of %v\nwant: %v\ngot: %v", actual[i], eOrigin, aOrigin)
+		}
+	}
+}
+
+func lhsMap(prods grammar.Productions) map[*grammar.Production]grammar.SymbolID {
+	m := map[*grammar.Production]grammar.SymbolID{}
+	for lhs, ps := range prods.All() {
+		for _, p := range ps {
+			m[p] = lhs
+		}
+	}
+
+	return m
+}
+
+func prodToString(st *grammar.SymbolTable, lhs grammar.SymbolID, p *grammar.Production) string {
+	lhsText, _ := st.ToString(lhs)
+	rhs, _ := p.RHS()
+	syms := make([]string, len(rhs))
+	for i, sym := range rhs {
+		syms[i], _ = st.ToString(sym)
+	}
+	if len(syms) == 0 {
+		syms = []string{"ε"}
+	}
+
+	return lhsText + " → " + strings.Join(syms, " ")
+}
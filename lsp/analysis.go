@@ -0,0 +1,331 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+)
+
+// diagnosticSource is the source of the diagnostics the server publishes.
+const diagnosticSource = "sousa"
+
+type occurrenceKind int
+
+const (
+	// occurrenceLHS is the LHS of a production, occurrenceRHS is a symbol of an alternative, and occurrenceStart is
+	// an argument of a %start directive.
+	occurrenceLHS = occurrenceKind(iota)
+	occurrenceRHS
+	occurrenceStart
+)
+
+// occurrence is a symbol written in a document. [begin, end) is its byte range including the quotes of a string.
+type occurrence struct {
+	name   string
+	kind   occurrenceKind
+	begin  int
+	end    int
+	quoted bool
+}
+
+// analysis is the result of analyzing a document.
+type analysis struct {
+	// ast is the AST of the document, which is nil when the document has a syntax error.
+	ast         *parser.AST
+	occurrences []*occurrence
+
+	// definitions holds the productions defining each non-terminal symbol in the document and the files it
+	// includes.
+	definitions map[string][]*parser.AST
+
+	// grammar, first, and follow are nil when the grammar is invalid.
+	grammar *ast2grammar.Grammar
+	first   *grammar.FirstSets
+	follow  grammar.FollowSets
+
+	diagnostics []*diagnostic
+}
+
+func (doc *document) analyze() *analysis {
+	if doc.analysis != nil {
+		return doc.analysis
+	}
+
+	a := &analysis{
+		definitions: map[string][]*parser.AST{},
+		diagnostics: []*diagnostic{},
+	}
+	doc.analysis = a
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(doc.text)))
+	if err != nil {
+		a.addError(doc, 0, 0, err.Error())
+		return a
+	}
+	p.SetSourceFilePath(doc.path)
+	ast, err := p.Parse()
+	if err != nil {
+		if synErr, ok := err.(*parser.SyntaxError); ok {
+			offset := doc.offset(synErr.Pos())
+			a.addError(doc, offset, offset, synErr.Message())
+		} else {
+			a.addError(doc, 0, 0, err.Error())
+		}
+		return a
+	}
+	for _, child := range ast.Children {
+		child.File = doc.path
+	}
+	a.ast = ast
+	a.occurrences = doc.occurrences(ast)
+
+	root := ast
+	var include *parser.AST
+	for _, child := range ast.Children {
+		if child.State == parser.StateDirective && child.Tokens[0].Text() == parser.DirectiveInclude {
+			include = child
+			break
+		}
+	}
+	if include != nil {
+		begin := doc.offset(include.Pos)
+		end := begin + len("%"+parser.DirectiveInclude)
+		if doc.path == "" {
+			a.addError(doc, begin, end, fmt.Sprintf("%%%v needs a document saved as a file", parser.DirectiveInclude))
+			return a
+		}
+		root, err = parser.ParseSource(doc.path, strings.NewReader(doc.text))
+		if err != nil {
+			if synErr, ok := err.(*parser.SyntaxError); ok {
+				a.addError(doc, begin, end, fmt.Sprintf("%v (%v:%v:%v)", synErr.Message(), synErr.File(), synErr.Pos().Line, synErr.Pos().Column))
+			} else {
+				a.addError(doc, begin, end, err.Error())
+			}
+			return a
+		}
+	}
+	for _, prodAST := range root.Children {
+		if prodAST.State != parser.StateProduction {
+			continue
+		}
+		lhs := prodAST.Children[0].Tokens[0].Text()
+		a.definitions[lhs] = append(a.definitions[lhs], prodAST)
+	}
+
+	undefined := false
+	for _, occ := range a.occurrences {
+		if occ.kind != occurrenceStart {
+			continue
+		}
+		if _, ok := a.definitions[occ.name]; !ok {
+			a.addError(doc, occ.begin, occ.end, fmt.Sprintf("start symbol %v is not defined", occ.name))
+			undefined = true
+		}
+	}
+	if undefined {
+		return a
+	}
+
+	g, err := ast2grammar.Convert(root)
+	if err != nil {
+		a.addError(doc, 0, 0, err.Error())
+		return a
+	}
+	first, err := grammar.GenerateFirstSets(g.Productions)
+	if err != nil {
+		a.addError(doc, 0, 0, err.Error())
+		return a
+	}
+	follow, err := grammar.GenerateFollowSets(g.Productions, first)
+	if err != nil {
+		a.addError(doc, 0, 0, err.Error())
+		return a
+	}
+	a.grammar = g
+	a.first = first
+	a.follow = follow
+
+	automaton, err := grammar.GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbols...)
+	if err != nil {
+		a.addError(doc, 0, 0, err.Error())
+		return a
+	}
+	pt, err := grammar.GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		a.addError(doc, 0, 0, err.Error())
+		return a
+	}
+	a.diagnostics = append(a.diagnostics, doc.conflicts(ast, g, pt)...)
+
+	sort.SliceStable(a.diagnostics, func(i, j int) bool {
+		pi, pj := a.diagnostics[i].Range.Start, a.diagnostics[j].Range.Start
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Character < pj.Character
+	})
+
+	return a
+}
+
+func (a *analysis) addError(doc *document, begin, end int, msg string) {
+	a.diagnostics = append(a.diagnostics, &diagnostic{
+		Range:    doc.textRange(begin, end),
+		Severity: severityError,
+		Source:   diagnosticSource,
+		Message:  msg,
+	})
+}
+
+// occurrences returns the symbols written in the document in order of appearance.
+func (doc *document) occurrences(ast *parser.AST) []*occurrence {
+	occs := []*occurrence{}
+	add := func(tok parser.Token, kind occurrenceKind) {
+		begin := doc.offset(tok.Pos())
+		occ := &occurrence{
+			name:  tok.Text(),
+			kind:  kind,
+			begin: begin,
+			end:   begin + len(tok.Text()),
+		}
+		if tok.Type() == parser.TokenTypeString {
+			occ.end += len(`""`)
+			occ.quoted = true
+		}
+		occs = append(occs, occ)
+	}
+
+	for _, child := range ast.Children {
+		switch child.State {
+		case parser.StateDirective:
+			if child.Tokens[0].Text() != parser.DirectiveStart {
+				continue
+			}
+			for _, tok := range child.Tokens[1:] {
+				if tok.Type() == parser.TokenTypeID {
+					add(tok, occurrenceStart)
+				}
+			}
+		case parser.StateProduction:
+			add(child.Children[0].Tokens[0], occurrenceLHS)
+			for _, altAST := range child.Children[1].Children {
+				for _, tok := range altAST.Tokens {
+					add(tok, occurrenceRHS)
+				}
+			}
+		}
+	}
+
+	return occs
+}
+
+// occurrenceAt returns the symbol at a byte offset. An offset just after a symbol also points to it so that a cursor
+// at the end of a word works.
+func (a *analysis) occurrenceAt(offset int) (*occurrence, bool) {
+	for _, occ := range a.occurrences {
+		if occ.begin <= offset && offset <= occ.end {
+			return occ, true
+		}
+	}
+	return nil, false
+}
+
+// conflicts returns a warning at each alternative in the document that takes part in a conflict of the parsing table.
+func (doc *document) conflicts(ast *parser.AST, g *ast2grammar.Grammar, pt *grammar.ParsingTable) []*diagnostic {
+	altRanges := map[parser.Position]textRange{}
+	for _, prodAST := range ast.Children {
+		if prodAST.State != parser.StateProduction {
+			continue
+		}
+		for _, altAST := range prodAST.Children[1].Children {
+			begin := doc.offset(altAST.Pos)
+			end := begin
+			if len(altAST.Tokens) > 0 {
+				last := altAST.Tokens[len(altAST.Tokens)-1]
+				end = doc.offset(last.Pos()) + len(last.Text())
+				if last.Type() == parser.TokenTypeString {
+					end += len(`""`)
+				}
+			}
+			altRanges[altAST.Pos] = doc.textRange(begin, end)
+		}
+	}
+
+	diags := []*diagnostic{}
+	reported := map[string]struct{}{}
+	report := func(kind, terminal string, prodFp grammar.ProductionFingerprint) {
+		prod := g.Productions.LookupByFingerprint(prodFp)
+		if prod == nil {
+			return
+		}
+		src, ok := g.Sources[prod.ID()]
+		if !ok || src.File != doc.path {
+			return
+		}
+		r, ok := altRanges[src.Position]
+		if !ok {
+			return
+		}
+		msg := fmt.Sprintf("%v conflict on %v", kind, terminal)
+		key := fmt.Sprintf("%v %v", prod.ID(), msg)
+		if _, ok := reported[key]; ok {
+			return
+		}
+		reported[key] = struct{}{}
+		diags = append(diags, &diagnostic{
+			Range:    r,
+			Severity: severityWarning,
+			Source:   diagnosticSource,
+			Message:  msg,
+		})
+	}
+
+	kernels := []grammar.KernelFingerprint{}
+	for kernel := range pt.Action() {
+		kernels = append(kernels, kernel)
+	}
+	sort.Slice(kernels, func(i, j int) bool {
+		return pt.States()[kernels[i]] < pt.States()[kernels[j]]
+	})
+	for _, kernel := range kernels {
+		as := pt.Action()[kernel]
+		syms := []grammar.SymbolID{}
+		for sym := range as.Conflicts() {
+			syms = append(syms, sym)
+		}
+		sort.Slice(syms, func(i, j int) bool {
+			return syms[i] < syms[j]
+		})
+		for _, sym := range syms {
+			acts := as.Conflicts()[sym]
+			kind := "reduce/reduce"
+			for _, act := range acts {
+				if act.Type() == grammar.ActionTypeShift {
+					kind = "shift/reduce"
+				}
+			}
+			name, _ := g.SymbolTable.ToString(sym)
+			for _, act := range acts {
+				if act.Type() == grammar.ActionTypeReduce {
+					report(kind, strconv.Quote(name), act.Production())
+				}
+			}
+		}
+		if as.ConflictByEOF() {
+			kind := "reduce/reduce"
+			if as.Acceptable() {
+				kind = "accept/reduce"
+			}
+			for _, prodFp := range as.ReducesByEOF() {
+				report(kind, "EOF", prodFp)
+			}
+		}
+	}
+
+	return diags
+}
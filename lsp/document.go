@@ -0,0 +1,146 @@
+package lsp
+
+import (
+	"net/url"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nihei9/sousa/parser"
+)
+
+// document is a text document the client opened, or a file read to answer a request about it.
+type document struct {
+	uri string
+	// path is the file path of the document. It is empty when the URI isn't a file URI.
+	path string
+	text string
+
+	// parserLines holds the byte offset at which each line begins as the parser counts lines, that is, each of '\r'
+	// and '\n' breaks a line. lines holds it as the protocol counts lines, where "\r\n" is a single line break.
+	parserLines []int
+	lines       []int
+
+	analysis *analysis
+}
+
+func newDocument(uri, text string) *document {
+	doc := &document{
+		uri:         uri,
+		path:        uriToPath(uri),
+		text:        text,
+		parserLines: []int{0},
+		lines:       []int{0},
+	}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			doc.parserLines = append(doc.parserLines, i+1)
+			doc.lines = append(doc.lines, i+1)
+		case '\r':
+			doc.parserLines = append(doc.parserLines, i+1)
+			if i+1 >= len(text) || text[i+1] != '\n' {
+				doc.lines = append(doc.lines, i+1)
+			}
+		}
+	}
+
+	return doc
+}
+
+// uriToPath returns the file path of a file URI, or an empty string for other URIs.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
+
+func pathToURI(path string) string {
+	u := &url.URL{
+		Scheme: "file",
+		Path:   path,
+	}
+	return u.String()
+}
+
+// offset returns the byte offset of a position the parser reported.
+func (doc *document) offset(pos parser.Position) int {
+	line := pos.Line - 1
+	if line < 0 {
+		return 0
+	}
+	if line >= len(doc.parserLines) {
+		return len(doc.text)
+	}
+	offset := doc.parserLines[line]
+	for col := 1; col < pos.Column && offset < len(doc.text); col++ {
+		_, size := utf8.DecodeRuneInString(doc.text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// position returns the position of a byte offset in the protocol.
+func (doc *document) position(offset int) position {
+	line := sort.Search(len(doc.lines), func(i int) bool {
+		return doc.lines[i] > offset
+	}) - 1
+	char := 0
+	for _, c := range doc.text[doc.lines[line]:offset] {
+		char += utf16Len(c)
+	}
+	return position{
+		Line:      line,
+		Character: char,
+	}
+}
+
+// offsetOf returns the byte offset of a position in the protocol. A position beyond the end of a line means the end
+// of the line.
+func (doc *document) offsetOf(pos position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(doc.lines) {
+		return len(doc.text)
+	}
+	offset := doc.lines[pos.Line]
+	for char := 0; char < pos.Character && offset < len(doc.text); {
+		c, size := utf8.DecodeRuneInString(doc.text[offset:])
+		if c == '\r' || c == '\n' {
+			break
+		}
+		char += utf16Len(c)
+		offset += size
+	}
+	return offset
+}
+
+func (doc *document) textRange(begin, end int) textRange {
+	return textRange{
+		Start: doc.position(begin),
+		End:   doc.position(end),
+	}
+}
+
+// idEnd returns the byte offset just after the identifier beginning at offset.
+func (doc *document) idEnd(offset int) int {
+	end := offset
+	for end < len(doc.text) {
+		c, size := utf8.DecodeRuneInString(doc.text[end:])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+func utf16Len(c rune) int {
+	if c >= 0x10000 {
+		return 2
+	}
+	return 1
+}
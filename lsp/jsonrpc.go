@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// message is a JSON-RPC 2.0 request, notification, or response. A notification has no ID.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// Error codes of JSON-RPC and LSP.
+const (
+	codeParseError           = -32700
+	codeInvalidRequest       = -32600
+	codeMethodNotFound       = -32601
+	codeInvalidParams        = -32602
+	codeInternalError        = -32603
+	codeServerNotInitialized = -32002
+	codeRequestFailed        = -32803
+)
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("%v (code: %v)", e.Message, e.Code)
+}
+
+func newResponseError(code int, format string, a ...interface{}) *responseError {
+	return &responseError{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+// readMessage reads a message framed by a Content-Length header. It returns io.EOF when r ends before a message.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for i := 0; ; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && i == 0 && line == "" {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to read a header: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		colon := strings.Index(line, ":")
+		if colon < 0 {
+			return nil, fmt.Errorf("invalid header: %v", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(line[:colon]), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(line[colon+1:]))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("invalid Content-Length: %v", line[colon+1:])
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("Content-Length is missing")
+	}
+
+	b := make([]byte, length)
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read a message: %v", err)
+	}
+
+	return b, nil
+}
+
+// writeMessage writes v as JSON framed by a Content-Length header.
+func writeMessage(w io.Writer, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %v\r\n\r\n%s", len(b), b)
+	return err
+}
@@ -0,0 +1,478 @@
+// Package lsp provides a language server for grammar files speaking the Language Server Protocol.
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+	"github.com/nihei9/sousa/printer"
+)
+
+// Server is a language server for grammar files. It publishes diagnostics of syntax errors, undefined start symbols,
+// and conflicts of the parsing table, and answers requests of go-to-definition, find-references, hover, rename, and
+// formatting.
+//
+// Navigation and renaming work on the symbols written in a document. Diagnostics and hover take the files that the
+// document includes into account.
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document
+
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer returns a server reading messages from in and writing messages to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends the exit notification. It returns an error when the client exits
+// without requesting shutdown or in ends unexpectedly.
+func (s *Server) Serve() error {
+	for {
+		b, err := readMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				if s.shutdown {
+					return nil
+				}
+				return fmt.Errorf("the input ended without the exit notification")
+			}
+			return err
+		}
+
+		msg := &message{}
+		err = json.Unmarshal(b, msg)
+		if err != nil {
+			err := writeMessage(s.out, &errorResponse{
+				JSONRPC: "2.0",
+				Error:   newResponseError(codeParseError, "invalid message: %v", err),
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("the client exited without requesting shutdown")
+			}
+			return nil
+		}
+
+		if msg.ID == nil {
+			err := s.handleNotification(msg)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, resErr := s.handleRequest(msg)
+		if resErr != nil {
+			err = writeMessage(s.out, &errorResponse{
+				JSONRPC: "2.0",
+				ID:      msg.ID,
+				Error:   resErr,
+			})
+		} else {
+			err = writeMessage(s.out, &response{
+				JSONRPC: "2.0",
+				ID:      msg.ID,
+				Result:  result,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handleRequest(msg *message) (interface{}, *responseError) {
+	if msg.Method == "initialize" {
+		s.initialized = true
+		res := &initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:           1,
+				DefinitionProvider:         true,
+				ReferencesProvider:         true,
+				HoverProvider:              true,
+				RenameProvider:             true,
+				DocumentFormattingProvider: true,
+			},
+		}
+		res.ServerInfo.Name = "sousa"
+		return res, nil
+	}
+	if !s.initialized {
+		return nil, newResponseError(codeServerNotInitialized, "the server is not initialized")
+	}
+	if s.shutdown {
+		return nil, newResponseError(codeInvalidRequest, "the server is shut down")
+	}
+
+	switch msg.Method {
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/definition":
+		params := &textDocumentPositionParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, err
+		}
+		return s.definition(params)
+	case "textDocument/references":
+		params := &referenceParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, err
+		}
+		return s.references(params)
+	case "textDocument/hover":
+		params := &textDocumentPositionParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, err
+		}
+		return s.hover(params)
+	case "textDocument/rename":
+		params := &renameParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, err
+		}
+		return s.rename(params)
+	case "textDocument/formatting":
+		params := &documentFormattingParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil, err
+		}
+		return s.formatting(params)
+	}
+
+	return nil, newResponseError(codeMethodNotFound, "unsupported method: %v", msg.Method)
+}
+
+func (s *Server) handleNotification(msg *message) error {
+	if !s.initialized {
+		return nil
+	}
+
+	switch msg.Method {
+	case "textDocument/didOpen":
+		params := &didOpenTextDocumentParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil
+		}
+		doc := newDocument(params.TextDocument.URI, params.TextDocument.Text)
+		s.docs[doc.uri] = doc
+		return s.publishDiagnostics(doc)
+	case "textDocument/didChange":
+		params := &didChangeTextDocumentParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil
+		}
+		text := doc.text
+		for _, change := range params.ContentChanges {
+			if change.Range == nil {
+				text = change.Text
+			} else {
+				d := newDocument(doc.uri, text)
+				text = text[:d.offsetOf(change.Range.Start)] + change.Text + text[d.offsetOf(change.Range.End):]
+			}
+		}
+		doc = newDocument(doc.uri, text)
+		s.docs[doc.uri] = doc
+		return s.publishDiagnostics(doc)
+	case "textDocument/didClose":
+		params := &didCloseTextDocumentParams{}
+		if err := unmarshalParams(msg, params); err != nil {
+			return nil
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return writeMessage(s.out, &notification{
+			JSONRPC: "2.0",
+			Method:  "textDocument/publishDiagnostics",
+			Params: &publishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []*diagnostic{},
+			},
+		})
+	}
+
+	return nil
+}
+
+func unmarshalParams(msg *message, params interface{}) *responseError {
+	err := json.Unmarshal(msg.Params, params)
+	if err != nil {
+		return newResponseError(codeInvalidParams, "invalid parameters of %v: %v", msg.Method, err)
+	}
+	return nil
+}
+
+func (s *Server) publishDiagnostics(doc *document) error {
+	return writeMessage(s.out, &notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params: &publishDiagnosticsParams{
+			URI:         doc.uri,
+			Diagnostics: doc.analyze().diagnostics,
+		},
+	})
+}
+
+// symbolAt returns the document and the symbol at a position. The symbol is nil when the position points to none.
+func (s *Server) symbolAt(params *textDocumentPositionParams) (*document, *occurrence, *responseError) {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil, newResponseError(codeInvalidParams, "unknown document: %v", params.TextDocument.URI)
+	}
+	occ, ok := doc.analyze().occurrenceAt(doc.offsetOf(params.Position))
+	if !ok {
+		return doc, nil, nil
+	}
+	return doc, occ, nil
+}
+
+func (s *Server) definition(params *textDocumentPositionParams) (interface{}, *responseError) {
+	doc, occ, err := s.symbolAt(params)
+	if err != nil || occ == nil || occ.quoted {
+		return nil, err
+	}
+
+	locs := []*location{}
+	for _, prodAST := range doc.analyze().definitions[occ.name] {
+		target := doc
+		if prodAST.File != doc.path {
+			target = s.file(prodAST.File)
+			if target == nil {
+				continue
+			}
+		}
+		begin := target.offset(prodAST.Children[0].Tokens[0].Pos())
+		locs = append(locs, &location{
+			URI:   target.uri,
+			Range: target.textRange(begin, target.idEnd(begin)),
+		})
+	}
+	if len(locs) == 0 {
+		return nil, nil
+	}
+	return locs, nil
+}
+
+// file returns the open document of a file path, or reads the file. It returns nil when the file can't be read.
+func (s *Server) file(path string) *document {
+	for _, doc := range s.docs {
+		if doc.path == path {
+			return doc
+		}
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return newDocument(pathToURI(path), string(b))
+}
+
+func (s *Server) references(params *referenceParams) (interface{}, *responseError) {
+	doc, occ, err := s.symbolAt(&params.textDocumentPositionParams)
+	if err != nil || occ == nil || occ.quoted {
+		return nil, err
+	}
+
+	locs := []*location{}
+	for _, o := range doc.analyze().occurrences {
+		if o.name != occ.name || o.quoted {
+			continue
+		}
+		if o.kind == occurrenceLHS && !params.Context.IncludeDeclaration {
+			continue
+		}
+		locs = append(locs, &location{
+			URI:   doc.uri,
+			Range: doc.textRange(o.begin, o.end),
+		})
+	}
+	return locs, nil
+}
+
+func (s *Server) hover(params *textDocumentPositionParams) (interface{}, *responseError) {
+	doc, occ, err := s.symbolAt(params)
+	if err != nil || occ == nil {
+		return nil, err
+	}
+	a := doc.analyze()
+
+	var b strings.Builder
+	r := doc.textRange(occ.begin, occ.end)
+	if _, ok := a.definitions[occ.name]; !ok || occ.quoted {
+		fmt.Fprintf(&b, "`%v` terminal symbol", occ.name)
+		return &hover{
+			Contents: markupContent{
+				Kind:  "markdown",
+				Value: b.String(),
+			},
+			Range: &r,
+		}, nil
+	}
+
+	fmt.Fprintf(&b, "`%v` non-terminal symbol", occ.name)
+	if a.grammar != nil {
+		sym := a.grammar.SymbolTable.LookupByString(occ.name)
+		first := a.first.FirstOfSymbol(sym)
+		follow := a.follow.Get(sym)
+		if first != nil && follow != nil {
+			names := a.symbolNames(first.Symbols())
+			if first.Empty() {
+				names = append(names, "ε")
+			}
+			fmt.Fprintf(&b, "\n\nFIRST: %v", strings.Join(names, " "))
+			names = a.symbolNames(follow.Symbols())
+			if follow.EOF() {
+				names = append(names, "EOF")
+			}
+			fmt.Fprintf(&b, "\n\nFOLLOW: %v", strings.Join(names, " "))
+		}
+	}
+	return &hover{
+		Contents: markupContent{
+			Kind:  "markdown",
+			Value: b.String(),
+		},
+		Range: &r,
+	}, nil
+}
+
+// symbolNames returns the names of syms as code spans in the order in which they were interned.
+func (a *analysis) symbolNames(syms grammar.SymbolSet) []string {
+	ids := syms.Slice()
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		name, _ := a.grammar.SymbolTable.ToString(id)
+		names[i] = "`" + name + "`"
+	}
+	return names
+}
+
+func (s *Server) rename(params *renameParams) (interface{}, *responseError) {
+	doc, occ, err := s.symbolAt(&params.textDocumentPositionParams)
+	if err != nil {
+		return nil, err
+	}
+	a := doc.analyze()
+	if a.ast == nil {
+		return nil, newResponseError(codeRequestFailed, "cannot rename symbols of a document having syntax errors")
+	}
+	if occ == nil || occ.quoted {
+		return nil, newResponseError(codeRequestFailed, "no non-terminal symbol to rename")
+	}
+	defined := false
+	for _, o := range a.occurrences {
+		if o.kind == occurrenceLHS && o.name == occ.name {
+			defined = true
+			break
+		}
+	}
+	if !defined {
+		return nil, newResponseError(codeRequestFailed, "%v is not a non-terminal symbol defined in this document", occ.name)
+	}
+	if !isIdentifier(params.NewName) || params.NewName == grammar.ErrorSymbolName {
+		return nil, newResponseError(codeInvalidParams, "%v cannot be the name of a non-terminal symbol", params.NewName)
+	}
+	used := false
+	if _, ok := a.definitions[params.NewName]; ok {
+		used = true
+	}
+	for _, o := range a.occurrences {
+		if o.name == params.NewName {
+			used = true
+		}
+	}
+	if a.grammar != nil && !a.grammar.SymbolTable.LookupByString(params.NewName).IsNil() {
+		used = true
+	}
+	if used {
+		return nil, newResponseError(codeRequestFailed, "%v is already used in the grammar", params.NewName)
+	}
+
+	edits := []*textEdit{}
+	for _, o := range a.occurrences {
+		if o.name != occ.name || o.quoted {
+			continue
+		}
+		edits = append(edits, &textEdit{
+			Range:   doc.textRange(o.begin, o.end),
+			NewText: params.NewName,
+		})
+	}
+	return &workspaceEdit{
+		Changes: map[string][]*textEdit{
+			doc.uri: edits,
+		},
+	}, nil
+}
+
+// isIdentifier reports whether name is written as an identifier in a grammar.
+func isIdentifier(name string) bool {
+	for i, c := range name {
+		if i == 0 && !unicode.IsLetter(c) {
+			return false
+		}
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func (s *Server) formatting(params *documentFormattingParams) (interface{}, *responseError) {
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, newResponseError(codeInvalidParams, "unknown document: %v", params.TextDocument.URI)
+	}
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(doc.text)))
+	if err != nil {
+		return nil, newResponseError(codeInternalError, "%v", err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		return nil, newResponseError(codeRequestFailed, "cannot format a document having syntax errors")
+	}
+	b := new(bytes.Buffer)
+	err = printer.Fprint(b, ast)
+	if err != nil {
+		return nil, newResponseError(codeInternalError, "%v", err)
+	}
+
+	if b.String() == doc.text {
+		return []*textEdit{}, nil
+	}
+	return []*textEdit{
+		{
+			Range:   doc.textRange(0, len(doc.text)),
+			NewText: b.String(),
+		},
+	}, nil
+}
@@ -0,0 +1,484 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testClient is an in-process LSP client connected to a server through pipes.
+type testClient struct {
+	t      *testing.T
+	w      io.WriteCloser
+	r      *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &testClient{
+		t:    t,
+		w:    inW,
+		r:    bufio.NewReader(outR),
+		done: make(chan error, 1),
+	}
+	go func() {
+		err := NewServer(inR, outW).Serve()
+		outW.Close()
+		c.done <- err
+	}()
+
+	return c
+}
+
+func (c *testClient) send(v interface{}) {
+	c.t.Helper()
+
+	err := writeMessage(c.w, v)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() *message {
+	c.t.Helper()
+
+	b, err := readMessage(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg := &message{}
+	err = json.Unmarshal(b, msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// request sends a request and decodes the result of its response into result. It returns the error of the response.
+func (c *testClient) request(method string, params interface{}, result interface{}) *responseError {
+	c.t.Helper()
+
+	c.nextID++
+	c.send(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      c.nextID,
+		"method":  method,
+		"params":  params,
+	})
+	msg := c.read()
+	if msg.ID == nil || string(*msg.ID) != fmt.Sprint(c.nextID) {
+		c.t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.Error != nil {
+		return msg.Error
+	}
+	if result != nil {
+		err := json.Unmarshal(msg.Result, result)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	c.t.Helper()
+
+	c.send(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	})
+}
+
+// diagnostics reads a publishDiagnostics notification and returns its diagnostics formatted by diagnosticString.
+func (c *testClient) diagnostics(uri string) []string {
+	c.t.Helper()
+
+	msg := c.read()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("unexpected message: %+v", msg)
+	}
+	params := &publishDiagnosticsParams{}
+	err := json.Unmarshal(msg.Params, params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if params.URI != uri {
+		c.t.Fatalf("unexpected URI\nwant: %v\ngot: %v", uri, params.URI)
+	}
+	diags := make([]string, len(params.Diagnostics))
+	for i, d := range params.Diagnostics {
+		diags[i] = diagnosticString(d)
+	}
+	return diags
+}
+
+func (c *testClient) initialize() {
+	c.t.Helper()
+
+	res := &initializeResult{}
+	err := c.request("initialize", map[string]interface{}{}, res)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if !res.Capabilities.HoverProvider || !res.Capabilities.RenameProvider {
+		c.t.Fatalf("unexpected capabilities: %+v", res.Capabilities)
+	}
+	c.notify("initialized", map[string]interface{}{})
+}
+
+func (c *testClient) open(uri, text string) []string {
+	c.t.Helper()
+
+	c.notify("textDocument/didOpen", &didOpenTextDocumentParams{
+		TextDocument: textDocumentItem{
+			URI:        uri,
+			LanguageID: "sousa",
+			Version:    1,
+			Text:       text,
+		},
+	})
+	return c.diagnostics(uri)
+}
+
+// exit shuts down the server and waits for it to stop.
+func (c *testClient) exit() {
+	c.t.Helper()
+
+	err := c.request("shutdown", nil, nil)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func diagnosticString(d *diagnostic) string {
+	return fmt.Sprintf("%v %v: %v", rangeString(d.Range), d.Severity, d.Message)
+}
+
+func rangeString(r textRange) string {
+	return fmt.Sprintf("%v:%v-%v:%v", r.Start.Line, r.Start.Character, r.End.Line, r.End.Character)
+}
+
+func at(uri string, line, char int) *textDocumentPositionParams {
+	return &textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{
+			URI: uri,
+		},
+		Position: position{
+			Line:      line,
+			Character: char,
+		},
+	}
+}
+
+const testURI = "file:///grammar.sousa"
+
+func TestServer_Diagnostics(t *testing.T) {
+	tests := []struct {
+		caption string
+		src     string
+		diags   []string
+	}{
+		{
+			caption: "valid grammar",
+			src:     "expr: expr \"+\" term | term;\nterm: id;\n",
+			diags:   []string{},
+		},
+		{
+			caption: "syntax error",
+			src:     "expr: id\n",
+			diags:   []string{`1:0-1:0 1: unexpected token; expected: [;], actual: EOF`},
+		},
+		{
+			caption: "undefined start symbol",
+			src:     "%start stmt expr;\nexpr: id;\n",
+			diags:   []string{`0:7-0:11 1: start symbol stmt is not defined`},
+		},
+		{
+			caption: "conflicts",
+			src:     "%start stmt;\nstmt: expr \";\";\nexpr: expr \"+\" expr\n    | id;\n",
+			diags:   []string{`2:6-2:19 2: shift/reduce conflict on "+"`},
+		},
+		{
+			caption: "reduce/reduce conflict",
+			src:     "s: a | b;\na: id;\nb: id;\n",
+			diags: []string{
+				`1:3-1:5 2: reduce/reduce conflict on EOF`,
+				`2:3-2:5 2: reduce/reduce conflict on EOF`,
+			},
+		},
+		{
+			caption: "positions count UTF-16 code units",
+			src:     "// 😀\n%start 𝑥式;\n式: id;\n",
+			diags:   []string{`1:7-1:10 1: start symbol 𝑥式 is not defined`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			c := newTestClient(t)
+			c.initialize()
+			diags := c.open(testURI, tt.src)
+			if strings.Join(diags, "\n") != strings.Join(tt.diags, "\n") {
+				t.Fatalf("unexpected diagnostics\nwant:\n%v\ngot:\n%v", strings.Join(tt.diags, "\n"), strings.Join(diags, "\n"))
+			}
+
+			c.notify("textDocument/didChange", &didChangeTextDocumentParams{
+				TextDocument: textDocumentIdentifier{
+					URI: testURI,
+				},
+				ContentChanges: []*textDocumentContentChangeEvent{
+					{
+						Text: "expr: id;\n",
+					},
+				},
+			})
+			if diags := c.diagnostics(testURI); len(diags) != 0 {
+				t.Fatalf("the diagnostics must be cleared: %v", diags)
+			}
+
+			c.exit()
+		})
+	}
+}
+
+func TestServer_Navigation(t *testing.T) {
+	src := `%start stmt;
+
+stmt
+    : expr ";"
+    ;
+
+expr
+    : expr "+" term
+    | term
+    ;
+
+term
+    : id
+    ;
+`
+	c := newTestClient(t)
+	c.initialize()
+	if diags := c.open(testURI, src); len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	t.Run("definition", func(t *testing.T) {
+		locs := []*location{}
+		err := c.request("textDocument/definition", at(testURI, 7, 15), &locs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(locs) != 1 || locs[0].URI != testURI || rangeString(locs[0].Range) != "11:0-11:4" {
+			t.Fatalf("unexpected definition: %+v", locs)
+		}
+
+		// A terminal symbol has no definition.
+		var res interface{}
+		err = c.request("textDocument/definition", at(testURI, 12, 6), &res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != nil {
+			t.Fatalf("a terminal symbol must have no definition: %v", res)
+		}
+	})
+
+	t.Run("references", func(t *testing.T) {
+		for _, includeDecl := range []bool{true, false} {
+			params := &referenceParams{
+				textDocumentPositionParams: *at(testURI, 3, 7),
+			}
+			params.Context.IncludeDeclaration = includeDecl
+			locs := []*location{}
+			err := c.request("textDocument/references", params, &locs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ranges := []string{}
+			for _, loc := range locs {
+				ranges = append(ranges, rangeString(loc.Range))
+			}
+			expected := "3:6-3:10 7:6-7:10"
+			if includeDecl {
+				expected = "3:6-3:10 6:0-6:4 7:6-7:10"
+			}
+			if strings.Join(ranges, " ") != expected {
+				t.Fatalf("unexpected references\nwant: %v\ngot: %v", expected, strings.Join(ranges, " "))
+			}
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		tests := []struct {
+			pos     *textDocumentPositionParams
+			content string
+		}{
+			{
+				pos:     at(testURI, 6, 2),
+				content: "`expr` non-terminal symbol\n\nFIRST: `id`\n\nFOLLOW: `;` `+`",
+			},
+			{
+				pos:     at(testURI, 2, 0),
+				content: "`stmt` non-terminal symbol\n\nFIRST: `id`\n\nFOLLOW: EOF",
+			},
+			{
+				pos:     at(testURI, 7, 12),
+				content: "`+` terminal symbol",
+			},
+		}
+		for _, tt := range tests {
+			h := &hover{}
+			err := c.request("textDocument/hover", tt.pos, h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Contents.Value != tt.content {
+				t.Fatalf("unexpected hover\nwant:\n%v\ngot:\n%v", tt.content, h.Contents.Value)
+			}
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		edit := &workspaceEdit{}
+		err := c.request("textDocument/rename", &renameParams{
+			textDocumentPositionParams: *at(testURI, 0, 8),
+			NewName:                    "statement",
+		}, edit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ranges := []string{}
+		for _, e := range edit.Changes[testURI] {
+			if e.NewText != "statement" {
+				t.Fatalf("unexpected new text: %v", e.NewText)
+			}
+			ranges = append(ranges, rangeString(e.Range))
+		}
+		if strings.Join(ranges, " ") != "0:7-0:11 2:0-2:4" {
+			t.Fatalf("unexpected edits: %v", ranges)
+		}
+
+		for _, newName := range []string{"term", "id", "1st", "error", ""} {
+			err := c.request("textDocument/rename", &renameParams{
+				textDocumentPositionParams: *at(testURI, 0, 8),
+				NewName:                    newName,
+			}, nil)
+			if err == nil {
+				t.Fatalf("renaming to %q must fail", newName)
+			}
+		}
+
+		// A terminal symbol cannot be renamed.
+		err = c.request("textDocument/rename", &renameParams{
+			textDocumentPositionParams: *at(testURI, 12, 6),
+			NewName:                    "ident",
+		}, nil)
+		if err == nil {
+			t.Fatalf("renaming a terminal symbol must fail")
+		}
+	})
+
+	t.Run("formatting", func(t *testing.T) {
+		edits := []*textEdit{}
+		err := c.request("textDocument/formatting", &documentFormattingParams{
+			TextDocument: textDocumentIdentifier{
+				URI: testURI,
+			},
+		}, &edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edits) != 0 {
+			t.Fatalf("a formatted document must not change: %+v", edits)
+		}
+
+		uri := "file:///unformatted.sousa"
+		c.open(uri, "expr:id;")
+		err = c.request("textDocument/formatting", &documentFormattingParams{
+			TextDocument: textDocumentIdentifier{
+				URI: uri,
+			},
+		}, &edits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edits) != 1 || rangeString(edits[0].Range) != "0:0-0:8" || edits[0].NewText != "expr\n    : id\n    ;\n" {
+			t.Fatalf("unexpected edits: %+v", edits)
+		}
+	})
+
+	c.exit()
+}
+
+func TestServer_Include(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sousa-lsp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "term.sousa"), []byte("term\n    : id\n    ;\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uri := pathToURI(filepath.Join(dir, "main.sousa"))
+	c := newTestClient(t)
+	c.initialize()
+	if diags := c.open(uri, "%include \"term.sousa\";\nexpr: expr \"+\" term | term;\n"); len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	locs := []*location{}
+	rerr := c.request("textDocument/definition", at(uri, 1, 16), &locs)
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	if len(locs) != 1 || locs[0].URI != pathToURI(filepath.Join(dir, "term.sousa")) || rangeString(locs[0].Range) != "0:0-0:4" {
+		t.Fatalf("unexpected definition: %+v", locs)
+	}
+
+	diags := c.open(pathToURI(filepath.Join(dir, "broken.sousa")), "%include \"missing.sousa\";\nexpr: id;\n")
+	if len(diags) != 1 || !strings.HasPrefix(diags[0], "0:0-0:8 1: failed to include a file") {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	c.exit()
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	c := newTestClient(t)
+	err := c.request("textDocument/hover", at(testURI, 0, 0), nil)
+	if err == nil || err.Code != codeServerNotInitialized {
+		t.Fatalf("a request before initialize must fail: %v", err)
+	}
+	c.initialize()
+	err = c.request("textDocument/unknown", map[string]interface{}{}, nil)
+	if err == nil || err.Code != codeMethodNotFound {
+		t.Fatalf("an unknown method must fail: %v", err)
+	}
+	c.exit()
+
+	c = newTestClient(t)
+	c.initialize()
+	c.notify("exit", nil)
+	if err := <-c.done; err == nil {
+		t.Fatalf("exiting without shutdown must cause an error")
+	}
+}
@@ -0,0 +1,244 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ParseFile parses the grammar file at path and resolves its %include directives.
+//
+// %include "expr.sousa";
+// %include "expr.sousa" expr;
+//
+// The path of an included file is relative to the including file. Productions of the included file replace the
+// directive. When a namespace is given, the non-terminals defined in the included file are renamed like expr.Name.
+// A production defined in the including file overrides the productions of the included files that have the same LHS.
+// A production that reaches a file more than once under the same name, such as a production of a fragment that two
+// included files share, is added only once.
+//
+// The root of the returned AST holds productions and directives other than %include, and each of them has the path
+// of the file it was read from in File.
+func ParseFile(path string) (*AST, error) {
+	r := &includeResolver{
+		stack: []string{},
+	}
+	return r.resolve(path, nil, "")
+}
+
+// ParseFileWithIncludes is like ParseFile but also returns the paths of the grammar files it read, path first. The
+// paths are returned even when parsing fails so that a caller can watch them for changes; they include the path of
+// an included file that couldn't be read.
+func ParseFileWithIncludes(path string) (*AST, []string, error) {
+	r := &includeResolver{
+		stack: []string{},
+		files: []string{},
+	}
+	root, err := r.resolve(path, nil, "")
+	return root, r.files, err
+}
+
+// ParseSource is like ParseFile but reads the grammar file at path from src. The files it includes are read from the
+// file system. It lets an editor analyze a file that has unsaved changes.
+func ParseSource(path string, src io.Reader) (*AST, error) {
+	r := &includeResolver{
+		stack: []string{},
+		src:   src,
+	}
+	return r.resolve(path, nil, "")
+}
+
+type includeResolver struct {
+	// stack holds the absolute paths of the files being resolved.
+	stack []string
+
+	// src is the source of the root file. When it is nil, the root file is read from the file system.
+	src io.Reader
+
+	// files holds the paths of the files read so far without duplicates when it isn't nil.
+	files []string
+}
+
+func (r *includeResolver) resolve(path string, directive Token, includingFile string) (*AST, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range r.stack {
+		if p != absPath {
+			continue
+		}
+
+		cycle := []string{}
+		for _, p := range r.stack[i:] {
+			cycle = append(cycle, filepath.Base(p))
+		}
+		cycle = append(cycle, filepath.Base(absPath))
+		return nil, &SyntaxError{
+			file:     includingFile,
+			position: directive.Pos(),
+			message:  fmt.Sprintf("include cycle: %v", strings.Join(cycle, " -> ")),
+		}
+	}
+	r.stack = append(r.stack, absPath)
+	if r.files != nil {
+		r.addFile(path)
+	}
+	defer func() {
+		r.stack = r.stack[:len(r.stack)-1]
+	}()
+
+	var src io.Reader
+	if directive == nil && r.src != nil {
+		src = r.src
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			if directive == nil {
+				return nil, err
+			}
+			return nil, &SyntaxError{
+				file:     includingFile,
+				position: directive.Pos(),
+				message:  fmt.Sprintf("failed to include a file: %v", err),
+			}
+		}
+		defer file.Close()
+		src = file
+	}
+
+	p, err := NewParser(NewLexer(src))
+	if err != nil {
+		return nil, err
+	}
+	p.SetSourceFilePath(path)
+	root, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	own := map[string]struct{}{}
+	for _, child := range root.Children {
+		child.File = path
+		if child.State == StateProduction {
+			own[lhsOf(child)] = struct{}{}
+		}
+	}
+
+	children := []*AST{}
+	added := map[string]struct{}{}
+	for _, child := range root.Children {
+		if child.State != StateDirective || child.Tokens[0].Text() != DirectiveInclude {
+			children = append(children, child)
+			continue
+		}
+
+		incPath, incNS, err := includeArgs(child, path)
+		if err != nil {
+			return nil, err
+		}
+		incFile := filepath.Join(filepath.Dir(path), incPath)
+		included, err := r.resolve(incFile, child.Tokens[0], path)
+		if err != nil {
+			return nil, err
+		}
+
+		prods := []*AST{}
+		for _, c := range included.Children {
+			// Directives in included files only affect the files themselves.
+			if c.State == StateProduction {
+				prods = append(prods, c)
+			}
+		}
+		if incNS != "" {
+			qualify(prods, incNS)
+		}
+		for _, prod := range prods {
+			if _, overridden := own[lhsOf(prod)]; overridden {
+				continue
+			}
+			// The productions are compared after being qualified so that the references to a shared fragment are
+			// qualified in every file including it.
+			key, err := productionKey(prod)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := added[key]; ok {
+				continue
+			}
+			added[key] = struct{}{}
+			children = append(children, prod)
+		}
+	}
+	root.Children = children
+	root.File = path
+
+	return root, nil
+}
+
+// productionKey returns a key identifying a production by its LHS and the place where it is defined.
+func productionKey(prod *AST) (string, error) {
+	absFile, err := filepath.Abs(prod.File)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v %v:%v:%v", lhsOf(prod), absFile, prod.Pos.Line, prod.Pos.Column), nil
+}
+
+func (r *includeResolver) addFile(path string) {
+	for _, f := range r.files {
+		if f == path {
+			return
+		}
+	}
+	r.files = append(r.files, path)
+}
+
+func includeArgs(directive *AST, file string) (string, string, error) {
+	args := directive.Tokens[1:]
+	if len(args) < 1 || len(args) > 2 || args[0].Type() != TokenTypeString || (len(args) == 2 && args[1].Type() != TokenTypeID) {
+		return "", "", &SyntaxError{
+			file:     file,
+			position: directive.Pos,
+			message:  fmt.Sprintf("%%%s takes a file path and an optional namespace", DirectiveInclude),
+		}
+	}
+
+	ns := ""
+	if len(args) == 2 {
+		ns = args[1].Text()
+	}
+
+	return args[0].Text(), ns, nil
+}
+
+// qualify renames the non-terminals defined by prods to the names qualified by ns.
+func qualify(prods []*AST, ns string) {
+	defined := map[string]struct{}{}
+	for _, prod := range prods {
+		defined[lhsOf(prod)] = struct{}{}
+	}
+
+	rename := func(tok Token) Token {
+		if _, ok := defined[tok.Text()]; !ok {
+			return tok
+		}
+		return newIDToken(ns+"."+tok.Text(), tok.Pos())
+	}
+
+	for _, prod := range prods {
+		lhsAST := prod.Children[0]
+		lhsAST.Tokens[0] = rename(lhsAST.Tokens[0])
+		for _, altAST := range prod.Children[1].Children {
+			for i, tok := range altAST.Tokens {
+				altAST.Tokens[i] = rename(tok)
+			}
+		}
+	}
+}
+
+func lhsOf(prod *AST) string {
+	return prod.Children[0].Tokens[0].Text()
+}
@@ -0,0 +1,268 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	dir := filepath.Join("testdata", "include")
+	frag := filepath.Join(dir, "fragments")
+
+	ast, err := ParseFile(filepath.Join(dir, "main.sousa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		prod string
+		file string
+	}{
+		{prod: "T: F", file: filepath.Join(frag, "term.sousa")},
+		{prod: "E: E + T | T", file: filepath.Join(frag, "expr.sousa")},
+		{prod: "expr.T: expr.F", file: filepath.Join(frag, "term.sousa")},
+		{prod: "expr.E: expr.E + expr.T | expr.T", file: filepath.Join(frag, "expr.sousa")},
+		{prod: "stmt: expr.E ; | E", file: filepath.Join(dir, "main.sousa")},
+		{prod: "F: num", file: filepath.Join(dir, "main.sousa")},
+		{prod: "expr.F: id", file: filepath.Join(dir, "main.sousa")},
+	}
+	if len(ast.Children) != len(expected) {
+		t.Fatalf("unexpected number of productions\nwant: %v\ngot: %v", len(expected), len(ast.Children))
+	}
+	for i, e := range expected {
+		prodAST := ast.Children[i]
+		if prodAST.State != StateProduction {
+			t.Fatalf("unexpected node\nwant: %v\ngot: %v", StateProduction, prodAST.State)
+		}
+
+		alts := []string{}
+		for _, altAST := range prodAST.Children[1].Children {
+			syms := []string{}
+			for _, tok := range altAST.Tokens {
+				syms = append(syms, tok.Text())
+			}
+			alts = append(alts, strings.Join(syms, " "))
+		}
+		prod := prodAST.Children[0].Tokens[0].Text() + ": " + strings.Join(alts, " | ")
+		if prod != e.prod {
+			t.Errorf("unexpected production\nwant: %v\ngot: %v", e.prod, prod)
+		}
+		if prodAST.File != e.file {
+			t.Errorf("unexpected file\nwant: %v\ngot: %v", e.file, prodAST.File)
+		}
+	}
+}
+
+func TestParseFile_Diamond(t *testing.T) {
+	dir := filepath.Join("testdata", "include")
+	frag := filepath.Join(dir, "fragments")
+
+	type production struct {
+		lhs  string
+		rhs  []string
+		file string
+	}
+	tests := map[string]struct {
+		path     string
+		expected []production
+	}{
+		// term.sousa is included once through left.sousa and right.sousa and once more under the namespace r.
+		"without a namespace": {
+			path: filepath.Join(dir, "diamond.sousa"),
+			expected: []production{
+				{lhs: "T", rhs: []string{"F"}, file: filepath.Join(frag, "term.sousa")},
+				{lhs: "F", rhs: []string{"(", "E", ")"}, file: filepath.Join(frag, "term.sousa")},
+				{lhs: "L", rhs: []string{"T", "+"}, file: filepath.Join(frag, "left.sousa")},
+				{lhs: "R", rhs: []string{"T", "*"}, file: filepath.Join(frag, "right.sousa")},
+				{lhs: "r.T", rhs: []string{"r.F"}, file: filepath.Join(frag, "term.sousa")},
+				{lhs: "r.F", rhs: []string{"(", "E", ")"}, file: filepath.Join(frag, "term.sousa")},
+				{lhs: "r.R", rhs: []string{"r.T", "*"}, file: filepath.Join(frag, "right.sousa")},
+				{lhs: "S", rhs: []string{"L", "R", "r.R"}, file: filepath.Join(dir, "diamond.sousa")},
+			},
+		},
+		// The references to term.sousa in right.sousa are qualified even though term.sousa is added through left.sousa.
+		"with a namespace": {
+			path: filepath.Join(dir, "diamond_ns.sousa"),
+			expected: []production{
+				{lhs: "x.T", rhs: []string{"x.F"}, file: filepath.Join(frag, "term.sousa")},
+				{lhs: "x.F", rhs: []string{"(", "E", ")"}, file: filepath.Join(frag, "term.sousa")},
+				{lhs: "x.L", rhs: []string{"x.T", "+"}, file: filepath.Join(frag, "left.sousa")},
+				{lhs: "x.R", rhs: []string{"x.T", "*"}, file: filepath.Join(frag, "right.sousa")},
+				{lhs: "S", rhs: []string{"x.L", "x.R"}, file: filepath.Join(dir, "diamond_ns.sousa")},
+			},
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			ast, err := ParseFile(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ast.Children) != len(tt.expected) {
+				t.Fatalf("unexpected number of productions\nwant: %v\ngot: %v", len(tt.expected), len(ast.Children))
+			}
+			for i, e := range tt.expected {
+				prodAST := ast.Children[i]
+				if lhs := prodAST.Children[0].Tokens[0].Text(); lhs != e.lhs {
+					t.Errorf("unexpected production\nwant: %v\ngot: %v", e.lhs, lhs)
+				}
+				rhs := []string{}
+				for _, tok := range prodAST.Children[1].Children[0].Tokens {
+					rhs = append(rhs, tok.Text())
+				}
+				if strings.Join(rhs, " ") != strings.Join(e.rhs, " ") {
+					t.Errorf("unexpected RHS of %v\nwant: %v\ngot: %v", e.lhs, e.rhs, rhs)
+				}
+				if prodAST.File != e.file {
+					t.Errorf("unexpected file\nwant: %v\ngot: %v", e.file, prodAST.File)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFile_Error(t *testing.T) {
+	dir := filepath.Join("testdata", "include")
+
+	tests := map[string]struct {
+		path     string
+		errFile  string
+		errPos   Position
+		errMsgIn string
+	}{
+		"include cycle": {
+			path:     filepath.Join(dir, "cycle_a.sousa"),
+			errFile:  filepath.Join(dir, "cycle_b.sousa"),
+			errPos:   pos(1, 1),
+			errMsgIn: "include cycle: cycle_a.sousa -> cycle_b.sousa -> cycle_a.sousa",
+		},
+		"missing file": {
+			path:     filepath.Join(dir, "missing.sousa"),
+			errFile:  filepath.Join(dir, "missing.sousa"),
+			errPos:   pos(1, 1),
+			errMsgIn: "failed to include a file",
+		},
+		"syntax error in an included file": {
+			path:     filepath.Join(dir, "error_in_included.sousa"),
+			errFile:  filepath.Join(dir, "fragments", "broken.sousa"),
+			errPos:   pos(3, 1),
+			errMsgIn: "unexpected token",
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			_, err := ParseFile(tt.path)
+			if err == nil {
+				t.Fatal("an error was not returned")
+			}
+			synErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("unexpected error type\nwant: %T\ngot: %T", &SyntaxError{}, err)
+			}
+			if synErr.file != tt.errFile || synErr.position != tt.errPos {
+				t.Errorf("unexpected error position\nwant: %v %v\ngot: %v %v", tt.errFile, tt.errPos, synErr.file, synErr.position)
+			}
+			if !strings.Contains(synErr.message, tt.errMsgIn) {
+				t.Errorf("unexpected error message\nwant: a message containing %v\ngot: %v", tt.errMsgIn, synErr.message)
+			}
+		})
+	}
+}
+
+func TestParseFileWithIncludes(t *testing.T) {
+	dir := filepath.Join("testdata", "include")
+	frag := filepath.Join(dir, "fragments")
+
+	tests := []struct {
+		path  string
+		files []string
+		err   bool
+	}{
+		{
+			path: filepath.Join(dir, "main.sousa"),
+			files: []string{
+				filepath.Join(dir, "main.sousa"),
+				filepath.Join(frag, "expr.sousa"),
+				filepath.Join(frag, "term.sousa"),
+			},
+		},
+		{
+			path: filepath.Join(dir, "missing.sousa"),
+			files: []string{
+				filepath.Join(dir, "missing.sousa"),
+				filepath.Join(dir, "nothing.sousa"),
+			},
+			err: true,
+		},
+		{
+			path: filepath.Join(dir, "error_in_included.sousa"),
+			files: []string{
+				filepath.Join(dir, "error_in_included.sousa"),
+				filepath.Join(frag, "broken.sousa"),
+			},
+			err: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			ast, files, err := ParseFileWithIncludes(tt.path)
+			if tt.err {
+				if err == nil {
+					t.Fatal("an error was not returned")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ast == nil {
+					t.Fatal("an AST was not returned")
+				}
+			}
+			if strings.Join(files, " ") != strings.Join(tt.files, " ") {
+				t.Fatalf("unexpected files\nwant: %v\ngot: %v", tt.files, files)
+			}
+		})
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	dir := filepath.Join("testdata", "include")
+	path := filepath.Join(dir, "main.sousa")
+
+	// The source differs from the file at path, but the included files are read from the file system.
+	ast, err := ParseSource(path, strings.NewReader(`%include "fragments/term.sousa"; stmt: T ";";`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		lhs  string
+		file string
+	}{
+		{lhs: "T", file: filepath.Join(dir, "fragments", "term.sousa")},
+		{lhs: "F", file: filepath.Join(dir, "fragments", "term.sousa")},
+		{lhs: "stmt", file: path},
+	}
+	if len(ast.Children) != len(expected) {
+		t.Fatalf("unexpected number of productions\nwant: %v\ngot: %v", len(expected), len(ast.Children))
+	}
+	for i, e := range expected {
+		prodAST := ast.Children[i]
+		if lhs := prodAST.Children[0].Tokens[0].Text(); lhs != e.lhs {
+			t.Errorf("unexpected production\nwant: %v\ngot: %v", e.lhs, lhs)
+		}
+		if prodAST.File != e.file {
+			t.Errorf("unexpected file\nwant: %v\ngot: %v", e.file, prodAST.File)
+		}
+	}
+
+	_, err = ParseSource(path, strings.NewReader(`%include "fragments/broken.sousa";`))
+	synErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("a syntax error must occur. got: %v", err)
+	}
+	if synErr.File() != filepath.Join(dir, "fragments", "broken.sousa") {
+		t.Fatalf("unexpected file of the syntax error: %v", synErr.File())
+	}
+}
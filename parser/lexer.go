@@ -12,6 +12,9 @@ type Lexer interface {
 	Next() (Token, error)
 	Error() error
 	LastToken() Token
+
+	// Comments returns the comments skipped so far in order of appearance.
+	Comments() []Token
 }
 
 type lexer struct {
@@ -23,6 +26,7 @@ type lexer struct {
 	prevCharPos Position
 	err         error
 	unreadable  bool
+	comments    []Token
 }
 
 func NewLexer(src io.Reader) Lexer {
@@ -34,6 +38,7 @@ func NewLexer(src io.Reader) Lexer {
 		prevCharPos: newPosition(),
 		err:         nil,
 		unreadable:  false,
+		comments:    []Token{},
 	}
 }
 
@@ -70,6 +75,24 @@ func (l *lexer) next() (Token, error) {
 		return newSymbolToken(TokenTypeColon, pos), nil
 	case c == ';':
 		return newSymbolToken(TokenTypeSemicolon, pos), nil
+	case c == '=':
+		return newSymbolToken(TokenTypeEqual, pos), nil
+	case c == '%':
+		text, err := l.readDirective()
+		if err != nil {
+			return nil, err
+		}
+		if text != "" {
+			return newDirectiveToken(text, pos), nil
+		}
+	case c == '#':
+		text, err := l.readDirective()
+		if err != nil {
+			return nil, err
+		}
+		if text != "" {
+			return newLabelToken(text, pos), nil
+		}
 	case c == '"':
 		text, err := l.readString()
 		if err != nil {
@@ -111,6 +134,29 @@ func (l *lexer) readString() (string, error) {
 	return b.String(), nil
 }
 
+// readDirective reads the name of a directive following '%' or a label following '#'. When the character isn't
+// followed by a name, it returns an empty string.
+func (l *lexer) readDirective() (string, error) {
+	prefix := l.lastChar
+	c, eof, err := l.read()
+	if err != nil {
+		return "", err
+	}
+	if eof {
+		return "", nil
+	}
+	if !isIDChar(c) {
+		err := l.unread()
+		if err != nil {
+			return "", err
+		}
+		l.lastChar = prefix
+		return "", nil
+	}
+
+	return l.readID()
+}
+
 func (l *lexer) readID() (string, error) {
 	var b strings.Builder
 	fmt.Fprint(&b, string(l.lastChar))
@@ -122,7 +168,7 @@ func (l *lexer) readID() (string, error) {
 		if eof {
 			break
 		}
-		if !isIDChar(c) {
+		if !isIDTailChar(c) {
 			err := l.unread()
 			if err != nil {
 				return "", err
@@ -161,6 +207,18 @@ func (l *lexer) readUnknown() (string, error) {
 
 func (l *lexer) skipWhitespace() error {
 	for {
+		isComment, err := l.isNext("//")
+		if err != nil {
+			return err
+		}
+		if isComment {
+			err := l.readComment()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		c, eof, err := l.read()
 		if err != nil {
 			return err
@@ -180,6 +238,54 @@ func (l *lexer) skipWhitespace() error {
 	return nil
 }
 
+// readComment reads a comment that begins with "//" and continues until the end of the line.
+func (l *lexer) readComment() error {
+	pos := l.pos
+	for i := 0; i < 2; i++ {
+		_, _, err := l.read()
+		if err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	for {
+		c, eof, err := l.read()
+		if err != nil {
+			return err
+		}
+		if eof {
+			break
+		}
+		if c == '\n' || c == '\r' {
+			err := l.unread()
+			if err != nil {
+				return err
+			}
+			break
+		}
+		fmt.Fprint(&b, string(c))
+	}
+
+	l.comments = append(l.comments, newCommentToken(b.String(), pos))
+
+	return nil
+}
+
+// isNext reports whether the following characters are expected. Note that it disables unread.
+func (l *lexer) isNext(expected string) (bool, error) {
+	b, err := l.src.Peek(len(expected))
+	if err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	l.unreadable = false
+
+	return string(b) == expected, nil
+}
+
 func (l *lexer) read() (rune, bool, error) {
 	c, _, err := l.src.ReadRune()
 	if err != nil {
@@ -209,6 +315,10 @@ func isIDChar(c rune) bool {
 	return unicode.IsLetter(c)
 }
 
+func isIDTailChar(c rune) bool {
+	return isIDChar(c) || unicode.IsDigit(c) || c == '_' || c == '.'
+}
+
 func isWhitespace(c rune) bool {
 	return unicode.IsSpace(c)
 }
@@ -218,7 +328,7 @@ func isUnknownChar(c rune) bool {
 }
 
 func isFirstChar(c rune) bool {
-	return c == ':' || c == '|' || c == ';' || c == '"' || isIDChar(c) || isWhitespace(c)
+	return c == ':' || c == '|' || c == ';' || c == '=' || c == '"' || c == '%' || c == '#' || isIDChar(c) || isWhitespace(c)
 }
 
 func (l *lexer) Error() error {
@@ -228,3 +338,7 @@ func (l *lexer) Error() error {
 func (l *lexer) LastToken() Token {
 	return l.lastToken
 }
+
+func (l *lexer) Comments() []Token {
+	return l.comments
+}
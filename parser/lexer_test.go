@@ -32,6 +32,28 @@ func TestLexer_Run(t *testing.T) {
 			},
 			err: nil,
 		},
+		"labels": {
+			src: `left=expr #add # x`,
+			tokens: []Token{
+				newIDToken("left", dummyPos),
+				newSymbolToken(TokenTypeEqual, dummyPos),
+				newIDToken("expr", dummyPos),
+				newLabelToken("add", dummyPos),
+				newUnknownToken("#", dummyPos),
+				newIDToken("x", dummyPos),
+			},
+			err: nil,
+		},
+		"IDs contain letters, digits and underscores except the first character": {
+			src: `expr_2 _x 1`,
+			tokens: []Token{
+				newIDToken("expr_2", dummyPos),
+				newUnknownToken("_", dummyPos),
+				newIDToken("x", dummyPos),
+				newUnknownToken("1", dummyPos),
+			},
+			err: nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -73,3 +95,42 @@ func matchToken(expected, actual Token) bool {
 
 	return true
 }
+
+func TestLexer_Comments(t *testing.T) {
+	src := `// leading comment
+foo: bar; // trailing comment
+/ //`
+
+	l := NewLexer(strings.NewReader(src))
+	for {
+		tok, err := l.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type() == TokenTypeEOF {
+			break
+		}
+		if tok.Type() == TokenTypeComment {
+			t.Fatalf("a comment was returned as a token. token: %v", tok)
+		}
+	}
+
+	expected := []struct {
+		text string
+		pos  Position
+	}{
+		{text: " leading comment", pos: pos(1, 1)},
+		{text: " trailing comment", pos: pos(2, 11)},
+		{text: "", pos: pos(3, 3)},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("unexpected comments\nwant: %v comments\ngot: %v comments", len(expected), len(comments))
+	}
+	for i, e := range expected {
+		c := comments[i]
+		if c.Type() != TokenTypeComment || c.Text() != e.text || c.Pos() != e.pos {
+			t.Errorf("unexpected comment\nwant: %v %v\ngot: %v %v", e.text, e.pos, c.Text(), c.Pos())
+		}
+	}
+}
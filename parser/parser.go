@@ -6,6 +6,12 @@ import (
 
 // Grammar
 //
+// start
+//     : (directive | production)*
+//     ;
+// directive
+//     : "%" id (id | string)* ";"
+//     ;
 // production
 //     : lhs ":" rhs ";"
 //     ;
@@ -16,7 +22,13 @@ import (
 //     : alternative ("|" alternative)*
 //     ;
 // alternative
-//     : (id)*
+//     : element* label?
+//     ;
+// element
+//     : (id "=")? (id | string)
+//     ;
+// label
+//     : "#" id
 //     ;
 
 type State string
@@ -27,16 +39,44 @@ func (s State) String() string {
 
 const (
 	StateStart       = State("start")
+	StateDirective   = State("directive")
 	StateProduction  = State("production")
 	StateLHS         = State("lhs")
 	StateRHS         = State("rhs")
 	StateAlternative = State("alternative")
 )
 
+const (
+	DirectiveInclude = "include"
+	DirectiveStart   = "start"
+)
+
+var directives = map[string]struct{}{
+	DirectiveInclude: {},
+	DirectiveStart:   {},
+}
+
 type AST struct {
 	State    State
 	Tokens   []Token
 	Children []*AST
+
+	// Pos is the position of the first token of the node. For a node containing no token, it is the position of the following token.
+	Pos Position
+	// End is the position of the last token of the node. For a node containing no token, it equals Pos.
+	End Position
+
+	// Comments is set only on the root node and holds all comments in the source in order of appearance.
+	Comments []Token
+
+	// File is the path of the source file. ParseFile sets it on the root and the root's children.
+	File string
+
+	// Label is the label of an alternative written as #label. It is nil when the alternative has no label.
+	Label Token
+	// FieldLabels holds the label of each token of an alternative written as label=symbol. An element is nil when
+	// the symbol has no label, and FieldLabels is nil when no symbol has one.
+	FieldLabels []Token
 }
 
 func (ast *AST) appendChild(child *AST) {
@@ -47,9 +87,10 @@ func (ast *AST) appendChild(child *AST) {
 }
 
 type Frame struct {
-	state  State
-	tokens []Token
-	ast    *AST
+	state    State
+	tokens   []Token
+	ast      *AST
+	consumed bool
 }
 
 type Parser interface {
@@ -63,6 +104,7 @@ type parser struct {
 	stateStack   []*Frame
 	currentState *Frame
 	ast          *AST
+	lastTok      Token
 
 	sourceFilePath string
 }
@@ -95,6 +137,7 @@ func (p *parser) Parse() (ast *AST, err error) {
 	p.start()
 
 	ast = p.ast
+	ast.Comments = p.lex.Comments()
 	return
 }
 
@@ -105,12 +148,32 @@ func (p *parser) start() {
 		if p.isNext(TokenTypeEOF) {
 			break
 		}
+		if p.isNext(TokenTypeDirective) {
+			p.directive()
+			continue
+		}
 		p.production()
 	}
 
 	p.exit()
 }
 
+func (p *parser) directive() {
+	p.entry(StateDirective)
+
+	tok := p.consume(TokenTypeDirective)
+	if _, ok := directives[tok.Text()]; !ok {
+		p.raiseSyntaxError(tok, fmt.Sprintf("unknown directive: %%%s", tok.Text()))
+	}
+	p.currentState.tokens = append(p.currentState.tokens, tok)
+	for p.isNext(TokenTypeID, TokenTypeString) {
+		p.matchAndPush(TokenTypeID, TokenTypeString)
+	}
+	p.match(TokenTypeSemicolon)
+
+	p.exit()
+}
+
 func (p *parser) production() {
 	p.entry(StateProduction)
 
@@ -147,19 +210,38 @@ func (p *parser) rhs() {
 func (p *parser) alternative() {
 	p.entry(StateAlternative)
 
+	labels := []Token{}
+	labeled := false
 	for {
 		if !p.isNext(TokenTypeID, TokenTypeString) {
 			break
 		}
-		p.matchAndPush(TokenTypeID, TokenTypeString)
+		tok := p.consume(TokenTypeID, TokenTypeString)
+		var label Token
+		if tok.Type() == TokenTypeID && p.isNext(TokenTypeEqual) {
+			p.match(TokenTypeEqual)
+			label = tok
+			labeled = true
+			tok = p.consume(TokenTypeID, TokenTypeString)
+		}
+		p.currentState.tokens = append(p.currentState.tokens, tok)
+		labels = append(labels, label)
+	}
+	if labeled {
+		p.currentState.ast.FieldLabels = labels
+	}
+	if p.isNext(TokenTypeLabel) {
+		p.currentState.ast.Label = p.consume(TokenTypeLabel)
 	}
 
 	p.exit()
 }
 
 func (p *parser) entry(s State) {
+	p.peek()
 	ast := &AST{
 		State: s,
+		Pos:   p.peekedTok.Pos(),
 	}
 	if p.currentState != nil {
 		p.currentState.ast.appendChild(ast)
@@ -186,6 +268,11 @@ func (p *parser) exit() {
 	}
 
 	f.ast.Tokens = f.tokens
+	if f.consumed {
+		f.ast.End = p.lastTok.Pos()
+	} else {
+		f.ast.End = f.ast.Pos
+	}
 	p.ast = f.ast
 }
 
@@ -223,14 +310,23 @@ func (p *parser) consume(expected ...TokenType) Token {
 	}
 	for _, e := range expected {
 		if tok.Type() == e {
+			p.lastTok = tok
+			for _, f := range p.stateStack {
+				f.consumed = true
+			}
 			return tok
 		}
 	}
 
+	p.raiseSyntaxError(tok, fmt.Sprintf("unexpected token; expected: %v, actual: %v", expected, tok.Type()))
+	return nil
+}
+
+func (p *parser) raiseSyntaxError(tok Token, message string) {
 	panic(&SyntaxError{
 		file:     p.sourceFilePath,
 		position: tok.Pos(),
-		message:  fmt.Sprintf("unexpected token; expected: %v, actual: %v", expected, tok.Type()),
+		message:  message,
 	})
 }
 
@@ -18,6 +18,30 @@ func TestParser(t *testing.T) {
 			src: `foo: ; bar: | ; baz: | | ; bra: | abc | ;`,
 			err: false,
 		},
+		"the source contains directives": {
+			src: `%include "expr.sousa"; %include "expr.sousa" expr; %start foo; foo: expr.E;`,
+			err: false,
+		},
+		"the source contains an unknown directive": {
+			src: `%unknown; foo: bar;`,
+			err: true,
+		},
+		"a directive is not terminated": {
+			src: `%include "expr.sousa" foo: bar;`,
+			err: true,
+		},
+		"the source contains labels": {
+			src: `expr: left=expr op="+" right=term #add | term #term | #empty; term: id;`,
+			err: false,
+		},
+		"a field label is not followed by a symbol": {
+			src: `expr: left= #add;`,
+			err: true,
+		},
+		"an alternative has two labels": {
+			src: `expr: id #a #b;`,
+			err: true,
+		},
 	}
 	for caption, tt := range tests {
 		lex := NewLexer(strings.NewReader(tt.src))
@@ -64,3 +88,43 @@ func printAST(t *testing.T, ast *AST, depth int) {
 		printAST(t, child, depth+1)
 	}
 }
+
+func TestParser_Labels(t *testing.T) {
+	p, err := NewParser(NewLexer(strings.NewReader(`expr: left=expr "+" op="*" #mul | id; term: #empty;`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alts := ast.Children[0].Children[1].Children
+	if alts[0].Label == nil || alts[0].Label.Text() != "mul" {
+		t.Fatalf("unexpected label\nwant: %v\ngot: %v", "mul", alts[0].Label)
+	}
+	expected := []string{"left", "", "op"}
+	if len(alts[0].FieldLabels) != len(expected) || len(alts[0].Tokens) != len(expected) {
+		t.Fatalf("unexpected field labels\nwant: %v\ngot: %v", expected, alts[0].FieldLabels)
+	}
+	for i, label := range alts[0].FieldLabels {
+		text := ""
+		if label != nil {
+			text = label.Text()
+		}
+		if text != expected[i] {
+			t.Fatalf("unexpected field labels\nwant: %v\ngot: %v", expected, alts[0].FieldLabels)
+		}
+	}
+	if alts[0].Tokens[2].Text() != "*" {
+		t.Fatalf("a field label must not be a symbol. got: %v", alts[0].Tokens)
+	}
+	if alts[1].Label != nil || alts[1].FieldLabels != nil {
+		t.Fatalf("an alternative without labels must have no label")
+	}
+
+	empty := ast.Children[1].Children[1].Children[0]
+	if empty.Label == nil || empty.Label.Text() != "empty" || len(empty.Tokens) != 0 {
+		t.Fatalf("unexpected empty alternative: %+v", empty)
+	}
+}
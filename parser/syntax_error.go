@@ -19,3 +19,18 @@ func (synErr *SyntaxError) Error() string {
 
 	return b.String()
 }
+
+// File returns the path of the file in which the error occurred.
+func (synErr *SyntaxError) File() string {
+	return synErr.file
+}
+
+// Pos returns the position at which the error occurred.
+func (synErr *SyntaxError) Pos() Position {
+	return synErr.position
+}
+
+// Message returns the message of the error without its location.
+func (synErr *SyntaxError) Message() string {
+	return synErr.message
+}
@@ -18,6 +18,10 @@ const (
 	TokenTypeSemicolon = TokenType(";")
 	TokenTypeID        = TokenType("ID")
 	TokenTypeString    = TokenType("STRING")
+	TokenTypeComment   = TokenType("COMMENT")
+	TokenTypeDirective = TokenType("DIRECTIVE")
+	TokenTypeEqual     = TokenType("=")
+	TokenTypeLabel     = TokenType("LABEL")
 )
 
 type Position struct {
@@ -139,3 +143,57 @@ func (t *StringToken) Type() TokenType { return TokenTypeString }
 func (t *StringToken) Pos() Position   { return t.pos }
 func (t *StringToken) Text() string    { return t.text }
 func (t *StringToken) IsUnknown() bool { return false }
+
+type CommentToken struct {
+	pos  Position
+	text string
+}
+
+func newCommentToken(text string, pos Position) Token {
+	return &CommentToken{
+		pos:  pos,
+		text: text,
+	}
+}
+
+func (t *CommentToken) String() string  { return fmt.Sprintf("//%s", t.text) }
+func (t *CommentToken) Type() TokenType { return TokenTypeComment }
+func (t *CommentToken) Pos() Position   { return t.pos }
+func (t *CommentToken) Text() string    { return t.text }
+func (t *CommentToken) IsUnknown() bool { return false }
+
+type DirectiveToken struct {
+	pos  Position
+	text string
+}
+
+func newDirectiveToken(text string, pos Position) Token {
+	return &DirectiveToken{
+		pos:  pos,
+		text: text,
+	}
+}
+
+func (t *DirectiveToken) String() string  { return fmt.Sprintf("%%%s", t.text) }
+func (t *DirectiveToken) Type() TokenType { return TokenTypeDirective }
+func (t *DirectiveToken) Pos() Position   { return t.pos }
+func (t *DirectiveToken) Text() string    { return t.text }
+func (t *DirectiveToken) IsUnknown() bool { return false }
+
+type LabelToken struct {
+	pos  Position
+	text string
+}
+
+func newLabelToken(text string, pos Position) Token {
+	return &LabelToken{
+		pos:  pos,
+		text: text,
+	}
+}
+
+func (t *LabelToken) String() string  { return fmt.Sprintf("#%s", t.text) }
+func (t *LabelToken) Type() TokenType { return TokenTypeLabel }
+func (t *LabelToken) Pos() Position   { return t.pos }
+func (t *LabelToken) Text() string    { return t.text }
+func (t *LabelToken) IsUnknown() bool { return false }
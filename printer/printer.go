@@ -0,0 +1,364 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+)
+
+// Canonical format
+//
+// // A comment preceding a production stays on its own line.
+// lhs
+//     : alternative1       // trailing comments are aligned within a production
+//     | alternative2
+//     |
+//     ;
+//
+// %include "expr.sousa";
+//
+// Productions are separated by a blank line. Consecutive directives are not.
+
+const indent = "    "
+
+type production struct {
+	lhs  string
+	pos  parser.Position
+	end  parser.Position
+	alts []*alternative
+
+	// When directive is not nil, the item is a directive instead of a production.
+	directive *directive
+}
+
+type directive struct {
+	name string
+	args []string
+}
+
+type alternative struct {
+	symbols []string
+	pos     parser.Position
+	end     parser.Position
+}
+
+// Fprint writes the grammar represented by root in the canonical format. Comments held by root are preserved.
+func Fprint(w io.Writer, root *parser.AST) error {
+	if root == nil {
+		return fmt.Errorf("AST passed is nil")
+	}
+
+	prods := []*production{}
+	for _, prodAST := range root.Children {
+		if prodAST.State == parser.StateDirective {
+			args := make([]string, len(prodAST.Tokens)-1)
+			for i, tok := range prodAST.Tokens[1:] {
+				args[i] = tokenText(tok)
+			}
+			prods = append(prods, &production{
+				pos: prodAST.Pos,
+				end: prodAST.End,
+				directive: &directive{
+					name: prodAST.Tokens[0].Text(),
+					args: args,
+				},
+			})
+			continue
+		}
+		if prodAST.State != parser.StateProduction {
+			continue
+		}
+
+		lhsAST := prodAST.Children[0]
+		prod := &production{
+			lhs:  lhsAST.Tokens[0].Text(),
+			pos:  lhsAST.Pos,
+			end:  prodAST.End,
+			alts: []*alternative{},
+		}
+		for _, altAST := range prodAST.Children[1].Children {
+			syms := make([]string, len(altAST.Tokens))
+			for i, tok := range altAST.Tokens {
+				syms[i] = tokenText(tok)
+				if altAST.FieldLabels != nil && altAST.FieldLabels[i] != nil {
+					syms[i] = altAST.FieldLabels[i].Text() + "=" + syms[i]
+				}
+			}
+			if altAST.Label != nil {
+				syms = append(syms, "#"+altAST.Label.Text())
+			}
+			prod.alts = append(prod.alts, &alternative{
+				symbols: syms,
+				pos:     altAST.Pos,
+				end:     altAST.End,
+			})
+		}
+		prods = append(prods, prod)
+	}
+
+	return fprint(w, prods, root.Comments)
+}
+
+// FprintProductions writes productions in the canonical format.
+// Productions of augmented start symbols are omitted because they are synthesized when the grammar is read.
+// Instead, a %start directive is written unless the start symbol is the LHS of the first production.
+func FprintProductions(w io.Writer, st *grammar.SymbolTable, prods grammar.Productions) error {
+	if st == nil || prods == nil {
+		return fmt.Errorf("parameters passed contains nil")
+	}
+
+	all := []*grammar.Production{}
+	lhsOf := map[*grammar.Production]grammar.SymbolID{}
+	for lhs, ps := range prods.All() {
+		for _, p := range ps {
+			all = append(all, p)
+			lhsOf[p] = lhs
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID() < all[j].ID()
+	})
+
+	ps := []*production{}
+	index := map[grammar.SymbolID]*production{}
+	starts := []string{}
+	for _, p := range all {
+		lhs := lhsOf[p]
+		if lhs.Kind().IsStartSymbol() {
+			rhs, _ := p.RHS()
+			if len(rhs) != 1 {
+				return fmt.Errorf("a production of an augmented start symbol must have just one symbol. production: %v", p)
+			}
+			text, ok := st.ToString(rhs[0])
+			if !ok {
+				return fmt.Errorf("failed to get the text of a symbol. symbol: %v", rhs[0])
+			}
+			starts = append(starts, text)
+			continue
+		}
+
+		prod, ok := index[lhs]
+		if !ok {
+			lhsText, ok := st.ToString(lhs)
+			if !ok {
+				return fmt.Errorf("failed to get the text of a symbol. symbol: %v", lhs)
+			}
+			prod = &production{
+				lhs:  lhsText,
+				alts: []*alternative{},
+			}
+			index[lhs] = prod
+			ps = append(ps, prod)
+		}
+
+		rhs, _ := p.RHS()
+		syms := make([]string, len(rhs))
+		for i, sym := range rhs {
+			text, ok := st.ToString(sym)
+			if !ok {
+				return fmt.Errorf("failed to get the text of a symbol. symbol: %v", sym)
+			}
+			if sym.Kind().IsTerminalSymbol() && !isID(text) {
+				text = quote(text)
+			}
+			syms[i] = text
+		}
+		prod.alts = append(prod.alts, &alternative{
+			symbols: syms,
+		})
+	}
+
+	if len(starts) > 1 || (len(starts) == 1 && (len(ps) == 0 || ps[0].lhs != starts[0])) {
+		ps = append([]*production{
+			{
+				directive: &directive{
+					name: parser.DirectiveStart,
+					args: starts,
+				},
+			},
+		}, ps...)
+	}
+
+	return fprint(w, ps, nil)
+}
+
+type line struct {
+	text     string
+	indented bool
+	pos      parser.Position
+	end      parser.Position
+	trailing []string
+}
+
+func fprint(w io.Writer, prods []*production, comments []parser.Token) error {
+	buf := new(bytes.Buffer)
+	cs := comments
+
+	for i, prod := range prods {
+		if i > 0 && (prod.directive == nil || prods[i-1].directive == nil) {
+			fmt.Fprint(buf, "\n")
+		}
+
+		// Comments preceding the production
+		for len(cs) > 0 && before(cs[0].Pos(), prod.pos) {
+			fmt.Fprintf(buf, "//%s\n", cs[0].Text())
+			next := prod.pos
+			if len(cs) > 1 && before(cs[1].Pos(), prod.pos) {
+				next = cs[1].Pos()
+			}
+			if next.Line > cs[0].Pos().Line+1 {
+				fmt.Fprint(buf, "\n")
+			}
+			cs = cs[1:]
+		}
+
+		lines := prod.lines()
+
+		// Comments inside the production are attached to the lines. A comment on the same line as the end of a line
+		// becomes a trailing comment, and the other comments are printed on their own lines before the next line.
+		leading := make([][]string, len(lines))
+		for j, l := range lines {
+			for len(cs) > 0 {
+				c := cs[0]
+				if j+1 < len(lines) && !before(c.Pos(), lines[j+1].pos) {
+					break
+				}
+				if j+1 >= len(lines) && (c.Pos().Line > l.end.Line || (i+1 < len(prods) && !before(c.Pos(), prods[i+1].pos))) {
+					break
+				}
+
+				if c.Pos().Line <= l.end.Line {
+					l.trailing = append(l.trailing, "//"+c.Text())
+				} else {
+					leading[j+1] = append(leading[j+1], "//"+c.Text())
+				}
+				cs = cs[1:]
+			}
+		}
+
+		width := 0
+		for _, l := range lines {
+			if len(l.trailing) > 0 && l.width() > width {
+				width = l.width()
+			}
+		}
+		for j, l := range lines {
+			for _, c := range leading[j] {
+				fmt.Fprintf(buf, "%s%s\n", indent, c)
+			}
+
+			if l.indented {
+				fmt.Fprint(buf, indent)
+			}
+			fmt.Fprint(buf, l.text)
+			if len(l.trailing) > 0 {
+				fmt.Fprintf(buf, "%s %s", strings.Repeat(" ", width-l.width()), strings.Join(l.trailing, " "))
+			}
+			fmt.Fprint(buf, "\n")
+		}
+	}
+
+	// Comments following the last production
+	for i, c := range cs {
+		if i == 0 && len(prods) > 0 {
+			fmt.Fprint(buf, "\n")
+		}
+		fmt.Fprintf(buf, "//%s\n", c.Text())
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (prod *production) lines() []*line {
+	if prod.directive != nil {
+		text := "%" + prod.directive.name
+		if len(prod.directive.args) > 0 {
+			text += " " + strings.Join(prod.directive.args, " ")
+		}
+		return []*line{
+			{
+				text: text + ";",
+				pos:  prod.pos,
+				end:  prod.end,
+			},
+		}
+	}
+
+	lines := []*line{
+		{
+			text: prod.lhs,
+			pos:  prod.pos,
+			end:  prod.pos,
+		},
+	}
+	for j, alt := range prod.alts {
+		text := "|"
+		if j == 0 {
+			text = ":"
+		}
+		if len(alt.symbols) > 0 {
+			text += " " + strings.Join(alt.symbols, " ")
+		}
+		lines = append(lines, &line{
+			text:     text,
+			indented: true,
+			pos:      alt.pos,
+			end:      alt.end,
+		})
+	}
+	lines = append(lines, &line{
+		text:     ";",
+		indented: true,
+		pos:      prod.end,
+		end:      prod.end,
+	})
+
+	return lines
+}
+
+func (l *line) width() int {
+	w := len([]rune(l.text))
+	if l.indented {
+		w += len(indent)
+	}
+	return w
+}
+
+func before(p1, p2 parser.Position) bool {
+	if p1.Line == p2.Line {
+		return p1.Column < p2.Column
+	}
+	return p1.Line < p2.Line
+}
+
+func tokenText(tok parser.Token) string {
+	if tok.Type() == parser.TokenTypeString {
+		return quote(tok.Text())
+	}
+	return tok.Text()
+}
+
+func quote(text string) string {
+	return fmt.Sprintf("\"%s\"", text)
+}
+
+func isID(text string) bool {
+	for i, c := range text {
+		if unicode.IsLetter(c) {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(c) || c == '_' || c == '.') {
+			continue
+		}
+		return false
+	}
+
+	return text != ""
+}
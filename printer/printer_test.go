@@ -0,0 +1,201 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/parser"
+)
+
+func TestFprint(t *testing.T) {
+	tests := map[string]struct {
+		src      string
+		expected string
+	}{
+		"productions are formatted one alternative per line": {
+			src: `E: E "+" T | T; T: T "*" F | F; F: "(" E ")" | id;`,
+			expected: `E
+    : E "+" T
+    | T
+    ;
+
+T
+    : T "*" F
+    | F
+    ;
+
+F
+    : "(" E ")"
+    | id
+    ;
+`,
+		},
+		"empty alternatives are kept": {
+			src: `foo: ; bar: | a | ;`,
+			expected: `foo
+    :
+    ;
+
+bar
+    :
+    | a
+    |
+    ;
+`,
+		},
+		"labels are kept": {
+			src: `expr: left=expr op="+" right=term #add | term #term | #empty;`,
+			expected: `expr
+    : left=expr op="+" right=term #add
+    | term #term
+    | #empty
+    ;
+`,
+		},
+		"directives are kept in place": {
+			src: `%include "a.sousa"; %include "b.sousa" b; // b
+foo: b.bar; %include "c.sousa";`,
+			expected: `%include "a.sousa";
+%include "b.sousa" b; // b
+
+foo
+    : b.bar
+    ;
+
+%include "c.sousa";
+`,
+		},
+		"comments are preserved": {
+			src: `// header
+
+// about expr
+expr : expr "+" term // addition
+  | term   // a single term
+  // before ;
+  ;
+// about term
+term: id; // trailing
+// footer
+`,
+			expected: `// header
+
+// about expr
+expr
+    : expr "+" term // addition
+    | term          // a single term
+    // before ;
+    ;
+
+// about term
+term
+    : id
+    ; // trailing
+
+// footer
+`,
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			formatted := format(t, tt.src)
+			if formatted != tt.expected {
+				t.Fatalf("unexpected output\nwant:\n%v\ngot:\n%v", tt.expected, formatted)
+			}
+
+			// Formatting is idempotent.
+			reformatted := format(t, formatted)
+			if reformatted != formatted {
+				t.Fatalf("formatting is not idempotent\nwant:\n%v\ngot:\n%v", formatted, reformatted)
+			}
+		})
+	}
+}
+
+func TestFprintProductions(t *testing.T) {
+	tests := map[string]struct {
+		src      string
+		expected string
+	}{
+		"terminals are quoted unless they are IDs": {
+			src: `E: E "+" T | T; T: id | "if" | "a b";`,
+			expected: `E
+    : E "+" T
+    | T
+    ;
+
+T
+    : id
+    | if
+    | "a b"
+    ;
+`,
+		},
+		"start symbols are declared when they are not the first LHS": {
+			src: `%start T E; E: T; T: id;`,
+			expected: `%start T E;
+
+E
+    : T
+    ;
+
+T
+    : id
+    ;
+`,
+		},
+	}
+	for caption, tt := range tests {
+		t.Run(caption, func(t *testing.T) {
+			testFprintProductions(t, tt.src, tt.expected)
+		})
+	}
+}
+
+func testFprintProductions(t *testing.T, src string, expected string) {
+	t.Helper()
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = FprintProductions(&b, g.SymbolTable, g.Productions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expected {
+		t.Fatalf("unexpected output\nwant:\n%v\ngot:\n%v", expected, b.String())
+	}
+}
+
+func format(t *testing.T, src string) string {
+	t.Helper()
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = Fprint(&b, ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
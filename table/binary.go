@@ -0,0 +1,497 @@
+package table
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// Binary format
+//
+// All integers are little-endian. Counts and lengths are uint32, and symbol numbers, states, and table entries are
+// int32.
+//
+// magic       "SOUSATBL"
+// version     uint32
+// layout      uint8 (0: Table, 1: Packed)
+// symbols     count, then (kind uint8, name length, name bytes) for each symbol
+// terminals   count
+// productions count, then (lhs, RHS length, RHS) for each production
+// states      count
+// entries     count, then (symbol, state) for each entry point
+// conflicts   count, then (state, terminal, actions length, actions) for each conflict
+//
+// Table
+// actions     states × terminals entries
+// gotos       states × non-terminals entries
+//
+// Packed
+// The arrays from DefaultReductions to GoToNext in the order of the fields, each preceded by its length.
+
+const magic = "SOUSATBL"
+
+// FormatVersion is the version of the binary format that Write writes and Read reads.
+const FormatVersion = 1
+
+const (
+	layoutTable  = uint8(0)
+	layoutPacked = uint8(1)
+)
+
+// Write writes t in the binary format.
+func (t *Table) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	e := &encoder{
+		w: bw,
+	}
+
+	e.header(layoutTable, &t.Header)
+	e.int32s(t.Actions)
+	e.int32s(t.GoTos)
+
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+// Write writes p in the binary format.
+func (p *Packed) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	e := &encoder{
+		w: bw,
+	}
+
+	e.header(layoutPacked, &p.Header)
+	for _, a := range p.arrays() {
+		e.uint32(len(*a))
+		e.int32s(*a)
+	}
+
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+func (p *Packed) arrays() []*[]int32 {
+	return []*[]int32{
+		&p.DefaultReductions, &p.DefaultReductionTerminals, &p.ActionRows, &p.ActionBase, &p.ActionCheck, &p.ActionNext,
+		&p.DefaultGoTos, &p.GoToRows, &p.GoToBase, &p.GoToCheck, &p.GoToNext,
+	}
+}
+
+// IsBinary reports whether b begins like a parsing table in the binary format. It doesn't check the version or the
+// rest of b.
+func IsBinary(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(magic))
+}
+
+// Load reads a table in the binary format from the file at path.
+func Load(path string) (ParsingTable, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Read(bytes.NewReader(b))
+}
+
+// Read reads a table in the binary format. The table is a *Table or a *Packed according to the layout it was
+// written in.
+func Read(r io.Reader) (ParsingTable, error) {
+	d := &decoder{
+		r: bufio.NewReader(r),
+	}
+
+	m := d.bytes(len(magic))
+	if d.err != nil || string(m) != magic {
+		return nil, fmt.Errorf("not a parsing table of sousa")
+	}
+	if v := d.uint32(); d.err == nil && v != FormatVersion {
+		return nil, fmt.Errorf("unsupported format version. want: %v, got: %v", FormatVersion, v)
+	}
+	layout := d.uint8()
+
+	h := Header{}
+
+	// Slices grow as elements are read so that a malformed count doesn't cause a huge allocation.
+	syms := []Symbol{}
+	for i, n := 0, d.uint32(); i < n && d.err == nil; i++ {
+		kind := SymbolKind(d.uint8())
+		name := d.bytes(d.uint32())
+		syms = append(syms, Symbol{
+			Name: string(name),
+			Kind: kind,
+		})
+	}
+	terminals := d.uint32()
+
+	prods := []Production{}
+	for i, n := 0, d.uint32(); i < n && d.err == nil; i++ {
+		prod := Production{
+			LHS: int(d.int32()),
+			RHS: []int{},
+		}
+		for j, m := 0, d.uint32(); j < m && d.err == nil; j++ {
+			prod.RHS = append(prod.RHS, int(d.int32()))
+		}
+		prods = append(prods, prod)
+	}
+
+	states := d.uint32()
+	if d.err == nil && (terminals > len(syms) || states > maxEntries/(len(syms)+1)) {
+		return nil, fmt.Errorf("malformed parsing table. states: %v, symbols: %v, terminals: %v", states, len(syms), terminals)
+	}
+
+	eps := []EntryPoint{}
+	for i, n := 0, d.uint32(); i < n && d.err == nil; i++ {
+		ep := EntryPoint{
+			Symbol: int(d.int32()),
+			State:  int(d.int32()),
+		}
+		eps = append(eps, ep)
+	}
+
+	conflicts := []Conflict{}
+	for i, n := 0, d.uint32(); i < n && d.err == nil; i++ {
+		c := Conflict{
+			State:    int(d.int32()),
+			Terminal: int(d.int32()),
+			Actions:  []int32{},
+		}
+		for j, m := 0, d.uint32(); j < m && d.err == nil; j++ {
+			c.Actions = append(c.Actions, d.int32())
+		}
+		conflicts = append(conflicts, c)
+	}
+
+	h.Symbols = syms
+	h.Terminals = terminals
+	h.Productions = prods
+	h.States = states
+	h.EntryPoints = eps
+	h.Conflicts = conflicts
+
+	var t ParsingTable
+	var err error
+	switch layout {
+	case layoutTable:
+		tab := &Table{
+			Header: h,
+		}
+		tab.Actions = d.int32s(states * terminals)
+		tab.GoTos = d.int32s(states * (len(syms) - terminals))
+		t = tab
+		if d.err == nil {
+			err = tab.validate()
+		}
+	case layoutPacked:
+		p := &Packed{
+			Header: h,
+		}
+		for _, a := range p.arrays() {
+			*a = d.int32s(d.uint32())
+		}
+		t = p
+		if d.err == nil {
+			err = p.validate()
+		}
+	default:
+		if d.err == nil {
+			return nil, fmt.Errorf("malformed parsing table. unknown layout: %v", layout)
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("malformed parsing table: %v", d.err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (h *Header) validate() error {
+	for _, prod := range h.Productions {
+		if prod.LHS < h.Terminals || prod.LHS >= len(h.Symbols) {
+			return fmt.Errorf("malformed parsing table. invalid LHS: %v", prod.LHS)
+		}
+		for _, sym := range prod.RHS {
+			if sym <= SymbolEOF || sym >= len(h.Symbols) {
+				return fmt.Errorf("malformed parsing table. invalid RHS symbol: %v", sym)
+			}
+		}
+	}
+	for _, ep := range h.EntryPoints {
+		if ep.Symbol < h.Terminals || ep.Symbol >= len(h.Symbols) || ep.State < 0 || ep.State >= h.States {
+			return fmt.Errorf("malformed parsing table. invalid entry point: %+v", ep)
+		}
+	}
+	for i, c := range h.Conflicts {
+		if c.State < 0 || c.State >= h.States || c.Terminal < 0 || c.Terminal >= h.Terminals {
+			return fmt.Errorf("malformed parsing table. invalid conflict: %+v", c)
+		}
+		if i > 0 {
+			prev := h.Conflicts[i-1]
+			if prev.State > c.State || (prev.State == c.State && prev.Terminal >= c.Terminal) {
+				return fmt.Errorf("malformed parsing table. conflicts are not sorted")
+			}
+		}
+		for _, a := range c.Actions {
+			if a == 0 {
+				return fmt.Errorf("malformed parsing table. invalid conflict: %+v", c)
+			}
+			if err := validateAction(a, h.States, len(h.Productions)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateAction(a int32, states, prods int) error {
+	if (a > 0 && int(a) > states) || (a < 0 && a != actionAccept && int(-a) > prods) {
+		return fmt.Errorf("malformed parsing table. invalid action: %v", a)
+	}
+	return nil
+}
+
+func validateGoTo(g int32, states int) error {
+	if g < 0 || int(g) > states {
+		return fmt.Errorf("malformed parsing table. invalid goto: %v", g)
+	}
+	return nil
+}
+
+func (t *Table) validate() error {
+	err := t.Header.validate()
+	if err != nil {
+		return err
+	}
+	for _, a := range t.Actions {
+		if err := validateAction(a, t.States, len(t.Productions)); err != nil {
+			return err
+		}
+	}
+	for _, g := range t.GoTos {
+		if err := validateGoTo(g, t.States); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Packed) validate() error {
+	err := p.Header.validate()
+	if err != nil {
+		return err
+	}
+
+	if len(p.DefaultReductions) != p.States || len(p.DefaultGoTos) != p.NonTerminals() {
+		return fmt.Errorf("malformed parsing table. invalid number of defaults")
+	}
+	for _, a := range p.DefaultReductions {
+		if a > 0 || a == actionAccept {
+			return fmt.Errorf("malformed parsing table. invalid default reduction: %v", a)
+		}
+		if err := validateAction(a, p.States, len(p.Productions)); err != nil {
+			return err
+		}
+	}
+	words := lookaheadWords(p.Terminals)
+	if len(p.DefaultReductionTerminals) != p.States*words {
+		return fmt.Errorf("malformed parsing table. invalid number of default reduction terminals")
+	}
+	for state := 0; state < p.States; state++ {
+		for i, w := range p.DefaultReductionTerminals[state*words : (state+1)*words] {
+			if w == 0 {
+				continue
+			}
+			if p.DefaultReductions[state] == 0 || (i == words-1 && p.Terminals%32 != 0 && uint32(w)>>uint(p.Terminals%32) != 0) {
+				return fmt.Errorf("malformed parsing table. invalid default reduction terminals of state %v", state)
+			}
+		}
+	}
+	for _, g := range p.DefaultGoTos {
+		if err := validateGoTo(g, p.States); err != nil {
+			return err
+		}
+	}
+	for _, a := range p.ActionNext {
+		if err := validateAction(a, p.States, len(p.Productions)); err != nil {
+			return err
+		}
+	}
+	for _, g := range p.GoToNext {
+		if err := validateGoTo(g, p.States); err != nil {
+			return err
+		}
+	}
+
+	err = validateRows(p.States, p.ActionRows, p.ActionBase, p.ActionCheck, p.ActionNext)
+	if err != nil {
+		return err
+	}
+	return validateRows(p.States, p.GoToRows, p.GoToBase, p.GoToCheck, p.GoToNext)
+}
+
+func validateRows(states int, rowMap, base, check, next []int32) error {
+	if len(rowMap) != states || len(check) != len(next) {
+		return fmt.Errorf("malformed parsing table. invalid length of rows")
+	}
+	for _, r := range rowMap {
+		if r < 0 || int(r) >= len(base) {
+			return fmt.Errorf("malformed parsing table. invalid row: %v", r)
+		}
+	}
+	for _, b := range base {
+		if b < 0 {
+			return fmt.Errorf("malformed parsing table. invalid base: %v", b)
+		}
+	}
+	for _, c := range check {
+		if c < -1 || int(c) >= len(base) {
+			return fmt.Errorf("malformed parsing table. invalid check: %v", c)
+		}
+	}
+
+	return nil
+}
+
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) bytes(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) write(v interface{}) {
+	if e.err != nil {
+		return
+	}
+	e.err = binary.Write(e.w, binary.LittleEndian, v)
+}
+
+func (e *encoder) uint8(v uint8) {
+	e.write(v)
+}
+
+func (e *encoder) uint32(v int) {
+	e.write(uint32(v))
+}
+
+func (e *encoder) int32(v int32) {
+	e.write(v)
+}
+
+func (e *encoder) header(layout uint8, h *Header) {
+	e.bytes([]byte(magic))
+	e.uint32(FormatVersion)
+	e.uint8(layout)
+
+	e.uint32(len(h.Symbols))
+	for _, sym := range h.Symbols {
+		e.uint8(uint8(sym.Kind))
+		e.uint32(len(sym.Name))
+		e.bytes([]byte(sym.Name))
+	}
+	e.uint32(h.Terminals)
+
+	e.uint32(len(h.Productions))
+	for _, prod := range h.Productions {
+		e.int32(int32(prod.LHS))
+		e.uint32(len(prod.RHS))
+		for _, sym := range prod.RHS {
+			e.int32(int32(sym))
+		}
+	}
+
+	e.uint32(h.States)
+
+	e.uint32(len(h.EntryPoints))
+	for _, ep := range h.EntryPoints {
+		e.int32(int32(ep.Symbol))
+		e.int32(int32(ep.State))
+	}
+
+	e.uint32(len(h.Conflicts))
+	for _, c := range h.Conflicts {
+		e.int32(int32(c.State))
+		e.int32(int32(c.Terminal))
+		e.uint32(len(c.Actions))
+		e.int32s(c.Actions)
+	}
+}
+
+func (e *encoder) int32s(vs []int32) {
+	e.write(vs)
+}
+
+// maxEntries bounds the number of entries of the action and goto tables.
+const maxEntries = math.MaxInt32
+
+type decoder struct {
+	r   io.Reader
+	err error
+}
+
+func (d *decoder) read(v interface{}) {
+	if d.err != nil {
+		return
+	}
+	d.err = binary.Read(d.r, binary.LittleEndian, v)
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b := new(bytes.Buffer)
+	_, d.err = io.CopyN(b, d.r, int64(n))
+	return b.Bytes()
+}
+
+func (d *decoder) uint8() uint8 {
+	var v uint8
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) uint32() int {
+	var v uint32
+	d.read(&v)
+	return int(v)
+}
+
+func (d *decoder) int32() int32 {
+	var v int32
+	d.read(&v)
+	return v
+}
+
+func (d *decoder) int32s(n int) []int32 {
+	const chunkSize = 4096
+
+	vs := []int32{}
+	for len(vs) < n && d.err == nil {
+		chunk := make([]int32, chunkSize)
+		if n-len(vs) < chunkSize {
+			chunk = chunk[:n-len(vs)]
+		}
+		d.read(chunk)
+		vs = append(vs, chunk...)
+	}
+	return vs
+}
@@ -0,0 +1,259 @@
+package table
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Packed is a compressed form of Table.
+//
+// A state's most frequent reduce action becomes its default reduction, which is taken on any terminal without an
+// explicit action. A non-terminal's most frequent goto becomes its default goto. The remaining entries are grouped
+// into rows, identical rows are merged, and the rows are overlaid on a single array by row displacement as yacc
+// does. The entry of row r at column c is Next[Base[r]+c] if Check[Base[r]+c] == r.
+//
+// Because of default reductions, Action may return a reduce action where Table returns an error. Such reductions
+// never shift a terminal, so a parser detects the error before it consumes the erroneous terminal. Likewise, GoTo
+// may return a default goto where Table has none, which never happens while parsing.
+type Packed struct {
+	Header
+
+	// DefaultReductions holds an encoded reduce action or 0 for each state.
+	DefaultReductions []int32
+	// DefaultReductionTerminals holds, for each state, a bit set of the terminal symbols on which the state has its
+	// default reduction in Table. Each bit set occupies (Terminals+31)/32 words, and terminal symbol i is bit i%32
+	// of word i/32. ExpectedTerminals uses it to tell the terminal symbols on which a state has an action.
+	DefaultReductionTerminals []int32
+	ActionRows                []int32
+	ActionBase                []int32
+	ActionCheck               []int32
+	ActionNext                []int32
+
+	// DefaultGoTos holds the next state + 1 or 0 for each non-terminal symbol.
+	DefaultGoTos []int32
+	GoToRows     []int32
+	GoToBase     []int32
+	GoToCheck    []int32
+	GoToNext     []int32
+}
+
+// Pack compresses t.
+func Pack(t *Table) *Packed {
+	p := &Packed{
+		Header:                    t.Header,
+		DefaultReductions:         make([]int32, t.States),
+		DefaultReductionTerminals: make([]int32, t.States*lookaheadWords(t.Terminals)),
+	}
+
+	actionRows := make([][]int32, t.States)
+	for state := 0; state < t.States; state++ {
+		row := append([]int32{}, t.Actions[state*t.Terminals:(state+1)*t.Terminals]...)
+
+		counts := map[int32]int{}
+		for _, a := range row {
+			if a < 0 && a != actionAccept {
+				counts[a]++
+			}
+		}
+		def := mostFrequent(counts)
+		if def != 0 {
+			words := p.DefaultReductionTerminals[state*lookaheadWords(t.Terminals):]
+			for i, a := range row {
+				if a == def {
+					row[i] = 0
+					words[i/32] |= 1 << uint(i%32)
+				}
+			}
+		}
+		p.DefaultReductions[state] = def
+		actionRows[state] = row
+	}
+	p.ActionRows, p.ActionBase, p.ActionCheck, p.ActionNext = packRows(actionRows)
+
+	nonTerminals := t.NonTerminals()
+	p.DefaultGoTos = make([]int32, nonTerminals)
+	for col := 0; col < nonTerminals; col++ {
+		counts := map[int32]int{}
+		for state := 0; state < t.States; state++ {
+			if next := t.GoTos[state*nonTerminals+col]; next != 0 {
+				counts[next]++
+			}
+		}
+		p.DefaultGoTos[col] = mostFrequent(counts)
+	}
+	goToRows := make([][]int32, t.States)
+	for state := 0; state < t.States; state++ {
+		row := append([]int32{}, t.GoTos[state*nonTerminals:(state+1)*nonTerminals]...)
+		for col, next := range row {
+			if next == p.DefaultGoTos[col] {
+				row[col] = 0
+			}
+		}
+		goToRows[state] = row
+	}
+	p.GoToRows, p.GoToBase, p.GoToCheck, p.GoToNext = packRows(goToRows)
+
+	return p
+}
+
+// mostFrequent returns the most frequent value in counts. Ties are broken by the larger value so that the result
+// doesn't depend on the iteration order. It returns 0 when counts is empty.
+func mostFrequent(counts map[int32]int) int32 {
+	var v int32
+	n := 0
+	for value, count := range counts {
+		if count > n || (count == n && value > v) {
+			v = value
+			n = count
+		}
+	}
+
+	return v
+}
+
+// lookaheadWords returns the number of words of a bit set of terminal symbols.
+func lookaheadWords(terminals int) int {
+	return (terminals + 31) / 32
+}
+
+// packRows merges identical rows and overlays the unique rows on a single array. A zero entry means no entry.
+func packRows(rows [][]int32) (rowMap, base, check, next []int32) {
+	rowMap = make([]int32, len(rows))
+	unique := [][]int32{}
+	index := map[string]int{}
+	for i, row := range rows {
+		key := fmt.Sprint(row)
+		n, ok := index[key]
+		if !ok {
+			n = len(unique)
+			index[key] = n
+			unique = append(unique, row)
+		}
+		rowMap[i] = int32(n)
+	}
+
+	// Dense rows are placed first because they are the hardest to fit.
+	order := make([]int, len(unique))
+	entries := make([][]int, len(unique))
+	for i, row := range unique {
+		order[i] = i
+		for col, v := range row {
+			if v != 0 {
+				entries[i] = append(entries[i], col)
+			}
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(entries[order[i]]) > len(entries[order[j]])
+	})
+
+	base = make([]int32, len(unique))
+	check = []int32{}
+	next = []int32{}
+	for _, r := range order {
+		cols := entries[r]
+		if len(cols) == 0 {
+			continue
+		}
+
+		b := 0
+		for ; ; b++ {
+			fits := true
+			for _, col := range cols {
+				if b+col < len(check) && check[b+col] >= 0 {
+					fits = false
+					break
+				}
+			}
+			if fits {
+				break
+			}
+		}
+
+		for _, col := range cols {
+			for len(check) <= b+col {
+				check = append(check, -1)
+				next = append(next, 0)
+			}
+			check[b+col] = int32(r)
+			next[b+col] = unique[r][col]
+		}
+		base[r] = int32(b)
+	}
+
+	return rowMap, base, check, next
+}
+
+func lookupRow(rowMap, base, check, next []int32, row, col int) (int32, bool) {
+	r := rowMap[row]
+	i := int(base[r]) + col
+	if i < 0 || i >= len(check) || check[i] != r {
+		return 0, false
+	}
+
+	return next[i], true
+}
+
+// Action returns the action in state on terminal. The second value is the next state of a shift action or the
+// production of a reduce action.
+func (p *Packed) Action(state, terminal int) (ActionType, int) {
+	if state < 0 || state >= p.States || terminal < 0 || terminal >= p.Terminals {
+		return ActionTypeError, 0
+	}
+
+	if a, ok := lookupRow(p.ActionRows, p.ActionBase, p.ActionCheck, p.ActionNext, state, terminal); ok {
+		return decodeAction(a)
+	}
+
+	return decodeAction(p.DefaultReductions[state])
+}
+
+// ExpectedTerminals returns the terminal symbols, including the end of input, on which state has an action. Like
+// Table, it leaves out the terminal symbols on which only the default reduction of state applies.
+func (p *Packed) ExpectedTerminals(state int) []int {
+	syms := []int{}
+	if state < 0 || state >= p.States {
+		return syms
+	}
+
+	words := p.DefaultReductionTerminals[state*lookaheadWords(p.Terminals):]
+	for sym := 0; sym < p.Terminals; sym++ {
+		_, explicit := lookupRow(p.ActionRows, p.ActionBase, p.ActionCheck, p.ActionNext, state, sym)
+		if explicit || words[sym/32]&(1<<uint(sym%32)) != 0 {
+			syms = append(syms, sym)
+		}
+	}
+
+	return syms
+}
+
+// GoTo returns the next state of state on nonTerminal, a symbol number of a non-terminal symbol.
+func (p *Packed) GoTo(state, nonTerminal int) (int, bool) {
+	if state < 0 || state >= p.States || nonTerminal < p.Terminals || nonTerminal >= len(p.Symbols) {
+		return 0, false
+	}
+
+	col := nonTerminal - p.Terminals
+	next, ok := lookupRow(p.GoToRows, p.GoToBase, p.GoToCheck, p.GoToNext, state, col)
+	if !ok {
+		next = p.DefaultGoTos[col]
+	}
+	if next == 0 {
+		return 0, false
+	}
+
+	return int(next - 1), true
+}
+
+// Size returns the number of bytes occupied by the action and goto tables.
+func (p *Packed) Size() int {
+	n := 0
+	for _, a := range [][]int32{
+		p.DefaultReductions, p.DefaultReductionTerminals, p.ActionRows, p.ActionBase, p.ActionCheck, p.ActionNext,
+		p.DefaultGoTos, p.GoToRows, p.GoToBase, p.GoToCheck, p.GoToNext,
+	} {
+		n += len(a)
+	}
+
+	return 4 * n
+}
@@ -0,0 +1,556 @@
+// Package table provides a dense representation of a parsing table that can be saved in a compact binary format and
+// loaded at runtime without the grammar.
+package table
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/nihei9/sousa/grammar"
+)
+
+// SymbolEOF is the symbol number of the end of input. Terminal symbols follow it.
+const SymbolEOF = 0
+
+type SymbolKind uint8
+
+const (
+	SymbolKindEOF         = SymbolKind(0)
+	SymbolKindTerminal    = SymbolKind(1)
+	SymbolKindNonTerminal = SymbolKind(2)
+	SymbolKindStart       = SymbolKind(3)
+)
+
+func (k SymbolKind) String() string {
+	switch k {
+	case SymbolKindEOF:
+		return "eof"
+	case SymbolKindTerminal:
+		return "terminal"
+	case SymbolKindNonTerminal:
+		return "non-terminal"
+	case SymbolKindStart:
+		return "start"
+	}
+
+	return ""
+}
+
+// Symbol is an entry of the symbol table. The name of the end of input is empty.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+}
+
+// Production is an entry of the production table. LHS and RHS hold symbol numbers.
+type Production struct {
+	LHS int
+	RHS []int
+}
+
+// EntryPoint is the initial state to parse Symbol, a non-terminal symbol declared as a start symbol.
+type EntryPoint struct {
+	Symbol int
+	State  int
+}
+
+type ActionType int
+
+const (
+	ActionTypeError  = ActionType(0)
+	ActionTypeShift  = ActionType(1)
+	ActionTypeReduce = ActionType(2)
+	ActionTypeAccept = ActionType(3)
+)
+
+func (at ActionType) String() string {
+	switch at {
+	case ActionTypeError:
+		return "error"
+	case ActionTypeShift:
+		return "shift"
+	case ActionTypeReduce:
+		return "reduce"
+	case ActionTypeAccept:
+		return "accept"
+	}
+
+	return ""
+}
+
+// Action is a decoded action. N is the next state of a shift action or the production of a reduce action.
+type Action struct {
+	Type ActionType
+	N    int
+}
+
+// actionAccept is the encoded accept action. Other actions are encoded as follows.
+//
+// error:                 0
+// shift to state s:      s + 1
+// reduce by production p: -(p + 1)
+const actionAccept = math.MinInt32
+
+// ParsingTable is a parsing table indexed by integers. Table and Packed implement it.
+type ParsingTable interface {
+	// Action returns the action in state on terminal. The second value is the next state of a shift action or the
+	// production of a reduce action.
+	Action(state, terminal int) (ActionType, int)
+
+	// GoTo returns the next state of state on nonTerminal, a symbol number of a non-terminal symbol.
+	GoTo(state, nonTerminal int) (int, bool)
+
+	// ExpectedTerminals returns the terminal symbols, including the end of input, on which state has an action.
+	ExpectedTerminals(state int) []int
+
+	// LookupSymbol returns the symbol number of name.
+	LookupSymbol(name string) (int, bool)
+
+	// EntryPoint returns the initial state to parse the start symbol name.
+	EntryPoint(name string) (int, bool)
+
+	// Size returns the number of bytes occupied by the action and goto tables.
+	Size() int
+
+	// Write writes the table in the binary format.
+	Write(w io.Writer) error
+
+	// TableHeader returns the symbols, productions, and other properties shared by all layouts.
+	TableHeader() *Header
+}
+
+// Header holds the properties of a parsing table shared by Table and Packed.
+//
+// Symbols are numbered so that the end of input comes first, terminal symbols follow it, and non-terminal symbols
+// come last. Terminals is the number of the end of input and terminal symbols.
+type Header struct {
+	Symbols     []Symbol
+	Terminals   int
+	Productions []Production
+	States      int
+	EntryPoints []EntryPoint
+
+	// Conflicts holds the cells of the action table that have two or more actions, sorted by state and terminal.
+	Conflicts []Conflict
+}
+
+// Conflict holds all the encoded actions in State on Terminal. The action table holds the one of them that a
+// deterministic parser takes.
+type Conflict struct {
+	State    int
+	Terminal int
+	Actions  []int32
+}
+
+// Conflict returns all the actions in state on terminal when they conflict.
+func (h *Header) Conflict(state, terminal int) ([]Action, bool) {
+	i := sort.Search(len(h.Conflicts), func(i int) bool {
+		c := h.Conflicts[i]
+		return c.State > state || (c.State == state && c.Terminal >= terminal)
+	})
+	if i >= len(h.Conflicts) || h.Conflicts[i].State != state || h.Conflicts[i].Terminal != terminal {
+		return nil, false
+	}
+
+	acts := make([]Action, len(h.Conflicts[i].Actions))
+	for j, a := range h.Conflicts[i].Actions {
+		acts[j].Type, acts[j].N = decodeAction(a)
+	}
+	return acts, true
+}
+
+// TableHeader returns h itself.
+func (h *Header) TableHeader() *Header {
+	return h
+}
+
+// NonTerminals returns the number of non-terminal symbols.
+func (h *Header) NonTerminals() int {
+	return len(h.Symbols) - h.Terminals
+}
+
+// LookupSymbol returns the symbol number of name.
+func (h *Header) LookupSymbol(name string) (int, bool) {
+	for i, sym := range h.Symbols {
+		if sym.Kind != SymbolKindEOF && sym.Name == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// ErrorSymbol returns the symbol number of the reserved terminal symbol error. The second value is false when the
+// grammar doesn't use it.
+func (h *Header) ErrorSymbol() (int, bool) {
+	sym, ok := h.LookupSymbol(grammar.ErrorSymbolName)
+	if !ok || sym >= h.Terminals {
+		return 0, false
+	}
+
+	return sym, true
+}
+
+// EntryPoint returns the initial state to parse the start symbol name.
+func (h *Header) EntryPoint(name string) (int, bool) {
+	for _, e := range h.EntryPoints {
+		if h.Symbols[e.Symbol].Name == name {
+			return e.State, true
+		}
+	}
+
+	return 0, false
+}
+
+// Table is a parsing table indexed by integers.
+//
+// Actions is a States × Terminals matrix and GoTos is a States × NonTerminals() matrix, both stored in row-major
+// order. A goto is encoded as the next state + 1, and 0 means no goto.
+type Table struct {
+	Header
+	Actions []int32
+	GoTos   []int32
+}
+
+// New converts a parsing table into a Table. The entry points are ordered as augmentedStartSymbols.
+func New(st *grammar.SymbolTable, prods grammar.Productions, pt *grammar.ParsingTable, augmentedStartSymbols []grammar.SymbolID) (*Table, error) {
+	if st == nil || prods == nil || pt == nil {
+		return nil, fmt.Errorf("parameters passed contains nil")
+	}
+
+	t := &Table{
+		Header: Header{
+			Symbols: []Symbol{
+				{Kind: SymbolKindEOF},
+			},
+			Productions: []Production{},
+			States:      len(pt.States()),
+			EntryPoints: []EntryPoint{},
+			Conflicts:   []Conflict{},
+		},
+	}
+
+	symNums := map[grammar.SymbolID]int{}
+	syms := st.Symbols()
+	for _, nonTerminal := range []bool{false, true} {
+		for _, sym := range syms {
+			if sym.Kind().IsNonTerminalSymbol() != nonTerminal {
+				continue
+			}
+			name, ok := st.ToString(sym)
+			if !ok {
+				return nil, fmt.Errorf("failed to get the text of a symbol. symbol: %v", sym)
+			}
+			kind := SymbolKindTerminal
+			switch {
+			case sym.Kind().IsStartSymbol():
+				kind = SymbolKindStart
+			case sym.Kind().IsNonTerminalSymbol():
+				kind = SymbolKindNonTerminal
+			}
+			symNums[sym] = len(t.Symbols)
+			t.Symbols = append(t.Symbols, Symbol{
+				Name: name,
+				Kind: kind,
+			})
+		}
+		if !nonTerminal {
+			t.Terminals = len(t.Symbols)
+		}
+	}
+
+	all := []*grammar.Production{}
+	lhsOf := map[*grammar.Production]grammar.SymbolID{}
+	for lhs, ps := range prods.All() {
+		for _, p := range ps {
+			all = append(all, p)
+			lhsOf[p] = lhs
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID() < all[j].ID()
+	})
+	prodNums := map[grammar.ProductionFingerprint]int{}
+	for i, p := range all {
+		if int(p.ID()) != i {
+			return nil, fmt.Errorf("production IDs must be sequential from 0. production: %v", p)
+		}
+		rhs, _ := p.RHS()
+		prod := Production{
+			LHS: symNums[lhsOf[p]],
+			RHS: make([]int, len(rhs)),
+		}
+		for j, sym := range rhs {
+			num, ok := symNums[sym]
+			if !ok {
+				return nil, fmt.Errorf("a symbol was not found in the symbol table. symbol: %v", sym)
+			}
+			prod.RHS[j] = num
+		}
+		t.Productions = append(t.Productions, prod)
+		prodNums[p.Fingerprint()] = i
+	}
+
+	states := pt.States()
+	stateNum := func(kernelFp grammar.KernelFingerprint) (int, error) {
+		state, ok := states[kernelFp]
+		if !ok {
+			return 0, fmt.Errorf("failed to get a state. kernel fingerprint: %v", kernelFp)
+		}
+		return int(state), nil
+	}
+	prodNum := func(fp grammar.ProductionFingerprint) (int, error) {
+		num, ok := prodNums[fp]
+		if !ok {
+			return 0, fmt.Errorf("failed to get a production. fingerprint: %v", fp)
+		}
+		return num, nil
+	}
+
+	encodeAction := func(a *grammar.Action) (int32, error) {
+		switch a.Type() {
+		case grammar.ActionTypeShift:
+			next, err := stateNum(a.NextState())
+			if err != nil {
+				return 0, err
+			}
+			return encodeShift(next), nil
+		case grammar.ActionTypeReduce:
+			prod, err := prodNum(a.Production())
+			if err != nil {
+				return 0, err
+			}
+			return encodeReduce(prod), nil
+		}
+		return 0, fmt.Errorf("unknown action type. got: %v", a.Type())
+	}
+
+	t.Actions = make([]int32, t.States*t.Terminals)
+	for kernelFp, actions := range pt.Action() {
+		state, err := stateNum(kernelFp)
+		if err != nil {
+			return nil, err
+		}
+		row := t.Actions[state*t.Terminals : (state+1)*t.Terminals]
+
+		if actions.Acceptable() {
+			row[SymbolEOF] = actionAccept
+		}
+		if prodFp, reducible := actions.ReduceByEOF(); reducible {
+			prod, err := prodNum(prodFp)
+			if err != nil {
+				return nil, err
+			}
+			row[SymbolEOF] = encodeReduce(prod)
+		}
+		if actions.ConflictByEOF() {
+			c := Conflict{
+				State:    state,
+				Terminal: SymbolEOF,
+				Actions:  []int32{},
+			}
+			if actions.Acceptable() {
+				c.Actions = append(c.Actions, actionAccept)
+			}
+			for _, prodFp := range actions.ReducesByEOF() {
+				prod, err := prodNum(prodFp)
+				if err != nil {
+					return nil, err
+				}
+				c.Actions = append(c.Actions, encodeReduce(prod))
+			}
+			t.Conflicts = append(t.Conflicts, c)
+		}
+		for sym, a := range actions.Actions() {
+			act, err := encodeAction(a)
+			if err != nil {
+				return nil, err
+			}
+			row[symNums[sym]] = act
+		}
+		for sym, as := range actions.Conflicts() {
+			c := Conflict{
+				State:    state,
+				Terminal: symNums[sym],
+				Actions:  make([]int32, len(as)),
+			}
+			for i, a := range as {
+				c.Actions[i], err = encodeAction(a)
+				if err != nil {
+					return nil, err
+				}
+			}
+			t.Conflicts = append(t.Conflicts, c)
+		}
+	}
+	sort.Slice(t.Conflicts, func(i, j int) bool {
+		ci, cj := t.Conflicts[i], t.Conflicts[j]
+		if ci.State != cj.State {
+			return ci.State < cj.State
+		}
+		return ci.Terminal < cj.Terminal
+	})
+
+	nonTerminals := t.NonTerminals()
+	t.GoTos = make([]int32, t.States*nonTerminals)
+	for kernelFp, goTos := range pt.GoTo() {
+		state, err := stateNum(kernelFp)
+		if err != nil {
+			return nil, err
+		}
+		for sym, nextKernelFp := range goTos {
+			next, err := stateNum(nextKernelFp)
+			if err != nil {
+				return nil, err
+			}
+			t.GoTos[state*nonTerminals+symNums[sym]-t.Terminals] = int32(next + 1)
+		}
+	}
+
+	for _, sym := range augmentedStartSymbols {
+		kernelFp, ok := pt.InitialStates()[sym]
+		if !ok {
+			return nil, fmt.Errorf("failed to get an initial state. symbol: %v", sym)
+		}
+		state, err := stateNum(kernelFp)
+		if err != nil {
+			return nil, err
+		}
+		ps := prods.Get(sym)
+		if len(ps) != 1 {
+			return nil, fmt.Errorf("an augmented start symbol must have just one production. symbol: %v", sym)
+		}
+		rhs, _ := ps[0].RHS()
+		if len(rhs) != 1 {
+			return nil, fmt.Errorf("a production of an augmented start symbol must have just one symbol. production: %v", ps[0])
+		}
+		t.EntryPoints = append(t.EntryPoints, EntryPoint{
+			Symbol: symNums[rhs[0]],
+			State:  state,
+		})
+	}
+
+	return t, nil
+}
+
+func encodeShift(state int) int32 {
+	return int32(state + 1)
+}
+
+func encodeReduce(prod int) int32 {
+	return int32(-(prod + 1))
+}
+
+func decodeAction(a int32) (ActionType, int) {
+	switch {
+	case a == actionAccept:
+		return ActionTypeAccept, 0
+	case a > 0:
+		return ActionTypeShift, int(a - 1)
+	case a < 0:
+		return ActionTypeReduce, int(-a - 1)
+	}
+
+	return ActionTypeError, 0
+}
+
+// Action returns the action in state on terminal. The second value is the next state of a shift action or the
+// production of a reduce action.
+func (t *Table) Action(state, terminal int) (ActionType, int) {
+	if state < 0 || state >= t.States || terminal < 0 || terminal >= t.Terminals {
+		return ActionTypeError, 0
+	}
+
+	return decodeAction(t.Actions[state*t.Terminals+terminal])
+}
+
+// GoTo returns the next state of state on nonTerminal, a symbol number of a non-terminal symbol.
+func (t *Table) GoTo(state, nonTerminal int) (int, bool) {
+	nonTerminals := t.NonTerminals()
+	if state < 0 || state >= t.States || nonTerminal < t.Terminals || nonTerminal >= len(t.Symbols) {
+		return 0, false
+	}
+
+	next := t.GoTos[state*nonTerminals+nonTerminal-t.Terminals]
+	if next == 0 {
+		return 0, false
+	}
+
+	return int(next - 1), true
+}
+
+// ExpectedTerminals returns the terminal symbols, including the end of input, on which state has an action.
+func (t *Table) ExpectedTerminals(state int) []int {
+	syms := []int{}
+	for sym := 0; sym < t.Terminals; sym++ {
+		if act, _ := t.Action(state, sym); act != ActionTypeError {
+			syms = append(syms, sym)
+		}
+	}
+
+	return syms
+}
+
+// AllActions returns all the actions in state on terminal. It returns more than one action when they conflict and
+// none when the action is an error, so a GLR parser can take every action it returns.
+func AllActions(t ParsingTable, state, terminal int) []Action {
+	if acts, ok := t.TableHeader().Conflict(state, terminal); ok {
+		return acts
+	}
+	act, n := t.Action(state, terminal)
+	if act == ActionTypeError {
+		return []Action{}
+	}
+
+	return []Action{
+		{Type: act, N: n},
+	}
+}
+
+// ExpectedTerminals returns the terminal symbols that a parser whose state stack is states can shift or accept next.
+// Unlike the ExpectedTerminals method of t, it follows the reductions, including default reductions of a Packed, that
+// the parser would take before it shifts a terminal symbol. The terminal symbol error is excluded.
+func ExpectedTerminals(t ParsingTable, states []int) []int {
+	errSym, recoverable := t.TableHeader().ErrorSymbol()
+	expected := []int{}
+	for _, sym := range t.ExpectedTerminals(states[len(states)-1]) {
+		if recoverable && sym == errSym {
+			continue
+		}
+		if acceptable(t, states, sym) {
+			expected = append(expected, sym)
+		}
+	}
+
+	return expected
+}
+
+// acceptable reports whether a parser whose state stack is states shifts or accepts sym after reductions.
+func acceptable(t ParsingTable, states []int, sym int) bool {
+	prods := t.TableHeader().Productions
+	stack := append([]int{}, states...)
+	for {
+		act, n := t.Action(stack[len(stack)-1], sym)
+		switch act {
+		case ActionTypeShift, ActionTypeAccept:
+			return true
+		case ActionTypeReduce:
+			prod := prods[n]
+			stack = stack[:len(stack)-len(prod.RHS)]
+			next, ok := t.GoTo(stack[len(stack)-1], prod.LHS)
+			if !ok {
+				return false
+			}
+			stack = append(stack, next)
+		default:
+			return false
+		}
+	}
+}
+
+// Size returns the number of bytes occupied by the action and goto tables.
+func (t *Table) Size() int {
+	return 4 * (len(t.Actions) + len(t.GoTos))
+}
@@ -0,0 +1,353 @@
+package table
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+)
+
+type generated struct {
+	g  *ast2grammar.Grammar
+	pt *grammar.ParsingTable
+}
+
+func generate(t *testing.T, src string) *generated {
+	t.Helper()
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := grammar.GenerateFirstSets(g.Productions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := grammar.GenerateFollowSets(g.Productions, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := grammar.GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbols...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := grammar.GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &generated{
+		g:  g,
+		pt: pt,
+	}
+}
+
+func (gen *generated) table(t *testing.T) *Table {
+	t.Helper()
+
+	tab, err := New(gen.g.SymbolTable, gen.g.Productions, gen.pt, gen.g.AugmentedStartSymbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tab
+}
+
+func TestTable_RoundTrip(t *testing.T) {
+	tests := map[string]string{
+		"arithmetic expressions": `E: E "+" T | T; T: T "*" F | F; F: "(" E ")" | id;`,
+		"multiple entry points":  `%start stmt expr; stmt: expr ";" | ; expr: expr "+" id | id;`,
+		"conflicts":              `S: E | A; E: E "+" E | E "*" E | id; A: id;`,
+	}
+	for caption, src := range tests {
+		t.Run(caption, func(t *testing.T) {
+			gen := generate(t, src)
+			orig := gen.table(t)
+
+			var b bytes.Buffer
+			err := orig.Write(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsBinary(b.Bytes()) {
+				t.Fatal("the written table must be recognized as the binary format")
+			}
+			loaded, err := Read(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, orig) {
+				t.Fatalf("the loaded table differs from the original one\nwant: %+v\ngot: %+v", orig, loaded)
+			}
+			testBehavior(t, gen, loaded)
+
+			packed := Pack(orig)
+			testBehavior(t, gen, packed)
+
+			b.Reset()
+			err = packed.Write(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err = Read(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, packed) {
+				t.Fatalf("the loaded table differs from the original one\nwant: %+v\ngot: %+v", packed, loaded)
+			}
+			testBehavior(t, gen, loaded)
+		})
+	}
+}
+
+func TestPack(t *testing.T) {
+	gen := generate(t, `
+stmts: stmts stmt | stmt;
+stmt: id "=" expr ";" | "print" expr ";" | "{" stmts "}";
+expr: expr "+" term | expr "-" term | term;
+term: term "*" factor | term "/" factor | factor;
+factor: "(" expr ")" | id | num;
+`)
+	tab := gen.table(t)
+	packed := Pack(tab)
+
+	if packed.Size() >= tab.Size() {
+		t.Fatalf("the packed table is not smaller than the original one\noriginal: %v bytes\npacked: %v bytes", tab.Size(), packed.Size())
+	}
+
+	// The reduction by factor → id is the default in the state after id, so the state has no explicit actions.
+	factorID := -1
+	for i, prod := range tab.Productions {
+		if tab.Symbols[prod.LHS].Name == "factor" && len(prod.RHS) == 1 && tab.Symbols[prod.RHS[0]].Name == "id" {
+			factorID = i
+		}
+	}
+	for state := 0; state < tab.States; state++ {
+		if packed.DefaultReductions[state] != encodeReduce(factorID) {
+			continue
+		}
+		if _, ok := lookupRow(packed.ActionRows, packed.ActionBase, packed.ActionCheck, packed.ActionNext, state, SymbolEOF); ok {
+			t.Fatalf("the default reduction remains in the row of state %v", state)
+		}
+		return
+	}
+	t.Fatal("factor → id is not a default reduction")
+}
+
+func actionString(typ ActionType, n int) string {
+	switch typ {
+	case ActionTypeShift, ActionTypeReduce:
+		return typ.String() + " " + grammar.ProductionID(n).String()
+	case ActionTypeError:
+		return ""
+	}
+	return typ.String()
+}
+
+// testBehavior checks that tab returns the same actions and gotos as the parsing table it was converted from.
+// A packed table may return a default reduction or a default goto instead of an error.
+func testBehavior(t *testing.T, gen *generated, tab ParsingTable) {
+	t.Helper()
+
+	_, packed := tab.(*Packed)
+	h := tab.TableHeader()
+
+	st := gen.g.SymbolTable
+	symNum := func(sym grammar.SymbolID) int {
+		name, _ := st.ToString(sym)
+		num, ok := tab.LookupSymbol(name)
+		if !ok {
+			t.Fatalf("a symbol was not found: %v", name)
+		}
+		return num
+	}
+
+	states := gen.pt.States()
+	if h.States != len(states) {
+		t.Fatalf("unexpected number of states\nwant: %v\ngot: %v", len(states), h.States)
+	}
+	for kernelFp, state := range states {
+		s := int(state)
+		actions := gen.pt.Action()[kernelFp]
+
+		expected := map[int]string{}
+		if actions != nil {
+			if actions.Acceptable() {
+				expected[SymbolEOF] = "accept"
+			}
+			if prodFp, ok := actions.ReduceByEOF(); ok {
+				expected[SymbolEOF] = "reduce " + gen.g.Productions.LookupByFingerprint(prodFp).ID().String()
+			}
+			for sym, a := range actions.Actions() {
+				switch a.Type() {
+				case grammar.ActionTypeShift:
+					expected[symNum(sym)] = "shift " + states[a.NextState()].String()
+				case grammar.ActionTypeReduce:
+					expected[symNum(sym)] = "reduce " + gen.g.Productions.LookupByFingerprint(a.Production()).ID().String()
+				}
+			}
+		}
+		conflicts := map[int][]string{}
+		if actions != nil {
+			if actions.ConflictByEOF() {
+				if actions.Acceptable() {
+					conflicts[SymbolEOF] = append(conflicts[SymbolEOF], "accept")
+				}
+				for _, prodFp := range actions.ReducesByEOF() {
+					conflicts[SymbolEOF] = append(conflicts[SymbolEOF], "reduce "+gen.g.Productions.LookupByFingerprint(prodFp).ID().String())
+				}
+			}
+			for sym, as := range actions.Conflicts() {
+				for _, a := range as {
+					switch a.Type() {
+					case grammar.ActionTypeShift:
+						conflicts[symNum(sym)] = append(conflicts[symNum(sym)], "shift "+states[a.NextState()].String())
+					case grammar.ActionTypeReduce:
+						conflicts[symNum(sym)] = append(conflicts[symNum(sym)], "reduce "+gen.g.Productions.LookupByFingerprint(a.Production()).ID().String())
+					}
+				}
+			}
+		}
+		for term := 0; term < h.Terminals; term++ {
+			if expectedActs, ok := conflicts[term]; ok {
+				gotActs := []string{}
+				for _, a := range AllActions(tab, s, term) {
+					gotActs = append(gotActs, actionString(a.Type, a.N))
+				}
+				if !reflect.DeepEqual(gotActs, expectedActs) {
+					t.Fatalf("unexpected conflicting actions in state %v on %v\nwant: %v\ngot: %v", s, h.Symbols[term].Name, expectedActs, gotActs)
+				}
+			}
+
+			typ, n := tab.Action(s, term)
+			got := actionString(typ, n)
+			if packed && expected[term] == "" && typ == ActionTypeReduce {
+				continue
+			}
+			if got != expected[term] {
+				t.Fatalf("unexpected action in state %v on %v\nwant: %v\ngot: %v", s, h.Symbols[term].Name, expected[term], got)
+			}
+		}
+
+		// Default reductions of a packed table must not change the expected terminals.
+		expectedTerms := []int{}
+		syms, eof := gen.pt.ExpectedTerminals(kernelFp)
+		if eof {
+			expectedTerms = append(expectedTerms, SymbolEOF)
+		}
+		for _, sym := range syms {
+			expectedTerms = append(expectedTerms, symNum(sym))
+		}
+		sort.Ints(expectedTerms)
+		if terms := tab.ExpectedTerminals(s); !reflect.DeepEqual(terms, expectedTerms) {
+			t.Fatalf("unexpected expected terminals in state %v\nwant: %v\ngot: %v", s, expectedTerms, terms)
+		}
+
+		goTos := gen.pt.GoTo()[kernelFp]
+		for nonTerm := h.Terminals; nonTerm < len(h.Symbols); nonTerm++ {
+			next, ok := tab.GoTo(s, nonTerm)
+			expectedNext, expectedOK := goTos[st.LookupByString(h.Symbols[nonTerm].Name)]
+			if packed && !expectedOK {
+				continue
+			}
+			if ok != expectedOK || (ok && grammar.StateID(next) != states[expectedNext]) {
+				t.Fatalf("unexpected goto in state %v on %v\nwant: %v %v\ngot: %v %v", s, h.Symbols[nonTerm].Name, states[expectedNext], expectedOK, next, ok)
+			}
+		}
+	}
+
+	for _, sym := range gen.g.AugmentedStartSymbols {
+		rhs, _ := gen.g.Productions.Get(sym)[0].RHS()
+		name, _ := st.ToString(rhs[0])
+		state, ok := tab.EntryPoint(name)
+		if !ok {
+			t.Fatalf("an entry point was not found: %v", name)
+		}
+		if grammar.StateID(state) != states[gen.pt.InitialStates()[sym]] {
+			t.Fatalf("unexpected entry point of %v\nwant: %v\ngot: %v", name, states[gen.pt.InitialStates()[sym]], state)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	gen := generate(t, `E: E "+" T | T; T: id;`)
+	orig := gen.table(t)
+
+	dir, err := ioutil.TempDir("", "sousa-table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sousa.tbl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = orig.Write(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBehavior(t, gen, loaded)
+}
+
+func TestRead_Error(t *testing.T) {
+	gen := generate(t, `E: E "+" T | T; T: id;`)
+	var b bytes.Buffer
+	err := gen.table(t).Write(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := b.Bytes()
+
+	tests := map[string][]byte{
+		"empty input": {},
+		"wrong magic": append([]byte("NOTATBL!"), valid[len(magic):]...),
+		"unsupported version": func() []byte {
+			bs := append([]byte{}, valid...)
+			binary.LittleEndian.PutUint32(bs[len(magic):], FormatVersion+1)
+			return bs
+		}(),
+		"truncated input": valid[:len(valid)-1],
+		"unknown layout": func() []byte {
+			bs := append([]byte{}, valid...)
+			bs[len(magic)+4] = 0xff
+			return bs
+		}(),
+		"huge count": func() []byte {
+			bs := append([]byte{}, valid[:len(magic)+5]...)
+			return append(bs, 0xff, 0xff, 0xff, 0xff)
+		}(),
+	}
+	for caption, input := range tests {
+		t.Run(caption, func(t *testing.T) {
+			_, err := Read(bytes.NewReader(input))
+			if err == nil {
+				t.Fatal("an error was not returned")
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package writer
+
+import (
+	"io"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/table"
+)
+
+type binaryWriter struct {
+	grammar      *ast2grammar.Grammar
+	parsingTable *grammar.ParsingTable
+	compress     bool
+}
+
+// NewBinaryWriter returns a Writer that writes the parsing table in the binary format of the table package.
+// When compress is true, the table is written in the packed layout.
+func NewBinaryWriter(g *ast2grammar.Grammar, parsingTable *grammar.ParsingTable, compress bool) Writer {
+	return &binaryWriter{
+		grammar:      g,
+		parsingTable: parsingTable,
+		compress:     compress,
+	}
+}
+
+func (bw *binaryWriter) Write(w io.Writer) error {
+	t, err := table.New(bw.grammar.SymbolTable, bw.grammar.Productions, bw.parsingTable, bw.grammar.AugmentedStartSymbols)
+	if err != nil {
+		return err
+	}
+	if bw.compress {
+		return table.Pack(t).Write(w)
+	}
+
+	return t.Write(w)
+}
@@ -0,0 +1,205 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/table"
+)
+
+// GoOptions configures the Go code generator.
+type GoOptions struct {
+	// Package is the package name of the generated code.
+	Package string
+
+	// Compress makes the generated code hold a table.Packed instead of a table.Table.
+	Compress bool
+
+	// Stamp identifies the inputs the code is generated from. When it isn't empty, it is written in a comment following
+	// the header, and ReadGoStamp reads it back so that a generator can find whether the code is up to date.
+	Stamp string
+}
+
+const (
+	goHeader      = "// Code generated by sousa. DO NOT EDIT.\n"
+	goStampPrefix = "// sousa:stamp "
+)
+
+// IsGoGenerated reports whether src is Go code that sousa generated.
+func IsGoGenerated(src []byte) bool {
+	return bytes.HasPrefix(src, []byte(goHeader))
+}
+
+// ReadGoStamp returns the stamp written in Go code that sousa generated, or an empty string when the code has no stamp.
+func ReadGoStamp(src []byte) string {
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if strings.HasPrefix(line, goStampPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, goStampPrefix))
+		}
+	}
+	return ""
+}
+
+// writeGoFileHeader writes the header marking code as generated and the stamp.
+func writeGoFileHeader(buf *bytes.Buffer, opts GoOptions) {
+	fmt.Fprintf(buf, "%s", goHeader)
+	if opts.Stamp != "" {
+		fmt.Fprintf(buf, "%s%s\n", goStampPrefix, opts.Stamp)
+	}
+	fmt.Fprintf(buf, "\n")
+}
+
+type goWriter struct {
+	grammar      *ast2grammar.Grammar
+	parsingTable *grammar.ParsingTable
+	opts         GoOptions
+}
+
+// NewGoWriter returns a Writer that writes Go source code defining the parsing table as a variable ParsingTable.
+// The generated code depends on the table package.
+func NewGoWriter(g *ast2grammar.Grammar, parsingTable *grammar.ParsingTable, opts GoOptions) Writer {
+	return &goWriter{
+		grammar:      g,
+		parsingTable: parsingTable,
+		opts:         opts,
+	}
+}
+
+func (gw *goWriter) Write(w io.Writer) error {
+	if gw.opts.Package == "" {
+		return fmt.Errorf("package name is empty")
+	}
+
+	t, err := table.New(gw.grammar.SymbolTable, gw.grammar.Productions, gw.parsingTable, gw.grammar.AugmentedStartSymbols)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	writeGoFileHeader(buf, gw.opts)
+	fmt.Fprintf(buf, "package %s\n\n", gw.opts.Package)
+	fmt.Fprintf(buf, "import \"github.com/nihei9/sousa/table\"\n\n")
+	fmt.Fprintf(buf, "// ParsingTable is the parsing table of the grammar.\n")
+	if gw.opts.Compress {
+		p := table.Pack(t)
+		fmt.Fprintf(buf, "var ParsingTable table.ParsingTable = &table.Packed{\n")
+		writeGoHeader(buf, &p.Header)
+		writeGoInt32s(buf, "DefaultReductions", p.DefaultReductions)
+		writeGoInt32s(buf, "DefaultReductionTerminals", p.DefaultReductionTerminals)
+		writeGoInt32s(buf, "ActionRows", p.ActionRows)
+		writeGoInt32s(buf, "ActionBase", p.ActionBase)
+		writeGoInt32s(buf, "ActionCheck", p.ActionCheck)
+		writeGoInt32s(buf, "ActionNext", p.ActionNext)
+		writeGoInt32s(buf, "DefaultGoTos", p.DefaultGoTos)
+		writeGoInt32s(buf, "GoToRows", p.GoToRows)
+		writeGoInt32s(buf, "GoToBase", p.GoToBase)
+		writeGoInt32s(buf, "GoToCheck", p.GoToCheck)
+		writeGoInt32s(buf, "GoToNext", p.GoToNext)
+	} else {
+		fmt.Fprintf(buf, "var ParsingTable table.ParsingTable = &table.Table{\n")
+		writeGoHeader(buf, &t.Header)
+		writeGoInt32s(buf, "Actions", t.Actions)
+		writeGoInt32s(buf, "GoTos", t.GoTos)
+	}
+	fmt.Fprintf(buf, "}\n\n")
+	fmt.Fprintf(buf, "%s", goExpectedTerminals)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+const goExpectedTerminals = `// ExpectedTerminals returns the names of the terminal symbols that a parser whose state stack is states can shift or
+// accept next. Like the parser of the driver package, it follows reductions and excludes the terminal symbol error.
+// The end of input is named EOF.
+func ExpectedTerminals(states []int) []string {
+	h := ParsingTable.TableHeader()
+	names := []string{}
+	for _, sym := range table.ExpectedTerminals(ParsingTable, states) {
+		if sym == table.SymbolEOF {
+			names = append(names, "EOF")
+			continue
+		}
+		names = append(names, h.Symbols[sym].Name)
+	}
+	return names
+}
+`
+
+func writeGoHeader(buf *bytes.Buffer, h *table.Header) {
+	kinds := map[table.SymbolKind]string{
+		table.SymbolKindEOF:         "table.SymbolKindEOF",
+		table.SymbolKindTerminal:    "table.SymbolKindTerminal",
+		table.SymbolKindNonTerminal: "table.SymbolKindNonTerminal",
+		table.SymbolKindStart:       "table.SymbolKindStart",
+	}
+
+	fmt.Fprintf(buf, "Header: table.Header{\n")
+	fmt.Fprintf(buf, "Symbols: []table.Symbol{\n")
+	for i, sym := range h.Symbols {
+		fmt.Fprintf(buf, "{Name: %s, Kind: %s}, // %v\n", strconv.Quote(sym.Name), kinds[sym.Kind], i)
+	}
+	fmt.Fprintf(buf, "},\n")
+	fmt.Fprintf(buf, "Terminals: %v,\n", h.Terminals)
+	fmt.Fprintf(buf, "Productions: []table.Production{\n")
+	for i, prod := range h.Productions {
+		fmt.Fprintf(buf, "{LHS: %v, RHS: []int{", prod.LHS)
+		for j, sym := range prod.RHS {
+			if j > 0 {
+				fmt.Fprintf(buf, ", ")
+			}
+			fmt.Fprintf(buf, "%v", sym)
+		}
+		fmt.Fprintf(buf, "}}, // %v\n", i)
+	}
+	fmt.Fprintf(buf, "},\n")
+	fmt.Fprintf(buf, "States: %v,\n", h.States)
+	fmt.Fprintf(buf, "EntryPoints: []table.EntryPoint{\n")
+	for _, ep := range h.EntryPoints {
+		fmt.Fprintf(buf, "{Symbol: %v, State: %v},\n", ep.Symbol, ep.State)
+	}
+	fmt.Fprintf(buf, "},\n")
+	if len(h.Conflicts) > 0 {
+		fmt.Fprintf(buf, "Conflicts: []table.Conflict{\n")
+		for _, c := range h.Conflicts {
+			fmt.Fprintf(buf, "{State: %v, Terminal: %v, Actions: []int32{", c.State, c.Terminal)
+			for i, a := range c.Actions {
+				if i > 0 {
+					fmt.Fprintf(buf, ", ")
+				}
+				fmt.Fprintf(buf, "%v", a)
+			}
+			fmt.Fprintf(buf, "}},\n")
+		}
+		fmt.Fprintf(buf, "},\n")
+	}
+	fmt.Fprintf(buf, "},\n")
+}
+
+func writeGoInt32s(buf *bytes.Buffer, field string, vs []int32) {
+	const perLine = 16
+
+	fmt.Fprintf(buf, "%s: []int32{", field)
+	for i, v := range vs {
+		if i%perLine == 0 {
+			fmt.Fprintf(buf, "\n")
+		}
+		fmt.Fprintf(buf, "%v, ", v)
+	}
+	if len(vs) > 0 {
+		fmt.Fprintf(buf, "\n")
+	}
+	fmt.Fprintf(buf, "},\n")
+}
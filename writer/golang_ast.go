@@ -0,0 +1,445 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+)
+
+// reservedGoASTNames holds the names that the generated code declares regardless of the grammar.
+var reservedGoASTNames = map[string]struct{}{
+	"Node":    {},
+	"Visitor": {},
+	"Walk":    {},
+	"Reduce":  {},
+	"Build":   {},
+}
+
+// reservedGoASTFields holds the names of the methods of a generated struct, which its fields cannot have.
+var reservedGoASTFields = map[string]struct{}{
+	"Children": {},
+	"Accept":   {},
+}
+
+type goASTWriter struct {
+	grammar *ast2grammar.Grammar
+	opts    GoOptions
+}
+
+// NewGoASTWriter returns a Writer that writes Go source code defining typed AST nodes of a grammar. A non-terminal
+// symbol having one alternative becomes a struct, and one having several alternatives becomes an interface
+// implemented by a struct per alternative. A struct is named after the label of its alternative, or after the
+// non-terminal symbol followed by the position of the alternative, and has a field per RHS symbol named after its
+// label or the symbol. The generated code also defines a Visitor, Walk, and Reduce and Build constructing nodes on
+// reductions and from a parse tree of the driver package.
+func NewGoASTWriter(g *ast2grammar.Grammar, opts GoOptions) Writer {
+	return &goASTWriter{
+		grammar: g,
+		opts:    opts,
+	}
+}
+
+// goASTRule is a non-terminal symbol and the Go types of its alternatives.
+type goASTRule struct {
+	lhs  string
+	name string
+	alts []*goASTAlt
+}
+
+// typeExpr returns the Go type of a field holding a node of the rule.
+func (r *goASTRule) typeExpr() string {
+	if len(r.alts) == 1 {
+		return "*" + r.alts[0].name
+	}
+	return r.name
+}
+
+// goASTAlt is a production and the Go struct of it.
+type goASTAlt struct {
+	prod   *grammar.Production
+	name   string
+	text   string
+	fields []*goASTField
+}
+
+type goASTField struct {
+	name string
+	typ  string
+	// node reports whether the field holds a node of a non-terminal symbol.
+	node bool
+}
+
+func (aw *goASTWriter) Write(w io.Writer) error {
+	if aw.opts.Package == "" {
+		return fmt.Errorf("package name is empty")
+	}
+
+	rules, err := aw.rules()
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	writeGoFileHeader(buf, aw.opts)
+	fmt.Fprintf(buf, "package %s\n\n", aw.opts.Package)
+	fmt.Fprintf(buf, "import (\n\"fmt\"\n\n\"github.com/nihei9/sousa/driver\"\n)\n\n")
+	fmt.Fprintf(buf, "%s\n", goASTNode)
+	for _, r := range rules {
+		if len(r.alts) > 1 {
+			fmt.Fprintf(buf, "// %s is a node of %s.\n", r.name, r.lhs)
+			fmt.Fprintf(buf, "type %s interface {\nNode\nis%s()\n}\n\n", r.name, r.name)
+		}
+		for _, alt := range r.alts {
+			writeGoASTAlt(buf, r, alt)
+		}
+	}
+
+	fmt.Fprintf(buf, "// Visitor has a method visiting each type of node.\n")
+	fmt.Fprintf(buf, "type Visitor interface {\n")
+	for _, r := range rules {
+		for _, alt := range r.alts {
+			fmt.Fprintf(buf, "Visit%s(n *%s) error\n", alt.name, alt.name)
+		}
+	}
+	fmt.Fprintf(buf, "}\n\n")
+	fmt.Fprintf(buf, "%s\n", goASTWalk)
+
+	fmt.Fprintf(buf, "%s", goASTReduceDoc)
+	fmt.Fprintf(buf, "func Reduce(prod int, children []interface{}) (Node, error) {\n")
+	fmt.Fprintf(buf, "switch prod {\n")
+	for _, r := range rules {
+		for _, alt := range r.alts {
+			writeGoASTReduction(buf, alt)
+		}
+	}
+	fmt.Fprintf(buf, "}\n")
+	fmt.Fprintf(buf, "return nil, fmt.Errorf(\"unknown production: %%v\", prod)\n")
+	fmt.Fprintf(buf, "}\n\n")
+	fmt.Fprintf(buf, "%s", goASTBuild)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func writeGoASTAlt(buf *bytes.Buffer, r *goASTRule, alt *goASTAlt) {
+	fmt.Fprintf(buf, "// %s is a node of %s.\n", alt.name, alt.text)
+	fmt.Fprintf(buf, "type %s struct {\n", alt.name)
+	for _, f := range alt.fields {
+		fmt.Fprintf(buf, "%s %s\n", f.name, f.typ)
+	}
+	fmt.Fprintf(buf, "}\n\n")
+
+	if len(r.alts) > 1 {
+		fmt.Fprintf(buf, "func (*%s) is%s() {}\n\n", alt.name, r.name)
+	}
+
+	fmt.Fprintf(buf, "// Children returns the nodes of the non-terminal symbols of n in order.\n")
+	fmt.Fprintf(buf, "func (n *%s) Children() []Node {\n", alt.name)
+	fmt.Fprintf(buf, "children := []Node{}\n")
+	for _, f := range alt.fields {
+		if !f.node {
+			continue
+		}
+		fmt.Fprintf(buf, "if n.%s != nil {\nchildren = append(children, n.%s)\n}\n", f.name, f.name)
+	}
+	fmt.Fprintf(buf, "return children\n")
+	fmt.Fprintf(buf, "}\n\n")
+
+	fmt.Fprintf(buf, "// Accept calls v.Visit%s with n.\n", alt.name)
+	fmt.Fprintf(buf, "func (n *%s) Accept(v Visitor) error {\nreturn v.Visit%s(n)\n}\n\n", alt.name, alt.name)
+}
+
+func writeGoASTReduction(buf *bytes.Buffer, alt *goASTAlt) {
+	fmt.Fprintf(buf, "case %v: // %s\n", alt.prod.ID(), alt.text)
+	fmt.Fprintf(buf, "if len(children) != %v {\n", len(alt.fields))
+	fmt.Fprintf(buf, "return nil, fmt.Errorf(\"production %%v takes %v children; got: %%v\", prod, len(children))\n", len(alt.fields))
+	fmt.Fprintf(buf, "}\n")
+	fmt.Fprintf(buf, "n := &%s{}\n", alt.name)
+	if len(alt.fields) > 0 {
+		fmt.Fprintf(buf, "var ok bool\n")
+	}
+	for i, f := range alt.fields {
+		fmt.Fprintf(buf, "if n.%s, ok = children[%v].(%s); !ok {\n", f.name, i, f.typ)
+		fmt.Fprintf(buf, "return nil, fmt.Errorf(\"child %v of production %%v must be %s; got: %%T\", prod, children[%v])\n", i, f.typ, i)
+		fmt.Fprintf(buf, "}\n")
+	}
+	fmt.Fprintf(buf, "return n, nil\n")
+}
+
+// rules returns the non-terminal symbols of the grammar except augmented start symbols in order of appearance.
+func (aw *goASTWriter) rules() ([]*goASTRule, error) {
+	g := aw.grammar
+
+	prods := []*grammar.Production{}
+	for _, ps := range g.Productions.All() {
+		for _, prod := range ps {
+			if prod.LHS().Kind().IsStartSymbol() {
+				continue
+			}
+			prods = append(prods, prod)
+		}
+	}
+	sort.Slice(prods, func(i, j int) bool {
+		return prods[i].ID() < prods[j].ID()
+	})
+
+	names := map[string]string{}
+	for name := range reservedGoASTNames {
+		names[name] = "the generated code"
+	}
+	declare := func(name, owner string) error {
+		if prev, ok := names[name]; ok {
+			return fmt.Errorf("the Go type %v of %v conflicts with %v", name, owner, prev)
+		}
+		names[name] = owner
+		return nil
+	}
+
+	rules := []*goASTRule{}
+	lhs2Rule := map[grammar.SymbolID]*goASTRule{}
+	for _, prod := range prods {
+		r, ok := lhs2Rule[prod.LHS()]
+		if !ok {
+			lhs, _ := g.SymbolTable.ToString(prod.LHS())
+			name := goASTName(lhs)
+			if name == "" {
+				return nil, fmt.Errorf("%v cannot be the name of a Go type", lhs)
+			}
+			r = &goASTRule{
+				lhs:  lhs,
+				name: name,
+			}
+			rules = append(rules, r)
+			lhs2Rule[prod.LHS()] = r
+		}
+		r.alts = append(r.alts, &goASTAlt{
+			prod: prod,
+		})
+	}
+
+	for _, r := range rules {
+		if len(r.alts) > 1 {
+			err := declare(r.name, r.lhs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i, alt := range r.alts {
+			alt.text = aw.productionText(alt.prod)
+			switch {
+			case g.Labels[alt.prod.ID()] != nil && g.Labels[alt.prod.ID()].Alternative != "":
+				alt.name = goASTName(g.Labels[alt.prod.ID()].Alternative)
+			case len(r.alts) == 1:
+				alt.name = r.name
+			default:
+				alt.name = fmt.Sprintf("%s%v", r.name, i+1)
+			}
+			if alt.name == "" {
+				return nil, fmt.Errorf("the label of %v cannot be the name of a Go type", alt.text)
+			}
+			err := declare(alt.name, alt.text)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, r := range rules {
+		for _, alt := range r.alts {
+			fields, err := aw.fields(alt, lhs2Rule)
+			if err != nil {
+				return nil, err
+			}
+			alt.fields = fields
+		}
+	}
+
+	return rules, nil
+}
+
+// fields returns the fields of the struct of an alternative. An unlabeled symbol appearing several times in the
+// alternative is numbered, such as Expr1 and Expr2.
+func (aw *goASTWriter) fields(alt *goASTAlt, lhs2Rule map[grammar.SymbolID]*goASTRule) ([]*goASTField, error) {
+	g := aw.grammar
+
+	var labels []string
+	if l, ok := g.Labels[alt.prod.ID()]; ok {
+		labels = l.Fields
+	}
+
+	rhs, _ := alt.prod.RHS()
+	fields := make([]*goASTField, len(rhs))
+	counts := map[string]int{}
+	for i, sym := range rhs {
+		text, _ := g.SymbolTable.ToString(sym)
+		f := &goASTField{}
+		switch {
+		case sym.Kind().IsNonTerminalSymbol():
+			r, ok := lhs2Rule[sym]
+			if !ok {
+				return nil, fmt.Errorf("%v has no productions", text)
+			}
+			f.typ = r.typeExpr()
+			f.node = true
+		case text == grammar.ErrorSymbolName:
+			f.typ = "*driver.Node"
+		default:
+			f.typ = "*driver.Token"
+		}
+		if len(labels) > i && labels[i] != "" {
+			f.name = goASTName(labels[i])
+		} else {
+			f.name = goASTName(text)
+			if f.name == "" {
+				f.name = "Token"
+			}
+			counts[f.name]++
+		}
+		fields[i] = f
+	}
+
+	numbers := map[string]int{}
+	for i, f := range fields {
+		if len(labels) > i && labels[i] != "" {
+			continue
+		}
+		if counts[f.name] > 1 {
+			numbers[f.name]++
+			f.name = fmt.Sprintf("%s%v", f.name, numbers[f.name])
+		}
+	}
+
+	names := map[string]struct{}{}
+	for _, f := range fields {
+		if f.name == "" {
+			return nil, fmt.Errorf("a label of %v cannot be the name of a Go field", alt.text)
+		}
+		if _, ok := reservedGoASTFields[f.name]; ok {
+			return nil, fmt.Errorf("the field %v of %v conflicts with its method", f.name, alt.text)
+		}
+		if _, ok := names[f.name]; ok {
+			return nil, fmt.Errorf("the field %v of %v is duplicated", f.name, alt.text)
+		}
+		names[f.name] = struct{}{}
+	}
+
+	return fields, nil
+}
+
+func (aw *goASTWriter) productionText(prod *grammar.Production) string {
+	lhs, _ := aw.grammar.SymbolTable.ToString(prod.LHS())
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s →", lhs)
+	rhs, _ := prod.RHS()
+	if len(rhs) == 0 {
+		fmt.Fprintf(&b, " ε")
+	}
+	for _, sym := range rhs {
+		text, _ := aw.grammar.SymbolTable.ToString(sym)
+		fmt.Fprintf(&b, " %s", text)
+	}
+	return b.String()
+}
+
+// goASTName converts a symbol or a label into an exported Go identifier, capitalizing each part separated by
+// characters other than letters and digits, such as expr_list to ExprList. It returns an empty string when the
+// result doesn't begin with a letter.
+func goASTName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, c := range s {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			upper = true
+			continue
+		}
+		if upper {
+			c = unicode.ToUpper(c)
+			upper = false
+		}
+		b.WriteRune(c)
+	}
+	name := b.String()
+	for _, c := range name {
+		if !unicode.IsLetter(c) {
+			return ""
+		}
+		if !unicode.IsUpper(c) {
+			return "X" + name
+		}
+		break
+	}
+	return name
+}
+
+const goASTNode = `// Node is a node of the typed AST.
+type Node interface {
+	// Children returns the nodes of the non-terminal symbols of the node in order.
+	Children() []Node
+	// Accept calls the method of v visiting the type of the node.
+	Accept(v Visitor) error
+}
+`
+
+const goASTWalk = `// Walk visits node with v and then walks its children in order. It stops at the first error v returns.
+func Walk(v Visitor, node Node) error {
+	err := node.Accept(v)
+	if err != nil {
+		return err
+	}
+	for _, child := range node.Children() {
+		err := Walk(v, child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+`
+
+const goASTReduceDoc = `// Reduce constructs the node of production prod from children, which hold a *driver.Token for a terminal symbol,
+// a *driver.Node for the error symbol, and a Node for a non-terminal symbol. A parser calls it on each reduction.
+`
+
+const goASTBuild = `// Build constructs the typed AST of a parse tree of the driver package by calling Reduce on each node of
+// a non-terminal symbol.
+func Build(root *driver.Node) (Node, error) {
+	n, err := build(root)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := n.(Node)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a non-terminal symbol", root.Name)
+	}
+	return node, nil
+}
+
+func build(n *driver.Node) (interface{}, error) {
+	if n.Token != nil {
+		return n.Token, nil
+	}
+	if n.Name == "error" {
+		return n, nil
+	}
+	children := make([]interface{}, len(n.Children))
+	for i, child := range n.Children {
+		c, err := build(child)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = c
+	}
+	return Reduce(n.Production, children)
+}
+`
@@ -0,0 +1,145 @@
+package writer
+
+import (
+	"bytes"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/parser"
+)
+
+func convertGrammar(t *testing.T, src string) *ast2grammar.Grammar {
+	t.Helper()
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestGoASTWriter(t *testing.T) {
+	src := `
+stmt: expr ";" | error ";" #errStmt;
+expr: left=expr "+" right=term #add | term #single;
+term: "(" expr ")" | id;
+list: list item | ;
+item: id;
+`
+	g := convertGrammar(t, src)
+
+	var b bytes.Buffer
+	err := NewGoASTWriter(g, GoOptions{Package: "ast"}).Write(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := goparser.ParseFile(token.NewFileSet(), "sousa_ast.go", b.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("the generated code is invalid: %v\n%v", err, b.String())
+	}
+
+	types := map[string][]string{}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			fields := []string{}
+			switch typ := ts.Type.(type) {
+			case *ast.StructType:
+				for _, field := range typ.Fields.List {
+					for _, name := range field.Names {
+						fields = append(fields, name.Name)
+					}
+				}
+			case *ast.InterfaceType:
+				fields = append(fields, "interface")
+			}
+			types[ts.Name.Name] = fields
+		}
+	}
+
+	expected := map[string][]string{
+		"Node":    {"interface"},
+		"Visitor": {"interface"},
+		"Stmt":    {"interface"},
+		"Stmt1":   {"Expr", "Token"},
+		"ErrStmt": {"Error", "Token"},
+		"Expr":    {"interface"},
+		"Add":     {"Left", "Token", "Right"},
+		"Single":  {"Term"},
+		"Term":    {"interface"},
+		"Term1":   {"Token1", "Expr", "Token2"},
+		"Term2":   {"Id"},
+		"List":    {"interface"},
+		"List1":   {"List", "Item"},
+		"List2":   {},
+		"Item":    {"Id"},
+	}
+	if len(types) != len(expected) {
+		names := []string{}
+		for name := range types {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		t.Fatalf("unexpected types\nwant: %v types\ngot: %v", len(expected), names)
+	}
+	for name, fields := range expected {
+		got, ok := types[name]
+		if !ok {
+			t.Fatalf("%v is not generated", name)
+		}
+		if strings.Join(got, ",") != strings.Join(fields, ",") {
+			t.Fatalf("unexpected fields of %v\nwant: %v\ngot: %v", name, fields, got)
+		}
+	}
+}
+
+func TestGoASTWriter_Conflicts(t *testing.T) {
+	tests := []struct {
+		caption string
+		src     string
+	}{
+		{
+			caption: "a label conflicts with a non-terminal symbol",
+			src:     `expr: id #term | term; term: id;`,
+		},
+		{
+			caption: "a non-terminal symbol conflicts with a name of the generated code",
+			src:     `node: id;`,
+		},
+		{
+			caption: "a label of a symbol conflicts with another symbol",
+			src:     `expr: term=id term;  term: id;`,
+		},
+		{
+			caption: "a label of a symbol conflicts with a method",
+			src:     `expr: children=id;`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.caption, func(t *testing.T) {
+			g := convertGrammar(t, tt.src)
+			err := NewGoASTWriter(g, GoOptions{Package: "ast"}).Write(&bytes.Buffer{})
+			if err == nil {
+				t.Fatal("an error was not returned")
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package writer
+
+import (
+	"bytes"
+	"fmt"
+	goparser "go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/driver"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/table"
+)
+
+func generateParsingTable(t *testing.T, g *ast2grammar.Grammar) *grammar.ParsingTable {
+	t.Helper()
+
+	first, err := grammar.GenerateFirstSets(g.Productions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := grammar.GenerateFollowSets(g.Productions, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := grammar.GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbols...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := grammar.GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pt
+}
+
+// stateStacks returns the state stacks that a parser can reach from the entry point by shifting or going to depth
+// states at most.
+func stateStacks(tab *table.Table, depth int) [][]int {
+	stacks := [][]int{}
+	var walk func(stack []int)
+	walk = func(stack []int) {
+		stacks = append(stacks, stack)
+		if len(stack) > depth {
+			return
+		}
+		state := stack[len(stack)-1]
+		for sym := 0; sym < len(tab.Symbols); sym++ {
+			next, ok := tab.GoTo(state, sym)
+			if act, n := tab.Action(state, sym); sym < tab.Terminals && act == table.ActionTypeShift {
+				next, ok = n, true
+			}
+			if ok {
+				walk(append(append([]int{}, stack...), next))
+			}
+		}
+	}
+	walk([]int{tab.EntryPoints[0].State})
+	return stacks
+}
+
+// TestGoWriter_ExpectedTerminals runs the generated code and checks that ExpectedTerminals returns the same terminal
+// symbols as the parser of the driver package even when the generated table is compressed.
+func TestGoWriter_ExpectedTerminals(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building the generated code takes time")
+	}
+
+	g := convertGrammar(t, `
+stmts: stmts stmt | stmt;
+stmt: expr ";" | error ";";
+expr: expr "+" term | term;
+term: term "*" factor | factor;
+factor: "(" expr ")" | id;
+`)
+	pt := generateParsingTable(t, g)
+	tab, err := table.New(g.SymbolTable, g.Productions, pt, g.AugmentedStartSymbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := driver.NewParser(tab, &emptyTokenStream{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stacks := stateStacks(tab, 4)
+	var expected bytes.Buffer
+	for _, stack := range stacks {
+		names := []string{}
+		for _, sym := range p.ExpectedTerminals(stack) {
+			if sym == table.SymbolEOF {
+				names = append(names, "EOF")
+				continue
+			}
+			names = append(names, tab.Symbols[sym].Name)
+		}
+		fmt.Fprintln(&expected, strings.Join(names, " "))
+	}
+	var stackLits bytes.Buffer
+	for _, stack := range stacks {
+		fmt.Fprintf(&stackLits, "%#v,\n", stack)
+	}
+
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compress: %v", compress), func(t *testing.T) {
+			// The directory is in the module so that the generated code can import the table package.
+			dir, err := ioutil.TempDir(".", "generated")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			f, err := os.Create(filepath.Join(dir, "sousa_table.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = NewGoWriter(g, pt, GoOptions{Package: "main", Compress: compress}).Write(f)
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func main() {
+	for _, stack := range [][]int{
+`+stackLits.String()+`	} {
+		fmt.Println(strings.Join(ExpectedTerminals(stack), " "))
+	}
+}
+`), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := exec.Command("go", "run", "./"+filepath.Base(dir)).CombinedOutput()
+			if err != nil {
+				t.Fatalf("failed to run the generated code: %v\n%s", err, out)
+			}
+			if string(out) != expected.String() {
+				t.Fatalf("unexpected expected terminals\nwant:\n%v\ngot:\n%s", expected.String(), out)
+			}
+		})
+	}
+}
+
+type emptyTokenStream struct{}
+
+func (ts *emptyTokenStream) Next() (*driver.Token, error) {
+	return nil, io.EOF
+}
+
+func TestReadGoStamp(t *testing.T) {
+	g := convertGrammar(t, `expr: expr "+" id | id;`)
+	pt := generateParsingTable(t, g)
+
+	for _, stamp := range []string{"", "version=0.1.0 lang=go inputs=0123abcd"} {
+		opts := GoOptions{
+			Package: "main",
+			Stamp:   stamp,
+		}
+		for _, w := range []Writer{NewGoWriter(g, pt, opts), NewGoASTWriter(g, opts)} {
+			var b bytes.Buffer
+			err := w.Write(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(b.String(), "// Code generated by sousa. DO NOT EDIT.\n") || !IsGoGenerated(b.Bytes()) {
+				t.Fatalf("the generated code must begin with the header\n%v", b.String())
+			}
+			_, err = goparser.ParseFile(token.NewFileSet(), "sousa.go", b.Bytes(), 0)
+			if err != nil {
+				t.Fatalf("the generated code is invalid: %v\n%v", err, b.String())
+			}
+			if got := ReadGoStamp(b.Bytes()); got != stamp {
+				t.Fatalf("unexpected stamp\nwant: %v\ngot: %v", stamp, got)
+			}
+		}
+	}
+
+	// A stamp is only looked for before the package clause.
+	if got := ReadGoStamp([]byte("package main\n\n// sousa:stamp version=0.1.0\n")); got != "" {
+		t.Fatalf("unexpected stamp: %v", got)
+	}
+}
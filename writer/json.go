@@ -0,0 +1,284 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+)
+
+// JSONFormatVersion is the version of the document that the JSON writer writes.
+// writer/schema.json describes the document.
+const JSONFormatVersion = 1
+
+// SymbolEOF represents the end of input in the actions of the JSON document.
+const SymbolEOF = "$"
+
+// IsJSON reports whether src is a document that the JSON writer wrote.
+func IsJSON(src []byte) bool {
+	doc := map[string]json.RawMessage{}
+	err := json.Unmarshal(src, &doc)
+	if err != nil {
+		return false
+	}
+	for _, key := range []string{"version", "symbols", "productions", "states"} {
+		if _, ok := doc[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type jsonDocument struct {
+	Version       int                 `json:"version"`
+	Symbols       []*jsonSymbol       `json:"symbols"`
+	Productions   []*jsonProduction   `json:"productions"`
+	States        []*jsonState        `json:"states"`
+	InitialState  grammar.StateID     `json:"initial_state"`
+	InitialStates []*jsonInitialState `json:"initial_states"`
+}
+
+type jsonSymbol struct {
+	ID   grammar.SymbolID   `json:"id"`
+	Name string             `json:"name"`
+	Kind grammar.SymbolKind `json:"kind"`
+}
+
+type jsonProduction struct {
+	ID     grammar.ProductionID `json:"id"`
+	LHS    grammar.SymbolID     `json:"lhs"`
+	RHS    []grammar.SymbolID   `json:"rhs"`
+	Source *jsonSource          `json:"source,omitempty"`
+}
+
+type jsonSource struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+type jsonState struct {
+	ID      grammar.StateID `json:"id"`
+	Actions []*jsonAction   `json:"actions"`
+	GoTos   []*jsonGoTo     `json:"gotos"`
+}
+
+type jsonAction struct {
+	Symbol     string                `json:"symbol"`
+	Type       string                `json:"type"`
+	State      *grammar.StateID      `json:"state,omitempty"`
+	Production *grammar.ProductionID `json:"production,omitempty"`
+}
+
+type jsonGoTo struct {
+	Symbol grammar.SymbolID `json:"symbol"`
+	State  grammar.StateID  `json:"state"`
+}
+
+type jsonInitialState struct {
+	Symbol grammar.SymbolID `json:"symbol"`
+	State  grammar.StateID  `json:"state"`
+}
+
+type jsonWriter struct {
+	grammar      *ast2grammar.Grammar
+	parsingTable *grammar.ParsingTable
+}
+
+// NewJSONWriter returns a Writer that writes the grammar and its parsing table as a single JSON document.
+func NewJSONWriter(g *ast2grammar.Grammar, parsingTable *grammar.ParsingTable) Writer {
+	return &jsonWriter{
+		grammar:      g,
+		parsingTable: parsingTable,
+	}
+}
+
+func (jw *jsonWriter) Write(w io.Writer) error {
+	if jw.grammar == nil || jw.parsingTable == nil {
+		return fmt.Errorf("parameters passed contains nil")
+	}
+
+	doc := &jsonDocument{
+		Version: JSONFormatVersion,
+	}
+
+	var err error
+	doc.Symbols, err = jw.symbols()
+	if err != nil {
+		return err
+	}
+	doc.Productions, err = jw.productions()
+	if err != nil {
+		return err
+	}
+	doc.States, err = jw.states()
+	if err != nil {
+		return err
+	}
+
+	states := jw.parsingTable.States()
+	initialState, ok := states[jw.parsingTable.InitialState()]
+	if !ok {
+		return fmt.Errorf("failed to get a state. kernel fingerprint: %v", jw.parsingTable.InitialState())
+	}
+	doc.InitialState = initialState
+	doc.InitialStates = []*jsonInitialState{}
+	for _, sym := range jw.grammar.AugmentedStartSymbols {
+		kernelFp, ok := jw.parsingTable.InitialStates()[sym]
+		if !ok {
+			return fmt.Errorf("failed to get an initial state. symbol: %v", sym)
+		}
+		state, ok := states[kernelFp]
+		if !ok {
+			return fmt.Errorf("failed to get a state. kernel fingerprint: %v", kernelFp)
+		}
+		doc.InitialStates = append(doc.InitialStates, &jsonInitialState{
+			Symbol: sym,
+			State:  state,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func (jw *jsonWriter) symbols() ([]*jsonSymbol, error) {
+	st := jw.grammar.SymbolTable
+	syms := []*jsonSymbol{}
+	for _, id := range st.Symbols() {
+		name, ok := st.ToString(id)
+		if !ok {
+			return nil, fmt.Errorf("failed to get the text of a symbol. symbol: %v", id)
+		}
+		syms = append(syms, &jsonSymbol{
+			ID:   id,
+			Name: name,
+			Kind: id.Kind(),
+		})
+	}
+
+	return syms, nil
+}
+
+func (jw *jsonWriter) productions() ([]*jsonProduction, error) {
+	prods := []*jsonProduction{}
+	for lhs, ps := range jw.grammar.Productions.All() {
+		for _, p := range ps {
+			rhs, _ := p.RHS()
+			prod := &jsonProduction{
+				ID:  p.ID(),
+				LHS: lhs,
+				RHS: append([]grammar.SymbolID{}, rhs...),
+			}
+			if src, ok := jw.grammar.Sources[p.ID()]; ok {
+				prod.Source = &jsonSource{
+					File:   src.File,
+					Line:   src.Position.Line,
+					Column: src.Position.Column,
+				}
+			}
+			prods = append(prods, prod)
+		}
+	}
+	sort.Slice(prods, func(i, j int) bool {
+		return prods[i].ID < prods[j].ID
+	})
+
+	return prods, nil
+}
+
+func (jw *jsonWriter) states() ([]*jsonState, error) {
+	prodID := func(fp grammar.ProductionFingerprint) (*grammar.ProductionID, error) {
+		prod := jw.grammar.Productions.LookupByFingerprint(fp)
+		if prod == nil {
+			return nil, fmt.Errorf("failed to get a production. fingerprint: %v", fp)
+		}
+		id := prod.ID()
+		return &id, nil
+	}
+
+	states := jw.parsingTable.States()
+	stateID := func(kernelFp grammar.KernelFingerprint) (*grammar.StateID, error) {
+		state, ok := states[kernelFp]
+		if !ok {
+			return nil, fmt.Errorf("failed to get a state. kernel fingerprint: %v", kernelFp)
+		}
+		return &state, nil
+	}
+
+	ss := []*jsonState{}
+	for kernelFp, id := range states {
+		s := &jsonState{
+			ID:      id,
+			Actions: []*jsonAction{},
+			GoTos:   []*jsonGoTo{},
+		}
+
+		if actions, ok := jw.parsingTable.Action()[kernelFp]; ok {
+			if actions.Acceptable() {
+				s.Actions = append(s.Actions, &jsonAction{
+					Symbol: SymbolEOF,
+					Type:   "accept",
+				})
+			}
+			if prodFp, reducible := actions.ReduceByEOF(); reducible {
+				prod, err := prodID(prodFp)
+				if err != nil {
+					return nil, err
+				}
+				s.Actions = append(s.Actions, &jsonAction{
+					Symbol:     SymbolEOF,
+					Type:       grammar.ActionType(grammar.ActionTypeReduce).String(),
+					Production: prod,
+				})
+			}
+			for sym, a := range actions.Actions() {
+				action := &jsonAction{
+					Symbol: sym.String(),
+					Type:   a.Type().String(),
+				}
+				var err error
+				switch a.Type() {
+				case grammar.ActionTypeShift:
+					action.State, err = stateID(a.NextState())
+				case grammar.ActionTypeReduce:
+					action.Production, err = prodID(a.Production())
+				default:
+					err = fmt.Errorf("unknown action type. got: %v", a.Type())
+				}
+				if err != nil {
+					return nil, err
+				}
+				s.Actions = append(s.Actions, action)
+			}
+			sort.SliceStable(s.Actions, func(i, j int) bool {
+				return s.Actions[i].Symbol < s.Actions[j].Symbol
+			})
+		}
+
+		for sym, nextKernelFp := range jw.parsingTable.GoTo()[kernelFp] {
+			next, err := stateID(nextKernelFp)
+			if err != nil {
+				return nil, err
+			}
+			s.GoTos = append(s.GoTos, &jsonGoTo{
+				Symbol: sym,
+				State:  *next,
+			})
+		}
+		sort.Slice(s.GoTos, func(i, j int) bool {
+			return s.GoTos[i].Symbol < s.GoTos[j].Symbol
+		})
+
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool {
+		return ss[i].ID < ss[j].ID
+	})
+
+	return ss, nil
+}
@@ -0,0 +1,135 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nihei9/sousa/ast2grammar"
+	"github.com/nihei9/sousa/grammar"
+	"github.com/nihei9/sousa/parser"
+)
+
+func TestJSONWriter(t *testing.T) {
+	src := `%start stmt expr; stmt: expr ";"; expr: expr "+" id | id;`
+
+	p, err := parser.NewParser(parser.NewLexer(strings.NewReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := ast2grammar.Convert(ast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := grammar.GenerateFirstSets(g.Productions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follow, err := grammar.GenerateFollowSets(g.Productions, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	automaton, err := grammar.GenerateLR0Automaton(g.SymbolTable, g.Productions, g.AugmentedStartSymbols...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := grammar.GenerateSLRParsingTable(automaton, follow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	err = NewJSONWriter(g, pt).Write(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsJSON(b.Bytes()) {
+		t.Fatal("the document must be recognized as the output of the JSON writer")
+	}
+	if IsJSON([]byte(`{"version": 1}`)) {
+		t.Fatal("a document lacking the fields must not be recognized as the output of the JSON writer")
+	}
+
+	doc := &jsonDocument{}
+	err = json.Unmarshal(b.Bytes(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Version != JSONFormatVersion {
+		t.Fatalf("unexpected version\nwant: %v\ngot: %v", JSONFormatVersion, doc.Version)
+	}
+
+	names := map[grammar.SymbolID]string{}
+	for _, sym := range doc.Symbols {
+		names[sym.ID] = sym.Name
+	}
+	for _, name := range []string{"stmt", "expr", "stmt'", "expr'", ";", "+", "id"} {
+		id := g.SymbolTable.LookupByString(name)
+		if names[id] != name {
+			t.Fatalf("unexpected symbol name\nwant: %v\ngot: %v", name, names[id])
+		}
+	}
+
+	if len(doc.Productions) != 5 {
+		t.Fatalf("unexpected number of productions\nwant: %v\ngot: %v", 5, len(doc.Productions))
+	}
+	for _, prod := range doc.Productions {
+		if prod.LHS.Kind().IsStartSymbol() {
+			if prod.Source != nil {
+				t.Fatalf("an augmented production has a source: %+v", prod.Source)
+			}
+			continue
+		}
+		if prod.Source == nil || prod.Source.Line != 1 {
+			t.Fatalf("unexpected source: %+v", prod.Source)
+		}
+	}
+
+	if len(doc.States) != len(pt.States()) {
+		t.Fatalf("unexpected number of states\nwant: %v\ngot: %v", len(pt.States()), len(doc.States))
+	}
+	for i, s := range doc.States {
+		if s.ID != grammar.StateID(i) {
+			t.Fatalf("states are not sorted by ID\nwant: %v\ngot: %v", i, s.ID)
+		}
+	}
+
+	// Each start symbol is accepted in the state reached from its initial state via the original start symbol.
+	if len(doc.InitialStates) != 2 {
+		t.Fatalf("unexpected number of initial states\nwant: %v\ngot: %v", 2, len(doc.InitialStates))
+	}
+	if doc.InitialState != doc.InitialStates[0].State {
+		t.Fatalf("unexpected initial state\nwant: %v\ngot: %v", doc.InitialStates[0].State, doc.InitialState)
+	}
+	for i, start := range []string{"stmt", "expr"} {
+		init := doc.InitialStates[i]
+		if init.Symbol != g.AugmentedStartSymbols[i] {
+			t.Fatalf("unexpected start symbol\nwant: %v\ngot: %v", g.AugmentedStartSymbols[i], init.Symbol)
+		}
+		next := grammar.StateID(-1)
+		for _, goTo := range doc.States[init.State].GoTos {
+			if goTo.Symbol == g.SymbolTable.LookupByString(start) {
+				next = goTo.State
+			}
+		}
+		if next < 0 {
+			t.Fatalf("a goto of %v was not found", start)
+		}
+		accepted := false
+		for _, a := range doc.States[next].Actions {
+			if a.Symbol == SymbolEOF && a.Type == "accept" {
+				accepted = true
+			}
+		}
+		if !accepted {
+			t.Fatalf("%v is not accepted", start)
+		}
+	}
+}
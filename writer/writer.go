@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/nihei9/sousa/grammar"
 )
@@ -13,6 +15,24 @@ type Writer interface {
 	Write(io.Writer) error
 }
 
+var csvLine = regexp.MustCompile(`^[0-9A-Za-z$-]+(,[0-9A-Za-z$-]+)*$`)
+
+// IsCSV reports whether src looks like a file that the writers of the production, action, goto, and start files
+// wrote, that is, it has one or more lines, and each line consists of comma-separated fields of letters, digits, '$',
+// and '-'. The writers never write an empty file, so an empty file isn't regarded as theirs.
+func IsCSV(src []byte) bool {
+	text := strings.TrimSuffix(string(src), "\n")
+	if text == "" {
+		return false
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if !csvLine.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
 type productionsWriter struct {
 	productions grammar.Productions
 }
@@ -145,3 +165,46 @@ func (gw *goToWriter) Write(w io.Writer) error {
 
 	return nil
 }
+
+type startWriter struct {
+	parsingTable *grammar.ParsingTable
+}
+
+// NewStartWriter returns a Writer that writes the initial state of each augmented start symbol.
+// Each line has the form `<augmented start symbol>,<initial state>`.
+func NewStartWriter(parsingTable *grammar.ParsingTable) Writer {
+	return &startWriter{
+		parsingTable: parsingTable,
+	}
+}
+
+func (sw *startWriter) Write(w io.Writer) error {
+	states := sw.parsingTable.States()
+
+	type entry struct {
+		sym   grammar.SymbolID
+		state grammar.StateID
+	}
+	entries := []*entry{}
+	for sym, kernelFp := range sw.parsingTable.InitialStates() {
+		state, ok := states[kernelFp]
+		if !ok {
+			return fmt.Errorf("failed to get a state. kernel fingerprint: %v", kernelFp)
+		}
+		entries = append(entries, &entry{
+			sym:   sym,
+			state: state,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].state < entries[j].state
+	})
+
+	buf := new(bytes.Buffer)
+	for _, e := range entries {
+		fmt.Fprintf(buf, "%v,%v\n", e.sym, e.state)
+	}
+	w.Write(buf.Bytes())
+
+	return nil
+}
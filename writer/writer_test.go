@@ -0,0 +1,24 @@
+package writer
+
+import "testing"
+
+func TestIsCSV(t *testing.T) {
+	tests := []struct {
+		src string
+		csv bool
+	}{
+		{src: "", csv: false},
+		{src: "\n", csv: false},
+		{src: "1,n1,1\n2,n2,3\n", csv: true},
+		{src: "1,t,t5-s4\n2,f,$-r3,t5-r3\n", csv: true},
+		{src: "s3,0\n", csv: true},
+		{src: "hello world\n", csv: false},
+		{src: "1,n1,1\n\n", csv: false},
+		{src: "{\"version\": 1}\n", csv: false},
+	}
+	for _, tt := range tests {
+		if csv := IsCSV([]byte(tt.src)); csv != tt.csv {
+			t.Errorf("unexpected result for %q\nwant: %v\ngot: %v", tt.src, tt.csv, csv)
+		}
+	}
+}